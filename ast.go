@@ -0,0 +1,387 @@
+package timewarp
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expr is a node of a parsed timerangeQL expression.
+type Expr interface {
+	// Pos returns the position of the first token of the expression.
+	Pos() Pos
+
+	// String returns the canonical timerangeQL representation.
+	String() string
+
+	// Filter compiles the expression into a filter.
+	Filter() Filter
+
+	expr()
+}
+
+// BinaryExpr joins two expressions with AND (union) or IN (intersection).
+type BinaryExpr struct {
+	Op    Token
+	OpPos Pos
+	X     Expr
+	Y     Expr
+}
+
+// OrdinalExpr selects the Nth result of X within each Unit, as in
+// "X OF N UNIT".
+type OrdinalExpr struct {
+	X     Expr
+	OfPos Pos
+	N     int
+	Unit  Expr
+}
+
+// NotExpr negates an expression.
+type NotExpr struct {
+	NotPos Pos
+	X      Expr
+}
+
+// YearExpr matches a calendar year.
+type YearExpr struct {
+	YearPos Pos
+	Year    int
+}
+
+// MonthExpr matches a month of the year, or every month if Month is zero.
+type MonthExpr struct {
+	MonthPos Pos
+	Month    time.Month
+}
+
+// WeekExpr matches weeks starting on Weekday, or starting from the input if
+// Weekday is negative.
+type WeekExpr struct {
+	WeekPos Pos
+	Weekday time.Weekday
+}
+
+// DayExpr matches days by number.  Args holds the day numbers as written:
+// none for every day, a single day, or a start and end day.
+type DayExpr struct {
+	DayPos Pos
+	Args   []int
+}
+
+// WeekdayExpr matches the days of the week from From through To inclusive.
+type WeekdayExpr struct {
+	DayPos Pos
+	From   time.Weekday
+	To     time.Weekday
+}
+
+// TimeExpr matches the time of day from From until To, both in the "1504"
+// format.
+type TimeExpr struct {
+	TimePos Pos
+	From    string
+	To      string
+}
+
+// RangeExpr is the no-op ordinal unit spanning the whole input.
+type RangeExpr struct {
+	RangePos Pos
+}
+
+// NamedExpr is a reference to an expression defined with a name.
+type NamedExpr struct {
+	NamePos Pos
+	Name    string
+	X       Expr
+}
+
+func (*BinaryExpr) expr()  {}
+func (*OrdinalExpr) expr() {}
+func (*NotExpr) expr()     {}
+func (*YearExpr) expr()    {}
+func (*MonthExpr) expr()   {}
+func (*WeekExpr) expr()    {}
+func (*DayExpr) expr()     {}
+func (*WeekdayExpr) expr() {}
+func (*TimeExpr) expr()    {}
+func (*RangeExpr) expr()   {}
+func (*NamedExpr) expr()   {}
+
+// Pos returns the position of the left operand.
+func (e *BinaryExpr) Pos() Pos { return e.X.Pos() }
+
+// Pos returns the position of the ordinal operand.
+func (e *OrdinalExpr) Pos() Pos { return e.X.Pos() }
+
+// Pos returns the position of the NOT keyword.
+func (e *NotExpr) Pos() Pos { return e.NotPos }
+
+// Pos returns the position of the YEAR keyword.
+func (e *YearExpr) Pos() Pos { return e.YearPos }
+
+// Pos returns the position of the MONTH keyword.
+func (e *MonthExpr) Pos() Pos { return e.MonthPos }
+
+// Pos returns the position of the WEEK keyword.
+func (e *WeekExpr) Pos() Pos { return e.WeekPos }
+
+// Pos returns the position of the DAY keyword.
+func (e *DayExpr) Pos() Pos { return e.DayPos }
+
+// Pos returns the position of the DAY keyword.
+func (e *WeekdayExpr) Pos() Pos { return e.DayPos }
+
+// Pos returns the position of the TIME keyword.
+func (e *TimeExpr) Pos() Pos { return e.TimePos }
+
+// Pos returns the position of the RANGE keyword.
+func (e *RangeExpr) Pos() Pos { return e.RangePos }
+
+// Pos returns the position of the name.
+func (e *NamedExpr) Pos() Pos { return e.NamePos }
+
+// String returns the canonical representation of the expression.  The right
+// operand is parenthesized when it is itself a compound expression since
+// operators are evaluated left to right.
+func (e *BinaryExpr) String() string {
+	return e.X.String() + " " + e.Op.String() + " " + operand(e.Y)
+}
+
+// String returns the canonical representation of the expression.
+func (e *OrdinalExpr) String() string {
+	var buf strings.Builder
+	buf.WriteString(e.X.String())
+	buf.WriteString(" OF ")
+	if e.N != 1 {
+		buf.WriteString(strconv.Itoa(e.N))
+		buf.WriteString(" ")
+	}
+	buf.WriteString(e.Unit.String())
+	return buf.String()
+}
+
+// String returns the canonical representation of the expression.
+func (e *NotExpr) String() string {
+	return "NOT " + operand(e.X)
+}
+
+// String returns the canonical representation of the expression.
+func (e *YearExpr) String() string {
+	return "YEAR " + strconv.Itoa(e.Year)
+}
+
+// String returns the canonical representation of the expression.
+func (e *MonthExpr) String() string {
+	if e.Month > 0 {
+		return "MONTH " + strings.ToUpper(e.Month.String())
+	}
+	return "MONTH"
+}
+
+// String returns the canonical representation of the expression.
+func (e *WeekExpr) String() string {
+	if e.Weekday >= 0 {
+		return "WEEK " + strings.ToUpper(e.Weekday.String())
+	}
+	return "WEEK"
+}
+
+// String returns the canonical representation of the expression.
+func (e *DayExpr) String() string {
+	var buf strings.Builder
+	buf.WriteString("DAY")
+	for _, v := range e.Args {
+		buf.WriteString(" ")
+		buf.WriteString(strconv.Itoa(v))
+	}
+	return buf.String()
+}
+
+// String returns the canonical representation of the expression.
+func (e *WeekdayExpr) String() string {
+	s := "DAY " + strings.ToUpper(e.From.String())
+	if e.To != e.From {
+		s += " " + strings.ToUpper(e.To.String())
+	}
+	return s
+}
+
+// String returns the canonical representation of the expression.
+func (e *TimeExpr) String() string {
+	return "TIME " + e.From + " " + e.To
+}
+
+// String returns the canonical representation of the expression.
+func (e *RangeExpr) String() string {
+	return "RANGE"
+}
+
+// String returns the canonical representation of the referenced expression.
+func (e *NamedExpr) String() string {
+	return e.X.String()
+}
+
+// operand returns the string of an expression, parenthesized if it is
+// compound.
+func operand(e Expr) string {
+	switch e := e.(type) {
+	case *NamedExpr:
+		return operand(e.X)
+	case *BinaryExpr, *OrdinalExpr:
+		return "(" + e.String() + ")"
+	default:
+		return e.String()
+	}
+}
+
+// Filter compiles the expression into a union or intersection filter.
+func (e *BinaryExpr) Filter() Filter {
+	if e.Op == IN {
+		return e.X.Filter().Intersect(e.Y.Filter())
+	}
+	return e.X.Filter().Union(e.Y.Filter())
+}
+
+// Filter compiles the expression into an ordinal filter.
+func (e *OrdinalExpr) Filter() Filter {
+	return e.X.Filter().Ordinal(e.N, unitFilter(e.Unit, e.N))
+}
+
+// Filter compiles the expression into a negated filter.
+func (e *NotExpr) Filter() Filter {
+	return e.X.Filter().Negate()
+}
+
+// Filter compiles the expression into a year filter.
+func (e *YearExpr) Filter() Filter {
+	return Year(e.Year).Filter()
+}
+
+// Filter compiles the expression into a month filter.
+func (e *MonthExpr) Filter() Filter {
+	return Month(e.Month).Filter()
+}
+
+// Filter compiles the expression into a week filter.
+func (e *WeekExpr) Filter() Filter {
+	return Week(e.Weekday, 7).Filter()
+}
+
+// Filter compiles the expression into a day filter.
+func (e *DayExpr) Filter() Filter {
+	switch len(e.Args) {
+	case 0:
+		return Days(0, 1).Filter()
+	case 1:
+		return Days(e.Args[0]-1, 1).Filter()
+	default:
+		return Days(e.Args[0]-1, e.Args[1]-e.Args[0]+1).Filter()
+	}
+}
+
+// Filter compiles the expression into a weekday filter.
+func (e *WeekdayExpr) Filter() Filter {
+	return Week(e.From, e.Span()).Filter()
+}
+
+// Filter compiles the expression into a time filter.
+func (e *TimeExpr) Filter() Filter {
+	return Times(timefmt, e.From, e.To).Filter()
+}
+
+// Filter compiles the expression into a no-op filter.
+func (e *RangeExpr) Filter() Filter {
+	return Range().Filter()
+}
+
+// Filter compiles the referenced expression.
+func (e *NamedExpr) Filter() Filter {
+	return e.X.Filter()
+}
+
+// Span returns the number of consecutive days matched by the expression.
+func (e *WeekdayExpr) Span() int {
+	return getWeekdayDelta(e.From, e.To) + 1
+}
+
+// unitFilter compiles the unit of an ordinal expression.  Units cover whole
+// calendar periods, so they use the "The" queries.
+func unitFilter(unit Expr, n int) Filter {
+	switch u := unit.(type) {
+	case *MonthExpr:
+		return TheMonth(u.Month).Filter()
+	case *WeekExpr:
+		if n > 0 {
+			return TheWeek(u.Weekday, 7, -n+1, 2*n-1).Filter()
+		}
+		return Week(u.Weekday, 7).Filter()
+	case *DayExpr:
+		switch len(u.Args) {
+		case 0:
+			return TheDays(-n+1, 2*n-1).Filter()
+		case 1:
+			return TheDays(u.Args[0], 1).Filter()
+		default:
+			return TheDays(u.Args[0], u.Args[1]).Filter()
+		}
+	default:
+		return unit.Filter()
+	}
+}
+
+// Inspect traverses the expression in depth-first order, calling fn for each
+// node.  If fn returns false, the children of the node are skipped.
+func Inspect(e Expr, fn func(Expr) bool) {
+	if e == nil || !fn(e) {
+		return
+	}
+
+	switch e := e.(type) {
+	case *BinaryExpr:
+		Inspect(e.X, fn)
+		Inspect(e.Y, fn)
+	case *OrdinalExpr:
+		Inspect(e.X, fn)
+		Inspect(e.Unit, fn)
+	case *NotExpr:
+		Inspect(e.X, fn)
+	case *NamedExpr:
+		Inspect(e.X, fn)
+	}
+}
+
+// Labeled compiles the expression into a labeled filter.  References to
+// named expressions are labeled with the name, and the other arms of a union
+// are labeled with their position and canonical string, as in
+// "1:5 DAY MONDAY FRIDAY".
+func Labeled(e Expr) LabeledFilter {
+	switch e := e.(type) {
+	case *NamedExpr:
+		return Labeled(e.X).Label(e.Name)
+	case *BinaryExpr:
+		if e.Op == IN {
+			return Labeled(e.X).Intersect(Labeled(e.Y))
+		}
+
+		var filters []LabeledFilter
+		for _, arm := range unionArms(e, nil) {
+			if _, ok := arm.(*NamedExpr); ok {
+				filters = append(filters, Labeled(arm))
+			} else {
+				filters = append(filters, Labeled(arm).Label(posLabel(arm)))
+			}
+		}
+		return filters[0].Union(filters[1:]...)
+	case *OrdinalExpr:
+		return Labeled(e.X).Ordinal(e.N, unitFilter(e.Unit, e.N))
+	default:
+		return e.Filter().Label()
+	}
+}
+
+// posLabel returns the positional label of an expression.
+func posLabel(e Expr) string {
+	pos := e.Pos()
+	return strconv.Itoa(pos.Line+1) + ":" + strconv.Itoa(pos.Char+1) + " " + e.String()
+}
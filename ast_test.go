@@ -0,0 +1,135 @@
+package timewarp_test
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/takeinitiative/timewarp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Expr", func() {
+	const datefmt = "01-02-06"
+
+	DescribeTable("Canonical string",
+		func(in, canonical string) {
+			e, err := timewarp.ParseExprString(in)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(e.String()).To(Equal(canonical))
+
+			// the canonical string must parse to the same filter
+			r, _ := timewarp.Parse(datefmt, "01-01-18", "01-01-19")
+			c, err := timewarp.ParseExprString(canonical)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(c.String()).To(Equal(canonical))
+			Expect(c.Filter()(*r)).To(Equal(e.Filter()(*r)))
+		},
+		Entry("keywords", `day tuesday of 2 month march in time 1200 1400`, `DAY TUESDAY OF 2 MONTH MARCH IN TIME 1200 1400`),
+		Entry("default ordinal", `DAY 5 OF 1 MONTH JUNE`, `DAY 5 OF MONTH JUNE`),
+		Entry("single weekday range", `DAY MONDAY MONDAY`, `DAY MONDAY`),
+		Entry("left parens", `(DAY MONDAY AND DAY FRIDAY) IN TIME 0900 1700`, `DAY MONDAY AND DAY FRIDAY IN TIME 0900 1700`),
+		Entry("right parens", `DAY MONDAY IN (TIME 0900 1000 AND TIME 1500 1600)`, `DAY MONDAY IN (TIME 0900 1000 AND TIME 1500 1600)`),
+		Entry("negation", `NOT (DAY TUESDAY OF MONTH MARCH)`, `NOT (DAY TUESDAY OF MONTH MARCH)`),
+		Entry("leaf negation", `NOT (DAY TUESDAY)`, `NOT DAY TUESDAY`),
+		Entry("units", `DAY 1 3 OF DAY 0 5 AND WEEK OF 3 WEEK MONDAY AND DAY OF RANGE`, `DAY 1 3 OF DAY 0 5 AND WEEK OF 3 WEEK MONDAY AND DAY OF RANGE`),
+	)
+
+	Describe("Inspect", func() {
+		It("should visit every node in order", func() {
+			e, err := timewarp.ParseExprString(`DAY MONDAY IN NOT (YEAR 2020 AND MONTH JUNE)`)
+			Expect(err).NotTo(HaveOccurred())
+
+			var visited []string
+			timewarp.Inspect(e, func(e timewarp.Expr) bool {
+				visited = append(visited, e.String())
+				return true
+			})
+			Expect(visited).To(Equal([]string{
+				`DAY MONDAY IN NOT (YEAR 2020 AND MONTH JUNE)`,
+				`DAY MONDAY`,
+				`NOT (YEAR 2020 AND MONTH JUNE)`,
+				`YEAR 2020 AND MONTH JUNE`,
+				`YEAR 2020`,
+				`MONTH JUNE`,
+			}))
+		})
+	})
+
+	Describe("Labeled", func() {
+		var (
+			p  *timewarp.Parser
+			r  *timewarp.TimeRange
+			lr []*timewarp.LabeledRange
+		)
+
+		BeforeEach(func() {
+			p = timewarp.NewParser(bytes.NewBufferString(`business AND DAY SATURDAY IN TIME 1000 1200`))
+			business, err := timewarp.ParseExprString(`DAY MONDAY FRIDAY IN TIME 0900 1700`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(p.Define("Business", business)).To(Succeed())
+
+			r, _ = timewarp.Parse(datefmt, "11-07-16", "11-14-16")
+		})
+
+		JustBeforeEach(func() {
+			e, err := p.ParseExpr()
+			Expect(err).NotTo(HaveOccurred())
+			lr = timewarp.Labeled(e)(*r)
+		})
+
+		It("should label named and positional arms", func() {
+			Expect(lr).To(HaveLen(6))
+			Expect(lr[0].Labels).To(Equal([]string{"business"}))
+			Expect(lr[5].Labels).To(Equal([]string{"1:14 DAY SATURDAY"}))
+			Expect(lr[5].Range.Start).To(Equal(time.Date(2016, 11, 12, 10, 0, 0, 0, time.UTC)))
+		})
+
+		It("should match the unlabeled filter", func() {
+			jan, _ := timewarp.Parse(datefmt, "01-01-18", "02-01-18")
+			for _, s := range []string{
+				`DAY MONDAY FRIDAY IN TIME 0900 1700 AND DAY SATURDAY IN TIME 1000 1200`,
+				`DAY MONDAY AND DAY TUESDAY`,
+				`(DAY MONDAY AND DAY TUESDAY) OF 2 MONTH`,
+				`(DAY MONDAY AND DAY TUESDAY) IN DAY MONDAY TUESDAY`,
+				`DAY OF 2 WEEK`,
+				`(DAY SATURDAY AND DAY SUNDAY AND DAY MONDAY) OF -1 MONTH`,
+			} {
+				e, err := timewarp.ParseExprString(s)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(timewarp.Labeled(e).Filter()(*r)).To(Equal(e.Filter()(*r)), s)
+				Expect(timewarp.Labeled(e).Filter()(*jan)).To(Equal(e.Filter()(*jan)), s)
+			}
+		})
+
+		It("should count whole ranges in ordinals", func() {
+			e, _ := timewarp.ParseExprString(`(DAY MONDAY AND DAY TUESDAY) OF 2 MONTH`)
+			jan, _ := timewarp.Parse(datefmt, "01-01-18", "02-01-18")
+			lr = timewarp.Labeled(e)(*jan)
+
+			Expect(lr).To(HaveLen(2))
+			Expect(lr[0].Range).To(Equal(timewarp.TimeRange{time.Date(2018, 1, 8, 0, 0, 0, 0, time.UTC), time.Date(2018, 1, 9, 0, 0, 0, 0, time.UTC)}))
+			Expect(lr[0].Labels).To(Equal([]string{"1:2 DAY MONDAY"}))
+			Expect(lr[1].Labels).To(Equal([]string{"1:17 DAY TUESDAY"}))
+		})
+	})
+
+	Describe("Define", func() {
+		It("should reject keywords", func() {
+			e, _ := timewarp.ParseExprString(`DAY MONDAY`)
+			Expect(timewarp.NewParser(bytes.NewBufferString(``)).Define("monday", e)).NotTo(Succeed())
+		})
+
+		It("should reference names quoted", func() {
+			holidays, _ := timewarp.ParseExprString(`DAY 25 OF MONTH DECEMBER`)
+			p := timewarp.NewParser(bytes.NewBufferString(`DAY MONDAY FRIDAY IN NOT "Public Holidays"`))
+			Expect(p.Define("public holidays", holidays)).To(Succeed())
+
+			e, err := p.ParseExpr()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(e.String()).To(Equal(`DAY MONDAY FRIDAY IN NOT (DAY 25 OF MONTH DECEMBER)`))
+		})
+	})
+})
@@ -0,0 +1,485 @@
+package timewarp
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// calendarMaxYear is the last year searched for calendar events.
+const calendarMaxYear = 2199
+
+// calendarShorthands are the systemd calendar event shorthands.
+var calendarShorthands = map[string]string{
+	"minutely":     "*-*-* *:*:00",
+	"hourly":       "*-*-* *:00:00",
+	"daily":        "*-*-* 00:00:00",
+	"monthly":      "*-*-01 00:00:00",
+	"weekly":       "Mon *-*-* 00:00:00",
+	"yearly":       "*-01-01 00:00:00",
+	"annually":     "*-01-01 00:00:00",
+	"quarterly":    "*-01,04,07,10-01 00:00:00",
+	"semiannually": "*-01,07-01 00:00:00",
+}
+
+// CalendarSpec is a systemd calendar event, as used by the OnCalendar setting
+// of timer units, such as "Mon..Fri *-*-* 09:00:00".
+type CalendarSpec struct {
+	weekdays   uint8 // bit per weekday, or zero for every day
+	year       calendarChain
+	month      calendarChain
+	day        calendarChain
+	endOfMonth bool // days count back from the end of the month
+	hour       calendarChain
+	minute     calendarChain
+	second     calendarChain
+	loc        *time.Location
+	zone       string
+}
+
+// calendarChain is a list of values of a calendar component.  A nil chain
+// matches every value.
+type calendarChain []calendarItem
+
+// calendarItem is a value, a range of values, or a repeating value as in
+// "0/15" or "1..10/2".  A negative end is unbounded.
+type calendarItem struct {
+	start, end, repeat int
+}
+
+// ParseOnCalendar parses a systemd calendar event into a filter of slots of
+// the given length starting at every time that triggers the event.
+func ParseOnCalendar(spec string, slot time.Duration) (Filter, error) {
+	c, err := ParseCalendarSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	return c.Filter(slot), nil
+}
+
+// NormalizeOnCalendar returns the normalized form of a systemd calendar
+// event, as printed by "systemd-analyze calendar".
+func NormalizeOnCalendar(spec string) (string, error) {
+	c, err := ParseCalendarSpec(spec)
+	if err != nil {
+		return "", err
+	}
+	return c.String(), nil
+}
+
+// ParseCalendarSpec parses a systemd calendar event in the
+// "DayOfWeek Year-Month-Day Hour:Minute:Second TimeZone" format, where every
+// part is optional.  Components may be lists, ranges as in "Mon..Fri" or
+// "1..7", repetitions as in "0/15", or "*".  A "~" before the day counts back
+// from the end of the month.  The shorthands such as "daily" and "weekly" are
+// also accepted.
+func ParseCalendarSpec(spec string) (*CalendarSpec, error) {
+	fields := strings.Fields(spec)
+	if len(fields) > 0 {
+		if s, ok := calendarShorthands[strings.ToLower(fields[0])]; ok {
+			fields = append(strings.Fields(s), fields[1:]...)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("invalid calendar spec %q", spec)
+	}
+
+	var (
+		c   CalendarSpec
+		err error
+	)
+
+	// the optional parts must appear in order
+	if len(fields) > 0 && isCalendarWeekdays(fields[0]) {
+		if c.weekdays, err = parseCalendarWeekdays(fields[0]); err != nil {
+			return nil, err
+		}
+		fields = fields[1:]
+	}
+	if len(fields) > 0 && !strings.Contains(fields[0], ":") && strings.ContainsAny(fields[0], "-~") {
+		if err = c.parseDate(fields[0]); err != nil {
+			return nil, err
+		}
+		fields = fields[1:]
+	}
+	if len(fields) > 0 && strings.Contains(fields[0], ":") {
+		if err = c.parseTime(fields[0]); err != nil {
+			return nil, err
+		}
+		fields = fields[1:]
+	} else {
+		c.hour, c.minute, c.second = calendarChain{{0, 0, 0}}, calendarChain{{0, 0, 0}}, calendarChain{{0, 0, 0}}
+	}
+	if len(fields) == 1 {
+		if c.loc, err = time.LoadLocation(fields[0]); err != nil {
+			return nil, fmt.Errorf("invalid calendar spec %q: unknown time zone %q", spec, fields[0])
+		}
+		c.zone = fields[0]
+		fields = fields[1:]
+	}
+	if len(fields) > 0 {
+		return nil, fmt.Errorf("invalid calendar spec %q: unexpected %q", spec, fields[0])
+	}
+
+	return &c, nil
+}
+
+// isCalendarWeekdays returns true if the field starts with a weekday name.
+func isCalendarWeekdays(field string) bool {
+	name := field
+	if i := strings.IndexAny(name, ",."); i >= 0 {
+		name = name[:i]
+	}
+	_, ok := calendarWeekday(name)
+	return ok
+}
+
+// parseCalendarWeekdays parses a list of weekdays and weekday ranges into a
+// bit per weekday.  Ranges may wrap around the end of the week.
+func parseCalendarWeekdays(field string) (uint8, error) {
+	var set uint8
+
+	for _, item := range strings.Split(field, ",") {
+		names := strings.SplitN(item, "..", 2)
+		from, ok := calendarWeekday(names[0])
+		if !ok {
+			return 0, fmt.Errorf("invalid weekday %q", names[0])
+		}
+		to := from
+		if len(names) == 2 {
+			if to, ok = calendarWeekday(names[1]); !ok {
+				return 0, fmt.Errorf("invalid weekday %q", names[1])
+			}
+		}
+
+		for d := from; ; d = (d + 1) % 7 {
+			set |= 1 << uint(d)
+			if d == to {
+				break
+			}
+		}
+	}
+
+	return set, nil
+}
+
+// calendarWeekday returns the weekday of a full or abbreviated name.
+func calendarWeekday(name string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(name, d.String()) || strings.EqualFold(name, d.String()[:3]) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// parseDate parses the year, month and day, where the year may be omitted.
+func (c *CalendarSpec) parseDate(field string) error {
+	date := field
+	if i := strings.IndexByte(date, '~'); i >= 0 {
+		c.endOfMonth = true
+		date = date[:i] + "-" + date[i+1:]
+	}
+
+	parts := strings.Split(date, "-")
+	if len(parts) == 2 {
+		parts = append([]string{"*"}, parts...)
+	}
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid date %q", field)
+	}
+
+	var err error
+	if c.year, err = parseCalendarChain(parts[0], 1970, calendarMaxYear); err != nil {
+		return err
+	}
+	if c.month, err = parseCalendarChain(parts[1], 1, 12); err != nil {
+		return err
+	}
+	c.day, err = parseCalendarChain(parts[2], 1, 31)
+	return err
+}
+
+// parseTime parses the hour, minute and optional second.
+func (c *CalendarSpec) parseTime(field string) error {
+	parts := strings.Split(field, ":")
+	if len(parts) == 2 {
+		parts = append(parts, "00")
+	}
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid time %q", field)
+	}
+
+	var err error
+	if c.hour, err = parseCalendarChain(parts[0], 0, 23); err != nil {
+		return err
+	}
+	if c.minute, err = parseCalendarChain(parts[1], 0, 59); err != nil {
+		return err
+	}
+	c.second, err = parseCalendarChain(parts[2], 0, 59)
+	return err
+}
+
+// parseCalendarChain parses a list of values, ranges and repetitions within
+// the bounds.  Returns nil for "*".
+func parseCalendarChain(s string, lo, hi int) (calendarChain, error) {
+	if s == "*" {
+		return nil, nil
+	}
+
+	var chain calendarChain
+	for _, v := range strings.Split(s, ",") {
+		var (
+			item   = calendarItem{end: -1}
+			err    error
+			bounds = v
+		)
+
+		if i := strings.IndexByte(v, '/'); i >= 0 {
+			bounds = v[:i]
+			if item.repeat, err = strconv.Atoi(v[i+1:]); err != nil || item.repeat <= 0 {
+				return nil, fmt.Errorf("invalid repetition %q", v)
+			}
+		}
+
+		ends := strings.SplitN(bounds, "..", 2)
+		if item.start, err = strconv.Atoi(ends[0]); err != nil || item.start < lo || item.start > hi {
+			return nil, fmt.Errorf("invalid value %q, expected %d..%d", v, lo, hi)
+		}
+		if len(ends) == 2 {
+			if item.end, err = strconv.Atoi(ends[1]); err != nil || item.end < item.start || item.end > hi {
+				return nil, fmt.Errorf("invalid range %q", v)
+			}
+		} else if item.repeat == 0 {
+			item.end = item.start
+		}
+
+		chain = append(chain, item)
+	}
+
+	// sort and remove duplicates like systemd
+	sort.Slice(chain, func(i, j int) bool {
+		a, b := chain[i], chain[j]
+		if a.start != b.start {
+			return a.start < b.start
+		}
+		if a.end != b.end {
+			return a.end < b.end
+		}
+		return a.repeat < b.repeat
+	})
+	var result calendarChain
+	for i, item := range chain {
+		if i == 0 || item != chain[i-1] {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+// matches returns true if the value is in the chain.
+func (c calendarChain) matches(v int) bool {
+	if c == nil {
+		return true
+	}
+	for _, item := range c {
+		if v < item.start || item.end >= 0 && v > item.end {
+			continue
+		}
+		if item.repeat == 0 || (v-item.start)%item.repeat == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesBackward returns true if the value is in the chain, where repetitions
+// count down from their start.
+func (c calendarChain) matchesBackward(v int) bool {
+	if c == nil {
+		return true
+	}
+	for _, item := range c {
+		switch {
+		case item.repeat == 0:
+			if v >= item.start && v <= item.end {
+				return true
+			}
+		case v <= item.start && (item.end < 0 || v >= item.end) && (item.start-v)%item.repeat == 0:
+			return true
+		}
+	}
+	return false
+}
+
+// matchesDay returns true if the date matches the weekdays and days.
+func (c *CalendarSpec) matchesDay(t time.Time) bool {
+	if c.weekdays != 0 && c.weekdays&(1<<uint(t.Weekday())) == 0 {
+		return false
+	}
+	if c.endOfMonth {
+		return c.day.matchesBackward(daysIn(t.Month(), t.Year()) - t.Day() + 1)
+	}
+	return c.day.matches(t.Day())
+}
+
+// Next returns the first time at or after t that triggers the event.  Returns
+// false if the event never triggers again.
+func (c *CalendarSpec) Next(t time.Time) (time.Time, bool) {
+	loc := t.Location()
+	if c.loc != nil {
+		loc, t = c.loc, t.In(c.loc)
+	}
+	if t.Nanosecond() > 0 {
+		t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	}
+
+	for t.Year() <= calendarMaxYear {
+		var (
+			y, m, d  = t.Date()
+			h, mi, s = t.Clock()
+			next     time.Time
+		)
+
+		switch {
+		case !c.year.matches(y):
+			next = time.Date(y+1, 1, 1, 0, 0, 0, 0, loc)
+		case !c.month.matches(int(m)):
+			next = time.Date(y, m+1, 1, 0, 0, 0, 0, loc)
+		case !c.matchesDay(t):
+			next = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+		case !c.hour.matches(h):
+			next = time.Date(y, m, d, h+1, 0, 0, 0, loc)
+		case !c.minute.matches(mi):
+			next = time.Date(y, m, d, h, mi+1, 0, 0, loc)
+		case !c.second.matches(s):
+			next = time.Date(y, m, d, h, mi, s+1, 0, loc)
+		default:
+			return t, true
+		}
+
+		// the wall clock may repeat when daylight saving time ends
+		if !next.After(t) {
+			next = t.Add(time.Second)
+		}
+		t = next
+	}
+
+	return time.Time{}, false
+}
+
+// Filter returns a filter of slots of the given length starting at every
+// time that triggers the event.
+func (c *CalendarSpec) Filter(slot time.Duration) Filter {
+	return func(input TimeRange) []*TimeRange {
+		var (
+			result []*TimeRange
+			t      = input.Start.Add(-slot).Add(time.Second)
+		)
+
+		for {
+			next, ok := c.Next(t)
+			if !ok || !next.Before(input.End) {
+				break
+			}
+
+			tr := TimeRange{Start: next.In(input.Start.Location()), End: next.Add(slot).In(input.Start.Location())}
+			if clip(&tr, input) {
+				result = append(result, &tr)
+			}
+			t = next.Add(time.Second)
+		}
+
+		Merge(&result)
+		return result
+	}
+}
+
+// String returns the normalized form of the event.
+func (c *CalendarSpec) String() string {
+	var buf strings.Builder
+
+	if c.weekdays != 0 {
+		buf.WriteString(formatCalendarWeekdays(c.weekdays))
+		buf.WriteString(" ")
+	}
+
+	buf.WriteString(c.year.format(4))
+	buf.WriteString("-")
+	buf.WriteString(c.month.format(2))
+	if c.endOfMonth {
+		buf.WriteString("~")
+	} else {
+		buf.WriteString("-")
+	}
+	buf.WriteString(c.day.format(2))
+	buf.WriteString(" ")
+	buf.WriteString(c.hour.format(2))
+	buf.WriteString(":")
+	buf.WriteString(c.minute.format(2))
+	buf.WriteString(":")
+	buf.WriteString(c.second.format(2))
+
+	if c.zone != "" {
+		buf.WriteString(" ")
+		buf.WriteString(c.zone)
+	}
+	return buf.String()
+}
+
+// formatCalendarWeekdays formats weekdays from Monday, joining runs of three
+// or more days into ranges.
+func formatCalendarWeekdays(set uint8) string {
+	var (
+		buf   strings.Builder
+		start = -1
+	)
+
+	// index 7 is past Sunday, closing the last run
+	for x := 0; x <= 7; x++ {
+		in := x < 7 && set&(1<<uint((x+1)%7)) != 0
+		switch {
+		case in && start < 0:
+			if buf.Len() > 0 {
+				buf.WriteString(",")
+			}
+			buf.WriteString(time.Weekday((x + 1) % 7).String()[:3])
+			start = x
+		case !in && start >= 0:
+			if x > start+1 {
+				if x > start+2 {
+					buf.WriteString("..")
+				} else {
+					buf.WriteString(",")
+				}
+				buf.WriteString(time.Weekday(x % 7).String()[:3])
+			}
+			start = -1
+		}
+	}
+
+	return buf.String()
+}
+
+// format formats the chain with zero padded values.
+func (c calendarChain) format(width int) string {
+	if c == nil {
+		return "*"
+	}
+
+	var items []string
+	for _, item := range c {
+		s := fmt.Sprintf("%0*d", width, item.start)
+		if item.end >= 0 && item.end != item.start {
+			s += fmt.Sprintf("..%0*d", width, item.end)
+		}
+		if item.repeat > 0 {
+			s += "/" + strconv.Itoa(item.repeat)
+		}
+		items = append(items, s)
+	}
+	return strings.Join(items, ",")
+}
@@ -0,0 +1,107 @@
+package timewarp_test
+
+import (
+	"time"
+
+	"github.com/takeinitiative/timewarp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OnCalendar", func() {
+	const datefmt = "2006-01-02 15:04:05"
+
+	DescribeTable("NormalizeOnCalendar",
+		func(in, normalized string) {
+			s, err := timewarp.NormalizeOnCalendar(in)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(s).To(Equal(normalized))
+
+			// the normalized form is stable
+			s, err = timewarp.NormalizeOnCalendar(s)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(s).To(Equal(normalized))
+		},
+		Entry("daily", "daily", "*-*-* 00:00:00"),
+		Entry("weekly", "weekly", "Mon *-*-* 00:00:00"),
+		Entry("quarterly", "quarterly", "*-01,04,07,10-01 00:00:00"),
+		Entry("minutely", "minutely", "*-*-* *:*:00"),
+		Entry("weekday range", "Mon..Fri 9:00", "Mon..Fri *-*-* 09:00:00"),
+		Entry("two weekdays", "Sat,Sun 10:30", "Sat,Sun *-*-* 10:30:00"),
+		Entry("weekday list", "Fri,Mon,Tue,Wed", "Mon..Wed,Fri *-*-* 00:00:00"),
+		Entry("wrapping weekdays", "Sat..Mon", "Mon,Sat,Sun *-*-* 00:00:00"),
+		Entry("full names", "wednesday 12:00", "Wed *-*-* 12:00:00"),
+		Entry("date", "2024-3-5", "2024-03-05 00:00:00"),
+		Entry("month and day", "12-25 08:00", "*-12-25 08:00:00"),
+		Entry("repetition", "*:0/15", "*-*-* *:00/15:00"),
+		Entry("range repetition", "*-*-1..20/5 6..18/2:00", "*-*-01..20/5 06..18/2:00:00"),
+		Entry("sorted list", "*-*-* 18,06,12:00", "*-*-* 06,12,18:00:00"),
+		Entry("last day", "*-*~01", "*-*~01 00:00:00"),
+		Entry("last monday", "Mon *-05~07/1", "Mon *-05~07/1 00:00:00"),
+		Entry("time zone", "Sun 03:00 UTC", "Sun *-*-* 03:00:00 UTC"),
+	)
+
+	DescribeTable("Invalid",
+		func(in string) {
+			_, err := timewarp.NormalizeOnCalendar(in)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("empty", ""),
+		Entry("bad weekday", "Mon..Xyz"),
+		Entry("bad month", "*-13-01"),
+		Entry("bad hour", "24:00"),
+		Entry("bad repetition", "*:0/0"),
+		Entry("reversed range", "*-*-10..5"),
+		Entry("bad zone", "daily Mars/Olympus"),
+		Entry("trailing field", "Mon 10:00 UTC extra"),
+	)
+
+	DescribeTable("ParseOnCalendar",
+		func(in string, slot time.Duration, start, end string, expected ...string) {
+			f, err := timewarp.ParseOnCalendar(in, slot)
+			Expect(err).NotTo(HaveOccurred())
+
+			r, _ := timewarp.Parse(datefmt, start, end)
+			var out []string
+			for _, v := range f(*r) {
+				out = append(out, v.Start.Format(datefmt)+" - "+v.End.Format(datefmt))
+			}
+			if len(expected) == 0 {
+				Expect(out).To(BeEmpty())
+			} else {
+				Expect(out).To(Equal(expected))
+			}
+		},
+		Entry("maintenance window", "Sun 02:00", 2*time.Hour, "2024-01-01 00:00:00", "2024-01-15 00:00:00",
+			"2024-01-07 02:00:00 - 2024-01-07 04:00:00",
+			"2024-01-14 02:00:00 - 2024-01-14 04:00:00"),
+		Entry("slot in progress", "Sun 02:00", 2*time.Hour, "2024-01-07 03:00:00", "2024-01-08 00:00:00",
+			"2024-01-07 03:00:00 - 2024-01-07 04:00:00"),
+		Entry("repetition", "*:0/20", 5*time.Minute, "2024-01-01 10:00:00", "2024-01-01 11:00:00",
+			"2024-01-01 10:00:00 - 2024-01-01 10:05:00",
+			"2024-01-01 10:20:00 - 2024-01-01 10:25:00",
+			"2024-01-01 10:40:00 - 2024-01-01 10:45:00"),
+		Entry("last day of february", "*-02~01 12:00", time.Hour, "2024-01-01 00:00:00", "2025-03-01 00:00:00",
+			"2024-02-29 12:00:00 - 2024-02-29 13:00:00",
+			"2025-02-28 12:00:00 - 2025-02-28 13:00:00"),
+		Entry("last monday of may", "Mon *-05~07/1", 24*time.Hour, "2024-01-01 00:00:00", "2025-01-01 00:00:00",
+			"2024-05-27 00:00:00 - 2024-05-28 00:00:00"),
+		Entry("overlapping slots merge", "*:*:0/30", time.Minute, "2024-01-01 00:00:00", "2024-01-01 00:02:00",
+			"2024-01-01 00:00:00 - 2024-01-01 00:02:00"),
+		Entry("past years", "2020-01-01", time.Hour, "2024-01-01 00:00:00", "2025-01-01 00:00:00"),
+	)
+
+	Describe("Next", func() {
+		It("should convert to the time zone of the spec", func() {
+			c, err := timewarp.ParseCalendarSpec("daily UTC")
+			Expect(err).NotTo(HaveOccurred())
+
+			loc := time.FixedZone("EST", -5*60*60)
+			next, ok := c.Next(time.Date(2024, 1, 1, 12, 0, 0, 0, loc))
+			Expect(ok).To(BeTrue())
+			Expect(next).To(BeTemporally("==", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)))
+		})
+	})
+})
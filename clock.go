@@ -0,0 +1,218 @@
+package timewarp
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is a source of the current time.  APIs that depend on the current
+// time accept a clock so that they can be tested with a FakeClock.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After waits for the duration to elapse and then sends the current time
+	// on the returned channel.
+	After(d time.Duration) <-chan time.Time
+
+	// NewTimer returns a timer that sends the current time on its channel
+	// after the duration.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a single event created by a Clock.
+type Timer interface {
+	// C returns the channel on which the time is delivered.
+	C() <-chan time.Time
+
+	// Stop prevents the timer from firing.  Returns false if the timer has
+	// already fired or been stopped.
+	Stop() bool
+
+	// Reset changes the timer to fire after the duration.  Returns true if
+	// the timer had been active.
+	Reset(d time.Duration) bool
+}
+
+// RealClock is the clock of the system.
+type RealClock struct{}
+
+// Now returns the current time.
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// After waits for the duration to elapse and then sends the current time on
+// the returned channel.
+func (RealClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// NewTimer returns a system timer.
+func (RealClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+// realTimer adapts a system timer to the Timer interface.
+type realTimer struct {
+	t *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time        { return t.t.C }
+func (t realTimer) Stop() bool                 { return t.t.Stop() }
+func (t realTimer) Reset(d time.Duration) bool { return t.t.Reset(d) }
+
+// FakeClock is a clock that only moves when it is advanced.  Timers fire in
+// order of their deadlines as the clock passes them, each receiving its
+// deadline.
+type FakeClock struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// NewFakeClock returns a fake clock set to the time.
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the time of the clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns the channel of a new timer.
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// NewTimer returns a timer that fires when the clock is advanced past the
+// duration.  A timer with no duration fires immediately.
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{clock: c, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// Advance moves the clock forward by the duration, firing the timers that
+// are due.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(c.now.Add(d))
+}
+
+// Set moves the clock to the time, firing the timers that are due.  The clock
+// never moves backwards.
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t.After(c.now) {
+		c.set(t)
+	}
+}
+
+// set moves the clock while holding the lock.
+func (c *FakeClock) set(t time.Time) {
+	c.now = t
+
+	var pending []*fakeTimer
+	for _, timer := range c.timers {
+		if timer.deadline.After(t) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.fire()
+	}
+	c.timers = pending
+	c.cond.Broadcast()
+}
+
+// Next returns the deadline of the earliest pending timer.  Returns false if
+// there are no pending timers.
+func (c *FakeClock) Next() (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.timers) == 0 {
+		return time.Time{}, false
+	}
+	return c.timers[0].deadline, true
+}
+
+// BlockUntil blocks until there are at least n pending timers.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
+
+// fakeTimer is a timer of a FakeClock.
+type fakeTimer struct {
+	clock    *FakeClock
+	c        chan time.Time
+	deadline time.Time
+}
+
+// C returns the channel on which the deadline is delivered.
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+// Stop removes the timer from the clock.
+func (t *fakeTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return t.remove()
+}
+
+// Reset schedules the timer after the duration from the time of the clock.
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	active := t.remove()
+	t.deadline = c.now.Add(d)
+	if d <= 0 {
+		t.fire()
+		return active
+	}
+
+	c.timers = append(c.timers, t)
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		return c.timers[i].deadline.Before(c.timers[j].deadline)
+	})
+	c.cond.Broadcast()
+	return active
+}
+
+// remove removes the timer from the pending timers of the clock.  Returns
+// true if it was pending.
+func (t *fakeTimer) remove() bool {
+	c := t.clock
+	for i, timer := range c.timers {
+		if timer == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			c.cond.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+// fire delivers the deadline without blocking, like a system timer.
+func (t *fakeTimer) fire() {
+	select {
+	case t.c <- t.deadline:
+	default:
+	}
+}
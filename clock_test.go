@@ -0,0 +1,113 @@
+package timewarp_test
+
+import (
+	"time"
+
+	"github.com/takeinitiative/timewarp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Clock", func() {
+	Describe("RealClock", func() {
+		It("should tell the time", func() {
+			Expect(timewarp.RealClock{}.Now()).To(BeTemporally("~", time.Now(), time.Second))
+		})
+
+		It("should fire timers", func() {
+			t := timewarp.RealClock{}.NewTimer(time.Millisecond)
+			Eventually(t.C()).Should(Receive())
+			Expect(t.Stop()).To(BeFalse())
+		})
+	})
+
+	Describe("FakeClock", func() {
+		var (
+			clock *timewarp.FakeClock
+			start = time.Date(2016, 11, 7, 8, 0, 0, 0, time.UTC)
+		)
+
+		BeforeEach(func() {
+			clock = timewarp.NewFakeClock(start)
+		})
+
+		It("should only move when advanced", func() {
+			Expect(clock.Now()).To(Equal(start))
+
+			clock.Advance(time.Hour)
+			Expect(clock.Now()).To(Equal(start.Add(time.Hour)))
+
+			clock.Set(start)
+			Expect(clock.Now()).To(Equal(start.Add(time.Hour)))
+		})
+
+		It("should fire timers at their deadlines", func() {
+			var (
+				a = clock.NewTimer(2 * time.Hour)
+				b = clock.After(time.Hour)
+			)
+			next, ok := clock.Next()
+			Expect(ok).To(BeTrue())
+			Expect(next).To(Equal(start.Add(time.Hour)))
+
+			clock.Advance(time.Hour - time.Nanosecond)
+			Expect(b).NotTo(Receive())
+
+			clock.Advance(time.Nanosecond)
+			Expect(b).To(Receive(Equal(start.Add(time.Hour))))
+			Expect(a.C()).NotTo(Receive())
+
+			clock.Advance(3 * time.Hour)
+			Expect(a.C()).To(Receive(Equal(start.Add(2 * time.Hour))))
+			Expect(clock.Next()).To(BeZero())
+		})
+
+		It("should fire a timer without duration immediately", func() {
+			Expect(clock.After(0)).To(Receive(Equal(start)))
+		})
+
+		It("should stop and reset timers", func() {
+			t := clock.NewTimer(time.Hour)
+			Expect(t.Stop()).To(BeTrue())
+			Expect(t.Stop()).To(BeFalse())
+
+			clock.Advance(time.Hour)
+			Expect(t.C()).NotTo(Receive())
+
+			Expect(t.Reset(time.Hour)).To(BeFalse())
+			Expect(t.Reset(2 * time.Hour)).To(BeTrue())
+			clock.Advance(time.Hour)
+			Expect(t.C()).NotTo(Receive())
+			clock.Advance(time.Hour)
+			Expect(t.C()).To(Receive(Equal(start.Add(3 * time.Hour))))
+		})
+
+		It("should block until timers are waiting", func() {
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				clock.BlockUntil(2)
+			}()
+
+			clock.NewTimer(time.Hour)
+			Consistently(done, 50*time.Millisecond).ShouldNot(BeClosed())
+
+			clock.NewTimer(time.Hour)
+			Eventually(done).Should(BeClosed())
+		})
+	})
+
+	Describe("Current", func() {
+		It("should return the range at the time of the clock", func() {
+			f, err := timewarp.ParseString(`DAY MONDAY FRIDAY IN TIME 0900 1700`)
+			Expect(err).NotTo(HaveOccurred())
+
+			clock := timewarp.NewFakeClock(time.Date(2016, 11, 7, 8, 0, 0, 0, time.UTC))
+			Expect(f.Current(clock)).To(BeNil())
+
+			clock.Advance(2 * time.Hour)
+			Expect(f.Current(clock).String()).To(Equal("2016-11-07T09:00:00Z/2016-11-07T17:00:00Z"))
+		})
+	})
+})
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/takeinitiative/timewarp"
+)
+
+// document is an open text document of expressions separated by semicolons.
+type document struct {
+	uri     string
+	version int
+	text    string
+	lines   []int // byte offsets of the line starts
+	stmts   []*statement
+}
+
+// statement is the text between two semicolons of a document.
+type statement struct {
+	from, to   int // byte offsets of the text between the semicolons
+	start, end int // byte offsets of the first token and after the last one
+	base       timewarp.Pos
+	comments   bool // true if a comment is between the tokens
+	expr       timewarp.Expr
+	err        error
+}
+
+// empty returns true if the statement has no tokens.
+func (st *statement) empty() bool {
+	return st.start < 0
+}
+
+// pos returns the document position of a position in the statement text.
+func (st *statement) pos(p timewarp.Pos) timewarp.Pos {
+	return shift(st.base, p)
+}
+
+// shift returns the position of p in text beginning at the base position.
+func shift(base, p timewarp.Pos) timewarp.Pos {
+	if p.Line == 0 {
+		return timewarp.Pos{Line: base.Line, Char: base.Char + p.Char}
+	}
+	return timewarp.Pos{Line: base.Line + p.Line, Char: p.Char}
+}
+
+// newDocument splits the text into statements and parses them.
+func newDocument(uri string, version int, text string) *document {
+	d := &document{uri: uri, version: version, text: text, lines: []int{0}}
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\r':
+			if i+1 < len(text) && text[i+1] == '\n' {
+				i++
+			}
+			d.lines = append(d.lines, i+1)
+		case '\n':
+			d.lines = append(d.lines, i+1)
+		}
+	}
+
+	d.split()
+	for _, st := range d.stmts {
+		if !st.empty() {
+			st.expr, st.err = timewarp.ParseExprString(text[st.start:st.end])
+		}
+	}
+	return d
+}
+
+// split scans the text for the statements between semicolons.
+func (d *document) split() {
+	var (
+		s       = timewarp.NewScanner(strings.NewReader(d.text))
+		st      = &statement{start: -1}
+		last    bool // true if the last token was part of the statement
+		comment bool // true if a comment follows the last token
+	)
+	for {
+		tok, pos, _ := s.Scan()
+		off := d.offset(pos)
+		if last {
+			st.end = off
+		}
+
+		switch tok {
+		case timewarp.WS:
+			last = false
+		case timewarp.COMMENT:
+			last, comment = false, !st.empty()
+		case timewarp.SEMICOLON, timewarp.EOF:
+			st.to = off
+			if !st.empty() {
+				st.base = d.pos(st.start)
+			}
+			d.stmts = append(d.stmts, st)
+			if tok == timewarp.EOF {
+				return
+			}
+			st = &statement{from: off + 1, start: -1}
+			last, comment = false, false
+		default:
+			if st.empty() {
+				st.start = off
+			}
+			st.comments = st.comments || comment
+			last, comment = true, false
+		}
+	}
+}
+
+// statementAt returns the statement containing the byte offset.
+func (d *document) statementAt(off int) *statement {
+	for _, st := range d.stmts {
+		if off >= st.from && off <= st.to {
+			return st
+		}
+	}
+	return nil
+}
+
+// offset returns the byte offset of a position counted in runes.
+func (d *document) offset(p timewarp.Pos) int {
+	if p.Line >= len(d.lines) {
+		return len(d.text)
+	}
+
+	off := d.lines[p.Line]
+	for n := 0; n < p.Char && off < len(d.text); n++ {
+		if ch := d.text[off]; ch == '\r' || ch == '\n' {
+			break
+		}
+		_, size := utf8.DecodeRuneInString(d.text[off:])
+		off += size
+	}
+	return off
+}
+
+// pos returns the position, counted in runes, of the byte offset.
+func (d *document) pos(off int) timewarp.Pos {
+	line := d.line(off)
+	return timewarp.Pos{Line: line, Char: utf8.RuneCountInString(d.text[d.lines[line]:off])}
+}
+
+// line returns the line of the byte offset.
+func (d *document) line(off int) int {
+	return sort.Search(len(d.lines), func(i int) bool { return d.lines[i] > off }) - 1
+}
+
+// position returns the protocol position of the byte offset.
+func (d *document) position(off int) position {
+	line := d.line(off)
+
+	var n int
+	for _, ch := range d.text[d.lines[line]:off] {
+		n += utf16Len(ch)
+	}
+	return position{Line: line, Character: n}
+}
+
+// offsetAt returns the byte offset of the protocol position, clamped to the
+// end of its line.
+func (d *document) offsetAt(p position) int {
+	if p.Line < 0 {
+		return 0
+	} else if p.Line >= len(d.lines) {
+		return len(d.text)
+	}
+
+	off := d.lines[p.Line]
+	for n := 0; n < p.Character && off < len(d.text); {
+		ch, size := utf8.DecodeRuneInString(d.text[off:])
+		if ch == '\r' || ch == '\n' {
+			break
+		}
+		n += utf16Len(ch)
+		off += size
+	}
+	return off
+}
+
+// textRange returns the protocol range between the byte offsets.
+func (d *document) textRange(from, to int) textRange {
+	return textRange{Start: d.position(from), End: d.position(to)}
+}
+
+// tokenEnd returns the byte offset after the token at the byte offset.
+func (d *document) tokenEnd(off int) int {
+	s := timewarp.NewScanner(strings.NewReader(d.text[off:]))
+	if tok, _, _ := s.Scan(); tok == timewarp.EOF {
+		return off
+	}
+	_, p, _ := s.Scan()
+	return d.offset(shift(d.pos(off), p))
+}
+
+// utf16Len returns the number of UTF-16 code units encoding the rune.
+func utf16Len(ch rune) int {
+	if ch > 0xFFFF {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"github.com/takeinitiative/timewarp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("document", func() {
+	var d *document
+
+	BeforeEach(func() {
+		d = newDocument("file:///a.twq", 1, "DAY é;\r\n# 😀 x\rDAY AND ;  ")
+	})
+
+	It("should split the statements between semicolons", func() {
+		Expect(d.stmts).To(HaveLen(3))
+
+		Expect(d.text[d.stmts[0].start:d.stmts[0].end]).To(Equal("DAY é"))
+		Expect(d.stmts[0].err).To(HaveOccurred())
+
+		Expect(d.text[d.stmts[1].start:d.stmts[1].end]).To(Equal("DAY AND"))
+		Expect(d.stmts[1].base).To(Equal(timewarp.Pos{Line: 2, Char: 0}))
+		Expect(d.stmts[1].comments).To(BeFalse())
+
+		Expect(d.stmts[2].empty()).To(BeTrue())
+		Expect(d.statementAt(len(d.text))).To(Equal(d.stmts[2]))
+	})
+
+	It("should convert between offsets and positions", func() {
+		// the emoji is one rune and two UTF-16 code units
+		off := len("DAY é;\r\n# 😀")
+		Expect(d.pos(off)).To(Equal(timewarp.Pos{Line: 1, Char: 3}))
+		Expect(d.offset(timewarp.Pos{Line: 1, Char: 3})).To(Equal(off))
+		Expect(d.position(off)).To(Equal(position{Line: 1, Character: 4}))
+		Expect(d.offsetAt(position{Line: 1, Character: 4})).To(Equal(off))
+	})
+
+	It("should clamp positions to the end of the line", func() {
+		Expect(d.offsetAt(position{Line: 0, Character: 99})).To(Equal(len("DAY é;")))
+		Expect(d.offsetAt(position{Line: 9, Character: 0})).To(Equal(len(d.text)))
+	})
+
+	It("should find the end of a token", func() {
+		Expect(d.tokenEnd(len("DAY é;\r\n# 😀 x\rDAY "))).To(Equal(len("DAY é;\r\n# 😀 x\rDAY AND")))
+	})
+})
@@ -0,0 +1,30 @@
+// Command timewarp-lsp is a Language Server Protocol server for timerangeQL
+// documents, speaking JSON-RPC over stdio.
+//
+// A document holds expressions separated by semicolons.  The server reports
+// parse errors and lint diagnostics, completes the keywords the parser accepts
+// at the cursor, previews the next occurrences of an expression on hover and
+// formats expressions in their canonical form.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/takeinitiative/timewarp"
+)
+
+func main() {
+	os.Exit(run(os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run serves the protocol until the client exits and returns the exit code.
+func run(stdin io.Reader, stdout, stderr io.Writer) int {
+	s := newServer(stdin, stdout, timewarp.RealClock{})
+	code, err := s.serve()
+	if err != nil {
+		fmt.Fprintf(stderr, "timewarp-lsp: %s\n", err)
+	}
+	return code
+}
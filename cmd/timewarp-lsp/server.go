@@ -0,0 +1,438 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/takeinitiative/timewarp"
+)
+
+const (
+	// previewCount is the number of occurrences previewed on hover.
+	previewCount = 5
+
+	// previewHorizon is how far ahead occurrences are previewed.
+	previewHorizon = 366 * 24 * time.Hour
+
+	// previewLayout formats the times of previewed occurrences.
+	previewLayout = "Mon 2006-01-02 15:04"
+)
+
+// server is a language server for timerangeQL documents.
+type server struct {
+	conn        *conn
+	clock       timewarp.Clock
+	docs        map[string]*document
+	initialized bool
+	shutdown    bool
+}
+
+// newServer returns a server reading requests from r and writing responses
+// to w.  Occurrences are previewed from the current time of the clock.
+func newServer(r io.Reader, w io.Writer, clock timewarp.Clock) *server {
+	return &server{
+		conn:  newConn(r, w),
+		clock: clock,
+		docs:  make(map[string]*document),
+	}
+}
+
+// serve handles messages until the exit notification or the end of the input
+// and returns the exit code, which is zero only after a shutdown request.
+func (s *server) serve() (int, error) {
+	for {
+		m, err := s.conn.read()
+		if err == io.EOF {
+			return s.exitCode(), nil
+		} else if rerr, ok := err.(*responseError); ok {
+			if err := s.conn.reply(m.ID, nil, rerr); err != nil {
+				return 1, err
+			}
+			continue
+		} else if err != nil {
+			return 1, err
+		}
+
+		if m.Method == "exit" {
+			return s.exitCode(), nil
+		}
+
+		result, err := s.handle(m.Method, m.Params)
+		if m.ID == nil {
+			// notifications have no response
+			continue
+		}
+		if err := s.conn.reply(m.ID, result, err); err != nil {
+			return 1, err
+		}
+	}
+}
+
+// exitCode returns the exit code of the server.
+func (s *server) exitCode() int {
+	if s.shutdown {
+		return 0
+	}
+	return 1
+}
+
+// handle dispatches a request or notification by method.
+func (s *server) handle(method string, params json.RawMessage) (interface{}, error) {
+	switch {
+	case s.shutdown:
+		return nil, &responseError{Code: codeInvalidRequest, Message: "server is shut down"}
+	case method == "initialize":
+		s.initialized = true
+		return s.initialize(), nil
+	case !s.initialized:
+		return nil, &responseError{Code: codeServerNotInitialized, Message: "server is not initialized"}
+	}
+
+	switch method {
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var p didOpenTextDocumentParams
+		if err := unmarshalParams(params, &p); err != nil {
+			return nil, err
+		}
+		return nil, s.open(newDocument(p.TextDocument.URI, p.TextDocument.Version, p.TextDocument.Text))
+	case "textDocument/didChange":
+		var p didChangeTextDocumentParams
+		if err := unmarshalParams(params, &p); err != nil {
+			return nil, err
+		}
+		if len(p.ContentChanges) == 0 {
+			return nil, nil
+		}
+		text := p.ContentChanges[len(p.ContentChanges)-1].Text
+		return nil, s.open(newDocument(p.TextDocument.URI, p.TextDocument.Version, text))
+	case "textDocument/didClose":
+		var p didCloseTextDocumentParams
+		if err := unmarshalParams(params, &p); err != nil {
+			return nil, err
+		}
+		delete(s.docs, p.TextDocument.URI)
+		return nil, s.conn.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+			URI:         p.TextDocument.URI,
+			Diagnostics: []diagnostic{},
+		})
+
+	case "textDocument/completion":
+		var p textDocumentPositionParams
+		if err := unmarshalParams(params, &p); err != nil {
+			return nil, err
+		}
+		d, err := s.document(p.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return complete(d, d.offsetAt(p.Position)), nil
+	case "textDocument/hover":
+		var p textDocumentPositionParams
+		if err := unmarshalParams(params, &p); err != nil {
+			return nil, err
+		}
+		d, err := s.document(p.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		if h := s.hover(d, d.offsetAt(p.Position)); h != nil {
+			return h, nil
+		}
+		return nil, nil
+	case "textDocument/formatting":
+		var p documentFormattingParams
+		if err := unmarshalParams(params, &p); err != nil {
+			return nil, err
+		}
+		d, err := s.document(p.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return format(d), nil
+	}
+
+	return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q not found", method)}
+}
+
+// unmarshalParams decodes the params of a request.
+func unmarshalParams(params json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// initialize returns the capabilities of the server.
+func (s *server) initialize() initializeResult {
+	var r initializeResult
+	r.Capabilities.TextDocumentSync = textDocumentSyncFull
+	r.Capabilities.CompletionProvider.TriggerCharacters = []string{" ", "("}
+	r.Capabilities.HoverProvider = true
+	r.Capabilities.DocumentFormattingProvider = true
+	r.ServerInfo.Name = "timewarp-lsp"
+	return r
+}
+
+// document returns the open document with the URI.
+func (s *server) document(uri string) (*document, error) {
+	d, ok := s.docs[uri]
+	if !ok {
+		return nil, &responseError{Code: codeInvalidParams, Message: fmt.Sprintf("document %q is not open", uri)}
+	}
+	return d, nil
+}
+
+// open replaces the document and publishes its diagnostics.
+func (s *server) open(d *document) error {
+	s.docs[d.uri] = d
+
+	version := d.version
+	return s.conn.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         d.uri,
+		Version:     &version,
+		Diagnostics: diagnose(d),
+	})
+}
+
+// diagnose returns the parse errors and lint diagnostics of the document.
+func diagnose(d *document) []diagnostic {
+	diags := []diagnostic{}
+	for _, st := range d.stmts {
+		switch err := st.err.(type) {
+		case nil:
+		case *timewarp.ParseError:
+			off := d.offset(st.pos(err.Pos))
+			diags = append(diags, diagnostic{
+				Range:    d.textRange(off, d.tokenEnd(off)),
+				Severity: severityError,
+				Source:   "timewarp",
+				Message:  parseErrorMessage(err),
+			})
+		default:
+			diags = append(diags, diagnostic{
+				Range:    d.textRange(st.start, st.end),
+				Severity: severityError,
+				Source:   "timewarp",
+				Message:  err.Error(),
+			})
+		}
+
+		if st.expr == nil {
+			continue
+		}
+		for _, l := range timewarp.Lint(st.expr) {
+			off := d.offset(st.pos(l.Pos))
+			diags = append(diags, diagnostic{
+				Range:    d.textRange(off, d.tokenEnd(off)),
+				Severity: lintSeverity(l.Severity),
+				Source:   "timewarp",
+				Message:  l.Message,
+			})
+		}
+	}
+	return diags
+}
+
+// parseErrorMessage returns the message of a parse error without its position,
+// which the editor shows instead.
+func parseErrorMessage(err *timewarp.ParseError) string {
+	if err.Message != "" {
+		return err.Message
+	}
+	return fmt.Sprintf("found %s, expected %s", err.Found, strings.Join(err.Expected, ", "))
+}
+
+// lintSeverity returns the diagnostic severity of a lint severity.
+func lintSeverity(sev timewarp.Severity) int {
+	switch sev {
+	case timewarp.SeverityError:
+		return severityError
+	case timewarp.SeverityWarning:
+		return severityWarning
+	default:
+		return severityInformation
+	}
+}
+
+// complete returns the keywords the parser accepts at the byte offset.  Each
+// keyword is tried after the statement text preceding the word at the cursor,
+// and accepted if the parser reads past it.
+func complete(d *document, off int) completionList {
+	list := completionList{Items: []completionItem{}}
+
+	st := d.statementAt(off)
+	if st == nil {
+		return list
+	}
+
+	// the word being typed is replaced by the completion
+	from := off
+	for from > st.from {
+		ch, size := utf8.DecodeLastRuneInString(d.text[st.from:from])
+		if !isWordRune(ch) {
+			break
+		}
+		from -= size
+	}
+	prefix, word := d.text[st.from:from], d.text[from:off]
+	if inCommentOrString(prefix + word) {
+		return list
+	}
+
+	for _, c := range candidates() {
+		if !strings.HasPrefix(strings.ToLower(c), strings.ToLower(word)) || !accepts(prefix, c) {
+			continue
+		}
+		list.Items = append(list.Items, completionItem{
+			Label:    c,
+			Kind:     completionItemKeyword,
+			TextEdit: &textEdit{Range: d.textRange(from, off), NewText: c},
+		})
+	}
+	return list
+}
+
+// candidates returns the keywords and punctuation that can be completed.
+func candidates() []string {
+	c := []string{"("}
+	for tok := timewarp.AND; tok <= timewarp.SUNDAY; tok++ {
+		if timewarp.Lookup(tok.String()) == tok {
+			c = append(c, tok.String())
+		}
+	}
+	return c
+}
+
+// accepts returns true if the parser reads past the candidate when it follows
+// the prefix.
+func accepts(prefix, candidate string) bool {
+	src := prefix
+	if src != "" && !strings.HasSuffix(src, "(") && !isSpace(src[len(src)-1]) {
+		src += " "
+	}
+
+	// the candidate is the last token of the source
+	var (
+		s   = timewarp.NewScanner(strings.NewReader(src + candidate))
+		pos timewarp.Pos
+	)
+	for {
+		tok, p, _ := s.Scan()
+		if tok == timewarp.EOF {
+			break
+		} else if tok != timewarp.WS && tok != timewarp.COMMENT {
+			pos = p
+		}
+	}
+
+	_, err := timewarp.ParseExprString(src + candidate)
+	perr, ok := err.(*timewarp.ParseError)
+	switch {
+	case err == nil:
+		return true
+	case !ok:
+		return false
+	case perr.Pos.Line == pos.Line:
+		return perr.Pos.Char > pos.Char
+	default:
+		return perr.Pos.Line > pos.Line
+	}
+}
+
+// inCommentOrString returns true if the text ends inside a comment or an
+// unterminated string.
+func inCommentOrString(text string) bool {
+	var (
+		s    = timewarp.NewScanner(strings.NewReader(text))
+		last timewarp.Token
+		lit  string
+	)
+	for {
+		tok, _, l := s.Scan()
+		if tok == timewarp.EOF {
+			break
+		}
+		last, lit = tok, l
+	}
+	return last == timewarp.COMMENT || (last == timewarp.ILLEGAL && strings.HasPrefix(lit, `"`))
+}
+
+// hover returns a description and the next occurrences of the statement at the
+// byte offset, or nil if it is not a valid expression.
+func (s *server) hover(d *document, off int) *hover {
+	st := d.statementAt(off)
+	if st == nil || st.expr == nil || off < st.start || off > st.end {
+		return nil
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "`%s`\n\n%s\n\n", st.expr, timewarp.Describe(st.expr))
+
+	next := occurrences(st.expr.Filter(), s.clock.Now(), previewCount)
+	if len(next) == 0 {
+		buf.WriteString("No occurrences in the next year.\n")
+	} else {
+		buf.WriteString("Next occurrences:\n\n")
+		for _, r := range next {
+			fmt.Fprintf(&buf, "- %s – %s\n", r.Start.Format(previewLayout), r.End.Format(previewLayout))
+		}
+	}
+
+	r := d.textRange(st.start, st.end)
+	return &hover{
+		Contents: markupContent{Kind: "markdown", Value: buf.String()},
+		Range:    &r,
+	}
+}
+
+// occurrences returns up to n ranges of the filter that end after now, within
+// the preview horizon.  Ranges already in progress are included, so the
+// filter is evaluated from a week before now.
+func occurrences(f timewarp.Filter, now time.Time, n int) []*timewarp.TimeRange {
+	var next []*timewarp.TimeRange
+	for _, r := range f(timewarp.TimeRange{Start: now.AddDate(0, 0, -7), End: now.Add(previewHorizon)}) {
+		if len(next) == n {
+			break
+		} else if r.End.After(now) {
+			next = append(next, r)
+		}
+	}
+	return next
+}
+
+// format returns the edits replacing each valid expression with its canonical
+// form.  Expressions with comments between their tokens are left unchanged so
+// that the comments are kept.
+func format(d *document) []textEdit {
+	edits := []textEdit{}
+	for _, st := range d.stmts {
+		if st.expr == nil || st.comments {
+			continue
+		}
+		if s := st.expr.String(); s != d.text[st.start:st.end] {
+			edits = append(edits, textEdit{Range: d.textRange(st.start, st.end), NewText: s})
+		}
+	}
+	return edits
+}
+
+// isWordRune returns true if the rune can be part of a keyword or number.
+func isWordRune(ch rune) bool {
+	return unicode.IsLetter(ch) || unicode.IsDigit(ch)
+}
+
+// isSpace returns true if the byte is whitespace.
+func isSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n'
+}
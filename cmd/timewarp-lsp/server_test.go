@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/takeinitiative/timewarp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// client is an in-process JSON-RPC client of a server.
+type client struct {
+	conn     *conn
+	w        io.Closer
+	id       int
+	messages chan *message
+	notes    []*message
+}
+
+// newClient starts a server and returns its client along with a channel of
+// the exit code of the server.
+func newClient(clock timewarp.Clock) (*client, <-chan int) {
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+
+	code := make(chan int, 1)
+	go func() {
+		n, _ := newServer(sr, sw, clock).serve()
+		_ = sw.Close()
+		code <- n
+	}()
+
+	c := &client{conn: newConn(cr, cw), w: cw, messages: make(chan *message, 64)}
+	go func() {
+		defer close(c.messages)
+		for {
+			m, err := c.conn.read()
+			if err != nil {
+				return
+			}
+			c.messages <- m
+		}
+	}()
+	return c, code
+}
+
+// call sends a request and decodes the result of its response.
+func (c *client) call(method string, params, result interface{}) error {
+	c.id++
+	id := json.RawMessage(strconv.Itoa(c.id))
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	if err := c.conn.write(&message{ID: &id, Method: method, Params: data}); err != nil {
+		return err
+	}
+
+	for m := range c.messages {
+		if m.ID == nil {
+			c.notes = append(c.notes, m)
+			continue
+		}
+		if m.Error != nil {
+			return m.Error
+		}
+		if result == nil {
+			return nil
+		}
+		return json.Unmarshal(m.Result, result)
+	}
+	return io.EOF
+}
+
+// notify sends a notification.
+func (c *client) notify(method string, params interface{}) {
+	Expect(c.conn.notify(method, params)).To(Succeed())
+}
+
+// diagnostics returns the next diagnostics published by the server.
+func (c *client) diagnostics() publishDiagnosticsParams {
+	var m *message
+	if len(c.notes) > 0 {
+		m, c.notes = c.notes[0], c.notes[1:]
+	} else {
+		Eventually(c.messages).Should(Receive(&m))
+	}
+	Expect(m.Method).To(Equal("textDocument/publishDiagnostics"))
+
+	var p publishDiagnosticsParams
+	Expect(json.Unmarshal(m.Params, &p)).To(Succeed())
+	return p
+}
+
+var _ = Describe("server", func() {
+	const uri = "file:///schedules.twq"
+
+	var (
+		c    *client
+		code <-chan int
+	)
+
+	at := func(line, char int) position {
+		return position{Line: line, Character: char}
+	}
+
+	span := func(l1, c1, l2, c2 int) textRange {
+		return textRange{Start: at(l1, c1), End: at(l2, c2)}
+	}
+
+	open := func(text string) publishDiagnosticsParams {
+		c.notify("textDocument/didOpen", didOpenTextDocumentParams{
+			TextDocument: textDocumentItem{URI: uri, LanguageID: "timerangeql", Version: 1, Text: text},
+		})
+		return c.diagnostics()
+	}
+
+	BeforeEach(func() {
+		// 03-14-18 is a Wednesday
+		c, code = newClient(timewarp.NewFakeClock(time.Date(2018, 3, 14, 10, 0, 0, 0, time.UTC)))
+	})
+
+	AfterEach(func() {
+		_ = c.w.Close()
+	})
+
+	It("should require initialization", func() {
+		err := c.call("textDocument/hover", textDocumentPositionParams{}, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*responseError).Code).To(Equal(codeServerNotInitialized))
+	})
+
+	Context("initialized", func() {
+		var caps initializeResult
+
+		BeforeEach(func() {
+			Expect(c.call("initialize", map[string]interface{}{}, &caps)).To(Succeed())
+			c.notify("initialized", map[string]interface{}{})
+		})
+
+		It("should report its capabilities", func() {
+			Expect(caps.ServerInfo.Name).To(Equal("timewarp-lsp"))
+			Expect(caps.Capabilities.TextDocumentSync).To(Equal(textDocumentSyncFull))
+			Expect(caps.Capabilities.HoverProvider).To(BeTrue())
+			Expect(caps.Capabilities.DocumentFormattingProvider).To(BeTrue())
+		})
+
+		It("should reject unknown methods", func() {
+			err := c.call("workspace/symbol", map[string]interface{}{}, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*responseError).Code).To(Equal(codeMethodNotFound))
+		})
+
+		It("should exit cleanly after a shutdown", func() {
+			Expect(c.call("shutdown", nil, nil)).To(Succeed())
+			c.notify("exit", nil)
+			Eventually(code).Should(Receive(Equal(0)))
+		})
+
+		It("should exit with an error without a shutdown", func() {
+			c.notify("exit", nil)
+			Eventually(code).Should(Receive(Equal(1)))
+		})
+
+		Describe("diagnostics", func() {
+			It("should report the parse errors of each expression", func() {
+				p := open("DAY TUESDAY;\nDAY FRIDAY IN TIME 0900 9999;\n# trailing\nDAY TUESDAY AND")
+				Expect(p.URI).To(Equal(uri))
+				Expect(*p.Version).To(Equal(1))
+				Expect(p.Diagnostics).To(Equal([]diagnostic{
+					{Range: span(1, 24, 1, 28), Severity: severityError, Source: "timewarp", Message: "invalid time format"},
+					{Range: span(3, 15, 3, 15), Severity: severityError, Source: "timewarp", Message: "found EOF, expected (, NOT, YEAR, MONTH, WEEK, DAY, TIME"},
+				}))
+			})
+
+			It("should count characters in UTF-16 code units", func() {
+				p := open(`"😀"; DAY AND`)
+				Expect(p.Diagnostics).To(HaveLen(2))
+				Expect(p.Diagnostics[0].Range).To(Equal(span(0, 0, 0, 4)))
+				Expect(p.Diagnostics[0].Message).To(Equal(`undefined name "😀"`))
+				Expect(p.Diagnostics[1].Range).To(Equal(span(0, 13, 0, 13)))
+			})
+
+			It("should report lint diagnostics", func() {
+				p := open("DAY TUESDAY;\nNOT NOT DAY MONDAY")
+				Expect(p.Diagnostics).To(Equal([]diagnostic{
+					{Range: span(1, 0, 1, 3), Severity: severityWarning, Source: "timewarp", Message: "double negation has no effect"},
+				}))
+			})
+
+			It("should update and clear the diagnostics", func() {
+				Expect(open("DAY AND").Diagnostics).To(HaveLen(1))
+
+				c.notify("textDocument/didChange", didChangeTextDocumentParams{
+					TextDocument: versionedTextDocumentIdentifier{URI: uri, Version: 2},
+					ContentChanges: []struct {
+						Text string `json:"text"`
+					}{{Text: "DAY MONDAY"}},
+				})
+				p := c.diagnostics()
+				Expect(*p.Version).To(Equal(2))
+				Expect(p.Diagnostics).To(BeEmpty())
+
+				c.notify("textDocument/didClose", didCloseTextDocumentParams{TextDocument: textDocumentIdentifier{URI: uri}})
+				Expect(c.diagnostics().Diagnostics).To(BeEmpty())
+			})
+		})
+
+		Describe("completion", func() {
+			complete := func(text string, pos position) (labels []string, items []completionItem) {
+				open(text)
+
+				var list completionList
+				Expect(c.call("textDocument/completion", textDocumentPositionParams{
+					TextDocument: textDocumentIdentifier{URI: uri},
+					Position:     pos,
+				}, &list)).To(Succeed())
+				for _, item := range list.Items {
+					labels = append(labels, item.Label)
+				}
+				return labels, list.Items
+			}
+
+			It("should complete the start of an expression", func() {
+				labels, _ := complete("", at(0, 0))
+				Expect(labels).To(Equal([]string{"(", "NOT", "YEAR", "MONTH", "WEEK", "DAY", "TIME"}))
+			})
+
+			It("should complete the operators and arguments after an expression", func() {
+				labels, _ := complete("DAY TUESDAY ", at(0, 12))
+				Expect(labels).To(ContainElement("AND"))
+				Expect(labels).To(ContainElement("OF"))
+				Expect(labels).To(ContainElement("SUNDAY"))
+				Expect(labels).NotTo(ContainElement("MONTH"))
+			})
+
+			It("should complete the word at the cursor", func() {
+				labels, items := complete("DAY TUESDAY; DAY TUESDAY OF 2 mo", at(0, 32))
+				Expect(labels).To(Equal([]string{"MONTH"}))
+				Expect(items[0].TextEdit).To(Equal(&textEdit{Range: span(0, 30, 0, 32), NewText: "MONTH"}))
+			})
+
+			It("should not complete comments", func() {
+				labels, _ := complete("DAY TUESDAY # in ", at(0, 17))
+				Expect(labels).To(BeEmpty())
+			})
+		})
+
+		Describe("hover", func() {
+			hoverAt := func(text string, pos position) *hover {
+				open(text)
+
+				var h *hover
+				Expect(c.call("textDocument/hover", textDocumentPositionParams{
+					TextDocument: textDocumentIdentifier{URI: uri},
+					Position:     pos,
+				}, &h)).To(Succeed())
+				return h
+			}
+
+			It("should preview the next occurrences", func() {
+				h := hoverAt("# tuesdays\nday tuesday in time 9:00 10:00", at(1, 2))
+				Expect(h).NotTo(BeNil())
+				Expect(h.Range).To(Equal(&textRange{Start: at(1, 0), End: at(1, 30)}))
+				Expect(h.Contents.Kind).To(Equal("markdown"))
+				Expect(h.Contents.Value).To(HavePrefix("`DAY TUESDAY IN TIME 0900 1000`\n\n"))
+				Expect(h.Contents.Value).To(ContainSubstring("Next occurrences:\n\n- Tue 2018-03-20 09:00 – Tue 2018-03-20 10:00\n- Tue 2018-03-27 09:00"))
+			})
+
+			It("should include an occurrence in progress", func() {
+				h := hoverAt("DAY WEDNESDAY", at(0, 0))
+				Expect(h.Contents.Value).To(ContainSubstring("- Wed 2018-03-14 00:00 – Thu 2018-03-15 00:00\n"))
+			})
+
+			It("should not hover over invalid expressions", func() {
+				Expect(hoverAt("DAY TUESDAY AND", at(0, 2))).To(BeNil())
+			})
+		})
+
+		Describe("formatting", func() {
+			It("should print the expressions in canonical form", func() {
+				open("day tuesday in time 9:00 17:00 ; # keep\n(DAY MONDAY # inner\n);DAY   FRIDAY;DAY AND")
+
+				var edits []textEdit
+				Expect(c.call("textDocument/formatting", documentFormattingParams{
+					TextDocument: textDocumentIdentifier{URI: uri},
+				}, &edits)).To(Succeed())
+				Expect(edits).To(Equal([]textEdit{
+					{Range: span(0, 0, 0, 30), NewText: "DAY TUESDAY IN TIME 0900 1700"},
+					{Range: span(2, 2, 2, 14), NewText: "DAY FRIDAY"},
+				}))
+			})
+		})
+	})
+})
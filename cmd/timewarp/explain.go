@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/takeinitiative/timewarp"
+)
+
+// explainCmd prints the evaluation trace of an expression over a window.
+func explainCmd(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("explain", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var (
+		from   = fs.String("from", "", "start of the window (default now)")
+		to     = fs.String("to", "", "end of the window (default a week after the start)")
+		asJSON = fs.Bool("json", false, "print the trace as JSON")
+	)
+
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return 2
+	}
+
+	s, err := expression(args)
+	if err != nil {
+		fmt.Fprintf(stderr, "timewarp explain: %s\n", err)
+		return 2
+	}
+
+	start, end, err := parseWindow(*from, *to, 7*24*time.Hour, time.Local)
+	if err != nil {
+		fmt.Fprintf(stderr, "timewarp explain: %s\n", err)
+		return 2
+	}
+
+	e, err := timewarp.ParseExprString(s)
+	if err != nil {
+		fmt.Fprintf(stderr, "timewarp explain: %s\n", err)
+		return 1
+	}
+
+	trace := timewarp.Explain(e, timewarp.TimeRange{Start: start, End: end})
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(trace); err != nil {
+			fmt.Fprintf(stderr, "timewarp explain: %s\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Fprint(stdout, trace)
+	return 0
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("explain", func() {
+	var (
+		args   []string
+		stdout bytes.Buffer
+		stderr bytes.Buffer
+		code   int
+	)
+
+	BeforeEach(func() {
+		stdout.Reset()
+		stderr.Reset()
+	})
+
+	JustBeforeEach(func() {
+		code = run(append([]string{"explain"}, args...), nil, &stdout, &stderr)
+	})
+
+	Context("text", func() {
+		BeforeEach(func() {
+			args = []string{"DAY TUESDAY OF 5 MONTH", "-from", "2018-01-01T00:00:00Z", "-to", "2018-03-01T00:00:00Z"}
+		})
+
+		It("should print the trace", func() {
+			Expect(code).To(Equal(0))
+			Expect(stdout.String()).To(HavePrefix("DAY TUESDAY OF 5 MONTH (1 col 1)\n  in 2018-01-01T00:00:00Z/2018-03-01T00:00:00Z: 1 result(s)\n"))
+			Expect(stdout.String()).To(ContainSubstring("dropped 2018-02-01T00:00:00Z/2018-03-01T00:00:00Z: ordinal 5 exceeds 4 results"))
+		})
+	})
+
+	Context("JSON", func() {
+		BeforeEach(func() {
+			args = []string{"-json", "-from", "2018-01-01T00:00:00Z", "-to", "2018-03-01T00:00:00Z", "DAY", "TUESDAY"}
+		})
+
+		It("should print the trace as JSON", func() {
+			Expect(code).To(Equal(0))
+
+			var v map[string]interface{}
+			Expect(json.Unmarshal(stdout.Bytes(), &v)).To(Succeed())
+			Expect(v["expr"]).To(Equal("DAY TUESDAY"))
+		})
+	})
+
+	Context("invalid expression", func() {
+		BeforeEach(func() {
+			args = []string{"DAY TUESDAY AND"}
+		})
+
+		It("should print the parse error", func() {
+			Expect(code).To(Equal(1))
+			Expect(stderr.String()).To(ContainSubstring("at 1 col 16"))
+		})
+	})
+
+	Context("missing expression", func() {
+		BeforeEach(func() {
+			args = nil
+		})
+
+		It("should fail", func() {
+			Expect(code).To(Equal(2))
+		})
+	})
+})
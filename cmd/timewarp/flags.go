@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/takeinitiative/timewarp"
+)
+
+// clock is the source of the current time, replaced in tests.
+var clock timewarp.Clock = timewarp.RealClock{}
+
+// timeLayouts are the layouts accepted for times on the command line.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseArgs parses the flags of the flag set, allowing flags to follow
+// positional arguments, and returns the positional arguments.  Arguments
+// after a "--" terminator are always positional.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for i, arg := range args {
+		if arg == "--" {
+			args, rest = args[:i], args[i+1:]
+			break
+		}
+	}
+
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if args = fs.Args(); len(args) == 0 {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// parseTime parses a command line time in the location.
+func parseTime(s string, loc *time.Location) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected a date or RFC 3339 time", s)
+}
+
+// parseWindow parses the start and end of a window.  The start defaults to
+// now and the end defaults to the start plus the default duration.
+func parseWindow(from, to string, d time.Duration, loc *time.Location) (start, end time.Time, err error) {
+	start = clock.Now().In(loc)
+	if from != "" {
+		if start, err = parseTime(from, loc); err != nil {
+			return
+		}
+	}
+
+	end = start.Add(d)
+	if to != "" {
+		if end, err = parseTime(to, loc); err != nil {
+			return
+		}
+	}
+
+	if !start.Before(end) {
+		err = fmt.Errorf("the window must end after it starts")
+	}
+	return
+}
+
+// expression joins the positional arguments into a single expression.
+func expression(args []string) (string, error) {
+	s := strings.TrimSpace(strings.Join(args, " "))
+	if s == "" {
+		return "", fmt.Errorf("missing expression")
+	}
+	return s, nil
+}
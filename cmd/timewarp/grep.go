@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/takeinitiative/timewarp"
+)
+
+// namedLayouts are the layouts that may be given by name.  The "unix" and
+// "unixmilli" layouts read seconds or milliseconds since the epoch.
+var namedLayouts = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"UnixDate":    time.UnixDate,
+	"RubyDate":    time.RubyDate,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC850":      time.RFC850,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"Stamp":       time.Stamp,
+	"StampMilli":  time.StampMilli,
+	"StampMicro":  time.StampMicro,
+	"StampNano":   time.StampNano,
+	"DateTime":    "2006-01-02 15:04:05",
+	"DateOnly":    "2006-01-02",
+}
+
+// grepCmd prints the lines whose timestamp is within the expression.
+func grepCmd(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("grep", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var (
+		field   = fs.Int("time-field", 1, "whitespace separated field holding the timestamp, from 1")
+		layout  = fs.String("layout", "RFC3339", "timestamp layout, by name or as a Go layout, or unix or unixmilli")
+		invert  = fs.Bool("invert", false, "print the lines outside the expression")
+		jsonKey = fs.String("json-key", "", "read JSON lines and take the timestamp from the dotted key")
+		zone    = fs.String("zone", "Local", "time zone of the days of the expression and of timestamps without an offset")
+	)
+
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return 2
+	}
+
+	s, err := expression(args)
+	if err != nil {
+		fmt.Fprintf(stderr, "timewarp grep: %s\n", err)
+		return 2
+	}
+	if *field < 1 {
+		fmt.Fprintf(stderr, "timewarp grep: invalid time field %d\n", *field)
+		return 2
+	}
+
+	loc, err := time.LoadLocation(*zone)
+	if err != nil {
+		fmt.Fprintf(stderr, "timewarp grep: %s\n", err)
+		return 2
+	}
+
+	f, err := timewarp.ParseString(s)
+	if err != nil {
+		fmt.Fprintf(stderr, "timewarp grep: %s\n", err)
+		return 1
+	}
+	f = f.InLocation(loc)
+
+	p := timeParser{layout: *layout, loc: loc}
+	if l, ok := namedLayouts[*layout]; ok {
+		p.layout = l
+	}
+
+	var extract func(line string) (time.Time, bool)
+	if *jsonKey != "" {
+		extract = func(line string) (time.Time, bool) {
+			return p.fromJSON(line, *jsonKey)
+		}
+	} else {
+		extract = func(line string) (time.Time, bool) {
+			return p.fromFields(line, *field)
+		}
+	}
+
+	// lines without a timestamp, such as stack traces, follow the line
+	// before them
+	var (
+		events  = make(chan timewarp.Event)
+		scanErr = make(chan error, 1)
+	)
+	go func() {
+		defer close(events)
+
+		var (
+			last    time.Time
+			scanner = bufio.NewScanner(stdin)
+		)
+		scanner.Buffer(nil, 16*1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if t, ok := extract(line); ok {
+				last = t
+			}
+			events <- timewarp.Event{Time: last, Data: line}
+		}
+		scanErr <- scanner.Err()
+	}()
+
+	var (
+		printed int
+		m, u    = timewarp.Partition(f, events)
+	)
+	for m != nil || u != nil {
+		select {
+		case e, ok := <-m:
+			if !ok {
+				m = nil
+			} else if !*invert {
+				fmt.Fprintln(stdout, e.Data)
+				printed++
+			}
+		case e, ok := <-u:
+			if !ok {
+				u = nil
+			} else if *invert {
+				fmt.Fprintln(stdout, e.Data)
+				printed++
+			}
+		}
+	}
+
+	if err := <-scanErr; err != nil {
+		fmt.Fprintf(stderr, "timewarp grep: %s\n", err)
+		return 2
+	}
+	if printed == 0 {
+		return 1
+	}
+	return 0
+}
+
+// timeParser parses the timestamps of lines.
+type timeParser struct {
+	layout string
+	loc    *time.Location
+}
+
+// parse parses a timestamp.  Timestamps with an offset are converted to the
+// location, so that they count toward its days.
+func (p *timeParser) parse(s string) (time.Time, bool) {
+	switch p.layout {
+	case "unix", "unixmilli":
+		var v float64
+		if _, err := fmt.Sscanf(s, "%g", &v); err != nil {
+			return time.Time{}, false
+		}
+		return p.fromNumber(v)
+	}
+
+	t, err := time.ParseInLocation(p.layout, s, p.loc)
+	return t.In(p.loc), err == nil
+}
+
+// fromNumber converts a number of seconds or milliseconds since the epoch.
+func (p *timeParser) fromNumber(v float64) (time.Time, bool) {
+	switch p.layout {
+	case "unix":
+		return time.Unix(0, int64(v*float64(time.Second))).In(p.loc), true
+	case "unixmilli":
+		return time.Unix(0, int64(v*float64(time.Millisecond))).In(p.loc), true
+	}
+	return time.Time{}, false
+}
+
+// fromFields parses the timestamp starting at the whitespace separated field.
+// Layouts with spaces span as many fields.
+func (p *timeParser) fromFields(line string, field int) (time.Time, bool) {
+	var (
+		fields = strings.Fields(line)
+		n      = strings.Count(p.layout, " ") + 1
+	)
+	if field+n-1 > len(fields) {
+		return time.Time{}, false
+	}
+	return p.parse(strings.Join(fields[field-1:field+n-1], " "))
+}
+
+// fromJSON parses the timestamp of a JSON line at the dotted key.
+func (p *timeParser) fromJSON(line, key string) (time.Time, bool) {
+	var v interface{}
+	if err := json.Unmarshal([]byte(line), &v); err != nil {
+		return time.Time{}, false
+	}
+
+	for _, k := range strings.Split(key, ".") {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return time.Time{}, false
+		}
+		v = obj[k]
+	}
+
+	switch v := v.(type) {
+	case string:
+		return p.parse(v)
+	case float64:
+		return p.fromNumber(v)
+	}
+	return time.Time{}, false
+}
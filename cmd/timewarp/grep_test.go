@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("grep", func() {
+	const business = "DAY MONDAY FRIDAY IN TIME 0900 1700"
+
+	var (
+		args   []string
+		stdin  string
+		stdout bytes.Buffer
+		stderr bytes.Buffer
+		code   int
+	)
+
+	BeforeEach(func() {
+		stdout.Reset()
+		stderr.Reset()
+	})
+
+	JustBeforeEach(func() {
+		code = run(append([]string{"grep"}, args...), strings.NewReader(stdin), &stdout, &stderr)
+	})
+
+	Context("log lines", func() {
+		BeforeEach(func() {
+			// 2016-11-07 is a Monday
+			stdin = strings.Join([]string{
+				"2016-11-07T08:30:00Z INFO starting",
+				"2016-11-07T09:15:00Z ERROR failed",
+				"    at main.go:12",
+				"2016-11-12T10:00:00Z INFO weekend",
+				"",
+			}, "\n")
+			args = []string{business, "--time-field", "1", "--layout", "RFC3339", "--zone", "UTC"}
+		})
+
+		It("should print the lines in business hours", func() {
+			Expect(code).To(Equal(0))
+			Expect(stdout.String()).To(Equal("2016-11-07T09:15:00Z ERROR failed\n    at main.go:12\n"))
+		})
+
+		Context("inverted", func() {
+			BeforeEach(func() {
+				args = append(args, "--invert")
+			})
+
+			It("should print the other lines", func() {
+				Expect(code).To(Equal(0))
+				Expect(stdout.String()).To(Equal("2016-11-07T08:30:00Z INFO starting\n2016-11-12T10:00:00Z INFO weekend\n"))
+			})
+		})
+	})
+
+	Context("timestamps with an offset", func() {
+		BeforeEach(func() {
+			// 09:30 in Sydney on Monday 2016-11-07 is still Sunday in UTC, and
+			// 09:30 on Saturday 2016-11-12 is still Friday in UTC
+			stdin = "2016-11-07T09:30:00+11:00 monday\n2016-11-12T09:30:00+11:00 saturday\n"
+			args = []string{business, "-zone", "Australia/Sydney"}
+		})
+
+		It("should count the days of the zone", func() {
+			Expect(code).To(Equal(0))
+			Expect(stdout.String()).To(Equal("2016-11-07T09:30:00+11:00 monday\n"))
+		})
+
+		Context("converted from UTC", func() {
+			BeforeEach(func() {
+				stdin = "2016-11-06T22:30:00Z monday\n2016-11-11T22:30:00Z saturday\n"
+			})
+
+			It("should count the days of the zone", func() {
+				Expect(code).To(Equal(0))
+				Expect(stdout.String()).To(Equal("2016-11-06T22:30:00Z monday\n"))
+			})
+		})
+	})
+
+	Context("layouts spanning fields", func() {
+		BeforeEach(func() {
+			stdin = "app 2016-11-07 09:15:00 ok\napp 2016-11-07 18:00:00 late\n"
+			args = []string{business, "-time-field", "2", "-layout", "DateTime", "-zone", "UTC"}
+		})
+
+		It("should join the fields", func() {
+			Expect(code).To(Equal(0))
+			Expect(stdout.String()).To(Equal("app 2016-11-07 09:15:00 ok\n"))
+		})
+	})
+
+	Context("JSON lines", func() {
+		BeforeEach(func() {
+			stdin = `{"ts":{"at":"2016-11-07T10:00:00Z"},"msg":"a"}` + "\n" +
+				`{"ts":{"at":"2016-11-06T10:00:00Z"},"msg":"b"}` + "\n" +
+				`{"epoch":1478512800,"msg":"c"}` + "\n"
+			args = []string{business, "-json-key", "ts.at", "-zone", "UTC"}
+		})
+
+		It("should read the timestamp from the key", func() {
+			Expect(code).To(Equal(0))
+			Expect(stdout.String()).To(Equal(`{"ts":{"at":"2016-11-07T10:00:00Z"},"msg":"a"}` + "\n"))
+		})
+
+		Context("epoch seconds", func() {
+			BeforeEach(func() {
+				args = []string{business, "-json-key", "epoch", "-layout", "unix", "-zone", "UTC"}
+			})
+
+			It("should convert the number", func() {
+				Expect(code).To(Equal(0))
+				Expect(stdout.String()).To(Equal(`{"epoch":1478512800,"msg":"c"}` + "\n"))
+			})
+		})
+	})
+
+	Context("no matches", func() {
+		BeforeEach(func() {
+			stdin = "2016-11-12T10:00:00Z INFO weekend\n"
+			args = []string{business, "-zone", "UTC"}
+		})
+
+		It("should exit with 1", func() {
+			Expect(code).To(Equal(1))
+			Expect(stdout.String()).To(BeEmpty())
+		})
+	})
+
+	Context("invalid expression", func() {
+		BeforeEach(func() {
+			args = []string{"DAY FUNDAY"}
+		})
+
+		It("should fail", func() {
+			Expect(code).To(Equal(1))
+			Expect(stderr.String()).To(ContainSubstring("timewarp grep:"))
+		})
+	})
+})
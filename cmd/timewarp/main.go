@@ -0,0 +1,63 @@
+// Command timewarp evaluates and inspects timerangeQL expressions.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// command is a timewarp subcommand.
+type command struct {
+	usage string
+	run   func(args []string, stdin io.Reader, stdout, stderr io.Writer) int
+}
+
+// commands returns the timewarp subcommands by name.
+func commands() map[string]command {
+	return map[string]command{
+		"calendar": {"calendar [-from time] [-to time] [-zone zone] [-width n] [-color when] [-ascii] expression", calendarCmd},
+		"explain":  {"explain [-from time] [-to time] [-json] expression", explainCmd},
+		"grep":     {"grep [-time-field n] [-layout layout] [-json-key key] [-zone zone] [-invert] expression", grepCmd},
+		"repl":     {"repl [-from time] [-to time] [-zone zone] [-history file]", replCmd},
+		"serve":    {"serve [-addr addr] [-max-window duration] [-max-results n] [-max-body bytes]", serveCmd},
+		"timeline": {"timeline [-from time] [-to time] [-zone zone] [-width n] [-color when] [-ascii] expression", timelineCmd},
+	}
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the subcommand named by the first argument and returns the exit
+// code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	cmds := commands()
+	if len(args) == 0 {
+		usage(stderr, cmds)
+		return 2
+	}
+
+	cmd, ok := cmds[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "timewarp: unknown command %q\n", args[0])
+		usage(stderr, cmds)
+		return 2
+	}
+	return cmd.run(args[1:], stdin, stdout, stderr)
+}
+
+// usage writes the usage of every subcommand.
+func usage(w io.Writer, cmds map[string]command) {
+	var names []string
+	for name := range cmds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "usage:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  timewarp %s\n", cmds[name].usage)
+	}
+}
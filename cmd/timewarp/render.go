@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/takeinitiative/timewarp"
+)
+
+// renderer draws the ranges of a filter over a window.
+type renderer func(w io.Writer, f timewarp.Filter, window timewarp.TimeRange, opts timewarp.RenderOptions) error
+
+// calendarCmd draws a month calendar of the days matched by an expression.
+func calendarCmd(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	return renderCmd("calendar", 92*24*time.Hour, timewarp.RenderCalendar, args, stdout, stderr)
+}
+
+// timelineCmd draws an hour by day chart of the times matched by an
+// expression.
+func timelineCmd(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	return renderCmd("timeline", 7*24*time.Hour, timewarp.RenderTimeline, args, stdout, stderr)
+}
+
+// renderCmd parses the flags and expression of a rendering command and draws
+// the expression over the window.
+func renderCmd(name string, d time.Duration, render renderer, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var (
+		from  = fs.String("from", "", "start of the window (default now)")
+		to    = fs.String("to", "", fmt.Sprintf("end of the window (default %d days after the start)", d/(24*time.Hour)))
+		zone  = fs.String("zone", "Local", "time zone of the window")
+		width = fs.Int("width", terminalWidth(), "number of columns available")
+		color = fs.String("color", "auto", "highlight with colours: auto, always or never")
+		ascii = fs.Bool("ascii", false, "draw with ASCII characters only")
+	)
+
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return 2
+	}
+
+	s, err := expression(args)
+	if err != nil {
+		fmt.Fprintf(stderr, "timewarp %s: %s\n", name, err)
+		return 2
+	}
+
+	loc, err := time.LoadLocation(*zone)
+	if err != nil {
+		fmt.Fprintf(stderr, "timewarp %s: %s\n", name, err)
+		return 2
+	}
+
+	start, end, err := parseWindow(*from, *to, d, loc)
+	if err != nil {
+		fmt.Fprintf(stderr, "timewarp %s: %s\n", name, err)
+		return 2
+	}
+
+	opts := timewarp.RenderOptions{Width: *width, ASCII: *ascii}
+	switch *color {
+	case "auto":
+		opts.Color = isTerminal(stdout) && os.Getenv("NO_COLOR") == ""
+	case "always":
+		opts.Color = true
+	case "never":
+	default:
+		fmt.Fprintf(stderr, "timewarp %s: invalid color %q, expected auto, always or never\n", name, *color)
+		return 2
+	}
+
+	e, err := timewarp.ParseExprString(s)
+	if err != nil {
+		fmt.Fprintf(stderr, "timewarp %s: %s\n", name, err)
+		return 1
+	}
+
+	window := timewarp.TimeRange{Start: start, End: end}
+	if err := render(stdout, e.Filter().InLocation(loc), window, opts); err != nil {
+		fmt.Fprintf(stderr, "timewarp %s: %s\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+// terminalWidth returns the width of the terminal from the COLUMNS variable,
+// or zero for the default width.
+func terminalWidth() int {
+	n, _ := strconv.Atoi(os.Getenv("COLUMNS"))
+	return n
+}
+
+// isTerminal returns true if the writer is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
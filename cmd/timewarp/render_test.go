@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("render", func() {
+	var (
+		args   []string
+		stdout bytes.Buffer
+		stderr bytes.Buffer
+		code   int
+	)
+
+	BeforeEach(func() {
+		stdout.Reset()
+		stderr.Reset()
+	})
+
+	JustBeforeEach(func() {
+		code = run(args, nil, &stdout, &stderr)
+	})
+
+	Context("calendar", func() {
+		BeforeEach(func() {
+			args = []string{"calendar", "-from", "2018-03-01", "-to", "2018-04-01", "-zone", "UTC", "-ascii", "DAY", "TUESDAY", "IN", "TIME", "0900", "1000"}
+		})
+
+		It("should mark the matched days", func() {
+			Expect(code).To(Equal(0))
+			Expect(stdout.String()).To(Equal(strings.Join([]string{
+				"March 2018",
+				"Mo Tu We Th Fr Sa Su",
+				"          1  2  3  4",
+				" 5  6+ 7  8  9 10 11",
+				"12 13+14 15 16 17 18",
+				"19 20+21 22 23 24 25",
+				"26 27+28 29 30 31",
+			}, "\n") + "\n"))
+		})
+	})
+
+	Context("timeline in a zone", func() {
+		BeforeEach(func() {
+			args = []string{"timeline", "-from", "2018-03-05", "-to", "2018-03-07", "-zone", "America/New_York", "-width", "39", "-color", "never", "TIME 0900 1200"}
+		})
+
+		It("should shade the matched hours at their wall clock times", func() {
+			Expect(code).To(Equal(0))
+			Expect(stdout.String()).To(Equal(strings.Join([]string{
+				"               00    06    12    18",
+				"Mon 2018-03-05          ███",
+				"Tue 2018-03-06          ███",
+				"each column is 1h",
+			}, "\n") + "\n"))
+		})
+	})
+
+	Context("colour", func() {
+		BeforeEach(func() {
+			args = []string{"timeline", "-from", "2018-03-05", "-to", "2018-03-06", "-zone", "UTC", "-color", "always", "TIME 0900 1200"}
+		})
+
+		It("should highlight with escape sequences", func() {
+			Expect(code).To(Equal(0))
+			Expect(stdout.String()).To(ContainSubstring("\x1b[32m█\x1b[0m"))
+		})
+	})
+
+	Context("invalid colour", func() {
+		BeforeEach(func() {
+			args = []string{"calendar", "-color", "sometimes", "DAY MONDAY"}
+		})
+
+		It("should fail", func() {
+			Expect(code).To(Equal(2))
+			Expect(stderr.String()).To(ContainSubstring(`invalid color "sometimes"`))
+		})
+	})
+})
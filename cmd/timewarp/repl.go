@@ -0,0 +1,429 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/takeinitiative/timewarp"
+)
+
+const (
+	// replPrompt is written before each line is read.
+	replPrompt = "timewarp> "
+
+	// replMaxRanges is the number of matching ranges listed.
+	replMaxRanges = 20
+
+	// replMaxMonths is the number of months drawn in the calendar grid.
+	replMaxMonths = 12
+
+	// replLayout formats the times of matching ranges.
+	replLayout = "Mon 2006-01-02 15:04"
+)
+
+// replHelp describes the REPL commands.
+const replHelp = `Type an expression or an English phrase to list its ranges in the window.
+  :window [from to]   show or set the window
+  :zone [zone]        show or set the time zone, keeping the wall clock times
+  :ref [time]         show or set the reference time of phrases like "next Friday"
+  :let [name = expr]  list the names or define a name for an expression
+  :history            list the history
+  !n, !!              run history line n or the last line
+  :help               show this help
+  :quit               exit
+`
+
+// replCmd reads expressions and commands from stdin and prints the matching
+// ranges of each expression over the window.
+func replCmd(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("repl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var (
+		from    = fs.String("from", "", "start of the window (default now)")
+		to      = fs.String("to", "", "end of the window (default four weeks after the start)")
+		zone    = fs.String("zone", "Local", "time zone of the window and the ranges")
+		history = fs.String("history", defaultHistory(), "file keeping the history, or empty for none")
+	)
+
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(args) > 0 {
+		fmt.Fprintf(stderr, "timewarp repl: unexpected argument %q\n", args[0])
+		return 2
+	}
+
+	loc, err := time.LoadLocation(*zone)
+	if err != nil {
+		fmt.Fprintf(stderr, "timewarp repl: %s\n", err)
+		return 2
+	}
+
+	start, end, err := parseWindow(*from, *to, 28*24*time.Hour, loc)
+	if err != nil {
+		fmt.Fprintf(stderr, "timewarp repl: %s\n", err)
+		return 2
+	}
+
+	r := &repl{
+		out:    stdout,
+		loc:    loc,
+		window: timewarp.TimeRange{Start: start, End: end},
+		ref:    clock.Now().In(loc),
+		names:  make(map[string]timewarp.Expr),
+	}
+	if *history != "" {
+		if err := r.openHistory(*history); err != nil {
+			fmt.Fprintf(stderr, "timewarp repl: %s\n", err)
+			return 1
+		}
+		defer r.histFile.Close()
+	}
+
+	r.run(stdin)
+	return 0
+}
+
+// defaultHistory returns the path of the history file in the home directory,
+// or an empty string if there is none.
+func defaultHistory() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".timewarp_history")
+}
+
+// repl is the state of a REPL session.
+type repl struct {
+	out      io.Writer
+	loc      *time.Location
+	window   timewarp.TimeRange
+	ref      time.Time
+	names    map[string]timewarp.Expr
+	history  []string
+	histFile *os.File
+}
+
+// openHistory loads the history file and opens it for appending.
+func (r *repl) openHistory(path string) error {
+	if data, err := ioutil.ReadFile(path); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if line != "" {
+				r.history = append(r.history, line)
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	r.histFile = f
+	return nil
+}
+
+// run reads and evaluates lines until the end of the input or :quit.
+func (r *repl) run(in io.Reader) {
+	s := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(r.out, replPrompt)
+		if !s.Scan() {
+			fmt.Fprintln(r.out)
+			return
+		}
+
+		line := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(line, "!") {
+			var err error
+			if line, err = r.recall(line); err != nil {
+				fmt.Fprintf(r.out, "error: %s\n", err)
+				continue
+			}
+			fmt.Fprintln(r.out, line)
+		}
+		if line == "" {
+			continue
+		}
+
+		r.remember(line)
+		if !r.eval(line) {
+			return
+		}
+	}
+}
+
+// recall returns the history line of a !n or !! reference.
+func (r *repl) recall(ref string) (string, error) {
+	n := len(r.history)
+	if ref != "!!" {
+		var err error
+		if n, err = strconv.Atoi(ref[1:]); err != nil {
+			return "", fmt.Errorf("invalid history reference %q", ref)
+		}
+	}
+	if n < 1 || n > len(r.history) {
+		return "", fmt.Errorf("no history line %s", ref[1:])
+	}
+	return r.history[n-1], nil
+}
+
+// remember appends the line to the history.
+func (r *repl) remember(line string) {
+	r.history = append(r.history, line)
+	if r.histFile != nil {
+		fmt.Fprintln(r.histFile, line)
+	}
+}
+
+// eval evaluates a line and returns false to quit.
+func (r *repl) eval(line string) bool {
+	if !strings.HasPrefix(line, ":") {
+		r.evalExpr(line)
+		return true
+	}
+
+	cmd, arg := line, ""
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		cmd, arg = line[:i], strings.TrimSpace(line[i+1:])
+	}
+
+	switch cmd {
+	case ":quit", ":q":
+		return false
+	case ":help", ":h":
+		fmt.Fprint(r.out, replHelp)
+	case ":window":
+		r.setWindow(arg)
+	case ":zone":
+		r.setZone(arg)
+	case ":ref":
+		r.setRef(arg)
+	case ":let":
+		r.let(arg)
+	case ":history":
+		for i, h := range r.history {
+			fmt.Fprintf(r.out, "%4d  %s\n", i+1, h)
+		}
+	default:
+		fmt.Fprintf(r.out, "error: unknown command %s, try :help\n", cmd)
+	}
+	return true
+}
+
+// setWindow shows or sets the window.
+func (r *repl) setWindow(arg string) {
+	if arg != "" {
+		fields := strings.Fields(arg)
+		if len(fields) != 2 {
+			fmt.Fprintln(r.out, "error: expected :window from to")
+			return
+		}
+		start, end, err := parseWindow(fields[0], fields[1], 0, r.loc)
+		if err != nil {
+			fmt.Fprintf(r.out, "error: %s\n", err)
+			return
+		}
+		r.window = timewarp.TimeRange{Start: start, End: end}
+	}
+	fmt.Fprintf(r.out, "window %s – %s\n", r.window.Start.Format(replLayout), r.window.End.Format(replLayout))
+}
+
+// setZone shows or sets the time zone.  The window and reference time keep
+// their wall clock times in the new zone.
+func (r *repl) setZone(arg string) {
+	if arg != "" {
+		loc, err := time.LoadLocation(arg)
+		if err != nil {
+			fmt.Fprintf(r.out, "error: %s\n", err)
+			return
+		}
+		r.loc = loc
+		r.window = timewarp.TimeRange{Start: inZone(r.window.Start, loc), End: inZone(r.window.End, loc)}
+		r.ref = inZone(r.ref, loc)
+	}
+	fmt.Fprintf(r.out, "zone %s\n", r.loc)
+}
+
+// setRef shows or sets the reference time.
+func (r *repl) setRef(arg string) {
+	if arg != "" {
+		t, err := parseTime(arg, r.loc)
+		if err != nil {
+			fmt.Fprintf(r.out, "error: %s\n", err)
+			return
+		}
+		r.ref = t
+	}
+	fmt.Fprintf(r.out, "ref %s\n", r.ref.Format(replLayout))
+}
+
+// let lists the names or defines a name, as in ":let business = DAY MONDAY
+// FRIDAY IN TIME 0900 1700".
+func (r *repl) let(arg string) {
+	if arg == "" {
+		var names []string
+		for name := range r.names {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(r.out, "%s = %s\n", name, r.names[name])
+		}
+		return
+	}
+
+	i := strings.Index(arg, "=")
+	if i < 0 {
+		fmt.Fprintln(r.out, "error: expected :let name = expression")
+		return
+	}
+	name, src := strings.TrimSpace(arg[:i]), strings.TrimSpace(arg[i+1:])
+
+	e, err := r.parse(src)
+	if err != nil {
+		r.printError(src, err)
+		return
+	}
+	if err := timewarp.NewParser(strings.NewReader("")).Define(name, e); err != nil {
+		fmt.Fprintf(r.out, "error: %s\n", err)
+		return
+	}
+	r.names[strings.ToLower(name)] = e
+	fmt.Fprintf(r.out, "%s = %s\n", strings.ToLower(name), e)
+}
+
+// parse parses the expression with the names defined so far.  Expressions
+// that are not timerangeQL are parsed as English phrases.
+func (r *repl) parse(src string) (timewarp.Expr, error) {
+	p := timewarp.NewParser(strings.NewReader(src))
+	for name, e := range r.names {
+		_ = p.Define(name, e)
+	}
+
+	e, err := p.ParseExpr()
+	if err == nil {
+		return e, nil
+	}
+
+	s, nerr := timewarp.ParseNatural(src, r.ref)
+	if nerr == nil {
+		return s.Expr(), nil
+	}
+
+	// report the natural language error unless the source looks like
+	// timerangeQL
+	tok, _, lit := firstToken(src)
+	if _, named := r.names[strings.ToLower(lit)]; tok == timewarp.IDENT && !named {
+		return nil, nerr
+	}
+	return nil, err
+}
+
+// firstToken returns the first token of the source that is not whitespace or
+// a comment.
+func firstToken(src string) (tok timewarp.Token, pos timewarp.Pos, lit string) {
+	s := timewarp.NewScanner(strings.NewReader(src))
+	for {
+		if tok, pos, lit = s.Scan(); tok != timewarp.WS && tok != timewarp.COMMENT {
+			return
+		}
+	}
+}
+
+// evalExpr prints the canonical form, description, matching ranges and
+// calendar grid of an expression.
+func (r *repl) evalExpr(src string) {
+	e, err := r.parse(src)
+	if err != nil {
+		r.printError(src, err)
+		return
+	}
+
+	f := e.Filter().InLocation(r.loc)
+	ranges := f.Within(r.window)
+	fmt.Fprintf(r.out, "%s\n%s\n", e, timewarp.Describe(e))
+	fmt.Fprintf(r.out, "%d range(s) in %s – %s\n", len(ranges), r.window.Start.Format(replLayout), r.window.End.Format(replLayout))
+	for i, tr := range ranges {
+		if i == replMaxRanges {
+			fmt.Fprintf(r.out, "  … and %d more\n", len(ranges)-i)
+			break
+		}
+		fmt.Fprintf(r.out, "  %s\n", formatRange(tr, r.loc))
+	}
+	fmt.Fprintln(r.out)
+	grid(r.out, f, r.window)
+}
+
+// printError prints the source with a caret under the column of a parse
+// error.
+func (r *repl) printError(src string, err error) {
+	perr, ok := err.(*timewarp.ParseError)
+	if !ok || perr.Pos.Line > 0 {
+		fmt.Fprintf(r.out, "error: %s\n", err)
+		return
+	}
+
+	// the caret is indented like the source, keeping its tabs
+	var indent strings.Builder
+	for i, ch := range []rune(src) {
+		if i == perr.Pos.Char {
+			break
+		} else if ch == '\t' {
+			indent.WriteRune('\t')
+		} else {
+			indent.WriteRune(' ')
+		}
+	}
+	fmt.Fprintf(r.out, "  %s\n  %s^\nerror: %s\n", src, indent.String(), err)
+}
+
+// formatRange formats a range in the location, omitting the date of the end
+// if it is on the day of the start.
+func formatRange(tr *timewarp.TimeRange, loc *time.Location) string {
+	start, end := tr.Start.In(loc), tr.End.In(loc)
+
+	y1, m1, d1 := start.Date()
+	y2, m2, d2 := end.Date()
+
+	layout := replLayout
+	if y1 == y2 && m1 == m2 && d1 == d2 {
+		layout = "15:04"
+	}
+	return start.Format(replLayout) + " – " + end.Format(layout)
+}
+
+// grid draws a calendar of the months of the window in the location of its
+// start, up to the maximum.
+func grid(w io.Writer, f timewarp.Filter, window timewarp.TimeRange) {
+	start := window.Start
+	end := time.Date(start.Year(), start.Month()+replMaxMonths, 1, 0, 0, 0, 0, start.Location())
+	if window.End.Before(end) {
+		end = window.End
+	}
+
+	timewarp.RenderCalendar(w, f, timewarp.TimeRange{Start: start, End: end}, timewarp.RenderOptions{})
+	if end.Before(window.End) {
+		fmt.Fprintf(w, "… the calendar is limited to %d months\n", replMaxMonths)
+	}
+	fmt.Fprintln(w)
+}
+
+// inZone returns the time with the same wall clock time in the location, so
+// that the window keeps its wall clock times when the zone changes.
+func inZone(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
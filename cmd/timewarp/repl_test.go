@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("repl", func() {
+	var (
+		dir     string
+		history string
+		lines   []string
+		stdout  bytes.Buffer
+		stderr  bytes.Buffer
+		code    int
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "timewarp")
+		Expect(err).NotTo(HaveOccurred())
+
+		history = filepath.Join(dir, "history")
+		stdout.Reset()
+		stderr.Reset()
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	JustBeforeEach(func() {
+		args := []string{"repl", "-history", history, "-from", "2018-03-01", "-to", "2018-04-01", "-zone", "UTC"}
+		code = run(args, strings.NewReader(strings.Join(lines, "\n")), &stdout, &stderr)
+	})
+
+	Context("expressions", func() {
+		BeforeEach(func() {
+			lines = []string{"day tuesday in time 9:00 10:00"}
+		})
+
+		It("should print the ranges and the calendar", func() {
+			Expect(code).To(Equal(0))
+			Expect(stdout.String()).To(Equal(replPrompt + strings.Join([]string{
+				"DAY TUESDAY IN TIME 0900 1000",
+				"Tuesday, from 09:00 to 10:00",
+				"4 range(s) in Thu 2018-03-01 00:00 – Sun 2018-04-01 00:00",
+				"  Tue 2018-03-06 09:00 – 10:00",
+				"  Tue 2018-03-13 09:00 – 10:00",
+				"  Tue 2018-03-20 09:00 – 10:00",
+				"  Tue 2018-03-27 09:00 – 10:00",
+				"",
+				"March 2018",
+				"Mo Tu We Th Fr Sa Su",
+				"          1  2  3  4",
+				" 5  6◐ 7  8  9 10 11",
+				"12 13◐14 15 16 17 18",
+				"19 20◐21 22 23 24 25",
+				"26 27◐28 29 30 31",
+				"",
+				replPrompt,
+			}, "\n") + "\n"))
+		})
+	})
+
+	Context("phrases", func() {
+		BeforeEach(func() {
+			lines = []string{":ref 2018-03-14", "next friday"}
+		})
+
+		It("should parse English phrases from the reference time", func() {
+			Expect(stdout.String()).To(ContainSubstring("1 range(s) in Thu 2018-03-01 00:00 – Sun 2018-04-01 00:00\n  Fri 2018-03-16 00:00 – Sat 2018-03-17 00:00\n"))
+		})
+	})
+
+	Context("parse errors", func() {
+		BeforeEach(func() {
+			lines = []string{"DAY TUESDAY\tAND", "blah tuesday"}
+		})
+
+		It("should point at the column", func() {
+			Expect(stdout.String()).To(ContainSubstring("  DAY TUESDAY\tAND\n             \t   ^\nerror: found EOF, expected (, NOT, YEAR, MONTH, WEEK, DAY, TIME at 1 col 16\n"))
+			Expect(stdout.String()).To(ContainSubstring("  blah tuesday\n  ^\nerror: "))
+		})
+	})
+
+	Context("names", func() {
+		BeforeEach(func() {
+			lines = []string{
+				":let business = DAY MONDAY FRIDAY IN TIME 0900 1700",
+				":let monday = DAY MONDAY",
+				"business IN NOT DAY WEDNESDAY",
+				":let",
+			}
+		})
+
+		It("should define names for subexpressions", func() {
+			Expect(stdout.String()).To(ContainSubstring(replPrompt + "business = DAY MONDAY FRIDAY IN TIME 0900 1700\n"))
+			Expect(stdout.String()).To(ContainSubstring(`error: invalid name "monday"`))
+			Expect(stdout.String()).To(ContainSubstring("DAY MONDAY FRIDAY IN TIME 0900 1700 IN NOT DAY WEDNESDAY\n"))
+			Expect(stdout.String()).To(ContainSubstring("18 range(s)"))
+		})
+	})
+
+	Context("window and zone", func() {
+		BeforeEach(func() {
+			lines = []string{":window 2018-03-05 2018-03-12", ":zone America/New_York", ":window", "DAY MONDAY"}
+		})
+
+		It("should keep the wall clock times of the window", func() {
+			Expect(stdout.String()).To(ContainSubstring("window Mon 2018-03-05 00:00 – Mon 2018-03-12 00:00\n"))
+			Expect(stdout.String()).To(ContainSubstring("zone America/New_York\n"))
+			Expect(stdout.String()).To(ContainSubstring("1 range(s) in Mon 2018-03-05 00:00 – Mon 2018-03-12 00:00\n  Mon 2018-03-05 00:00 – Tue 2018-03-06 00:00\n"))
+		})
+	})
+
+	Context("history", func() {
+		BeforeEach(func() {
+			Expect(ioutil.WriteFile(history, []byte("DAY SUNDAY\n"), 0600)).To(Succeed())
+			lines = []string{"DAY MONDAY", "!1", "!!", "!9", ":history", ":quit", "DAY TUESDAY"}
+		})
+
+		It("should recall and keep the lines", func() {
+			Expect(stdout.String()).To(ContainSubstring(replPrompt + "DAY SUNDAY\nDAY SUNDAY\n"))
+			Expect(stdout.String()).To(ContainSubstring("error: no history line 9\n"))
+			Expect(stdout.String()).To(ContainSubstring("   1  DAY SUNDAY\n   2  DAY MONDAY\n   3  DAY SUNDAY\n   4  DAY SUNDAY\n   5  :history\n"))
+			Expect(stdout.String()).NotTo(ContainSubstring("DAY TUESDAY"))
+
+			data, err := ioutil.ReadFile(history)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("DAY SUNDAY\nDAY MONDAY\nDAY SUNDAY\nDAY SUNDAY\n:history\n:quit\n"))
+		})
+	})
+})
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/takeinitiative/timewarp"
+)
+
+// serveShutdown is how long requests in progress are given to finish when the
+// server is interrupted.
+const serveShutdown = 5 * time.Second
+
+// serveCmd serves the HTTP API evaluating expressions until interrupted.
+func serveCmd(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var (
+		addr       = fs.String("addr", "localhost:8080", "address to listen on")
+		maxWindow  = fs.Duration("max-window", 366*24*time.Hour, "longest window evaluated")
+		maxResults = fs.Int("max-results", 1000, "largest number of ranges returned")
+		maxBody    = fs.Int64("max-body", 64<<10, "largest request body in bytes")
+	)
+
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(args) > 0 {
+		fmt.Fprintf(stderr, "timewarp serve: unexpected argument %q\n", args[0])
+		return 2
+	}
+
+	srv := &http.Server{
+		Addr: *addr,
+		Handler: timewarp.NewHandler(timewarp.HandlerOptions{
+			MaxWindow:    *maxWindow,
+			MaxResults:   *maxResults,
+			MaxBodyBytes: *maxBody,
+		}),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+	fmt.Fprintf(stderr, "timewarp serve: listening on %s\n", *addr)
+
+	select {
+	case err = <-errc:
+	case <-interrupt:
+		shutdown, cancel := context.WithTimeout(context.Background(), serveShutdown)
+		defer cancel()
+		if err = srv.Shutdown(shutdown); err == nil {
+			err = <-errc
+		}
+	}
+
+	if err != nil && err != http.ErrServerClosed {
+		fmt.Fprintf(stderr, "timewarp serve: %s\n", err)
+		return 1
+	}
+	return 0
+}
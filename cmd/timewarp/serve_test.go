@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("serve", func() {
+	var (
+		args   []string
+		stdout bytes.Buffer
+		stderr bytes.Buffer
+		code   int
+	)
+
+	BeforeEach(func() {
+		stdout.Reset()
+		stderr.Reset()
+	})
+
+	JustBeforeEach(func() {
+		code = run(append([]string{"serve"}, args...), nil, &stdout, &stderr)
+	})
+
+	Context("with an argument", func() {
+		BeforeEach(func() {
+			args = []string{"-addr", "localhost:0", "DAY MONDAY"}
+		})
+
+		It("should fail", func() {
+			Expect(code).To(Equal(2))
+			Expect(stderr.String()).To(ContainSubstring(`unexpected argument "DAY MONDAY"`))
+		})
+	})
+
+	Context("with an invalid address", func() {
+		BeforeEach(func() {
+			args = []string{"-addr", "localhost:-1"}
+		})
+
+		It("should fail to listen", func() {
+			Expect(code).To(Equal(1))
+			Expect(stderr.String()).To(ContainSubstring("timewarp serve: listen tcp"))
+		})
+	})
+})
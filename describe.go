@@ -0,0 +1,218 @@
+package timewarp
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ordinalWords are the English words of the first ordinals.
+var ordinalWords = []string{
+	"zeroth", "first", "second", "third", "fourth", "fifth",
+	"sixth", "seventh", "eighth", "ninth", "tenth",
+}
+
+// Describe returns an English description of the expression, such as "the
+// second Tuesday of March, from 12:00 to 14:00" for
+// "DAY TUESDAY OF 2 MONTH MARCH IN TIME 1200 1400".  Intersections are joined
+// with commas, unions are listed, negations read as "except", and ordinals of
+// weeks read as "every nth week".  Locale.Describe describes expressions in
+// the language of a locale.
+func Describe(e Expr) string {
+	return describe(e)
+}
+
+// describe returns the description of an expression.
+func describe(e Expr) string {
+	switch e := unnamed(e).(type) {
+	case *BinaryExpr:
+		if e.Op == IN {
+			return describe(e.X) + ", " + qualify(e.Y)
+		}
+		return describeUnion(e)
+	case *OrdinalExpr:
+		return describeOrdinal(e)
+	case *NotExpr:
+		return "any time except " + describe(e.X)
+	case *YearExpr:
+		return strconv.Itoa(e.Year)
+	case *MonthExpr:
+		if e.Month == 0 {
+			return "every month"
+		}
+		return e.Month.String()
+	case *WeekExpr:
+		if e.Weekday < 0 {
+			return "every week"
+		}
+		return "every week starting on " + e.Weekday.String()
+	case *DayExpr:
+		switch len(e.Args) {
+		case 0:
+			return "every day"
+		case 1:
+			return "day " + strconv.Itoa(e.Args[0])
+		default:
+			return "days " + strconv.Itoa(e.Args[0]) + " to " + strconv.Itoa(e.Args[1])
+		}
+	case *WeekdayExpr:
+		return describeWeekdays(e)
+	case *TimeExpr:
+		return "from " + clockTime(e.From) + " to " + clockTime(e.To)
+	case *RangeExpr:
+		return "the whole range"
+	}
+	return e.String()
+}
+
+// qualify returns the description of the right operand of an intersection.
+func qualify(e Expr) string {
+	switch x := unnamed(e).(type) {
+	case *NotExpr:
+		return "except " + describe(x.X)
+	case *YearExpr:
+		return "in " + describe(x)
+	case *MonthExpr:
+		if x.Month != 0 {
+			return "in " + describe(x)
+		}
+	}
+	return describe(e)
+}
+
+// describeUnion lists the arms of a union.  Arms that contain commas are
+// separated by semicolons.
+func describeUnion(e *BinaryExpr) string {
+	var (
+		arms  []string
+		comma bool
+	)
+	for _, arm := range unionArms(e, nil) {
+		s := describe(arm)
+		comma = comma || strings.Contains(s, ",")
+		arms = append(arms, s)
+	}
+
+	if comma {
+		return strings.Join(arms[:len(arms)-1], "; ") + "; and " + arms[len(arms)-1]
+	}
+	return strings.Join(arms[:len(arms)-1], ", ") + " and " + arms[len(arms)-1]
+}
+
+// describeWeekdays returns the description of a range of weekdays.
+func describeWeekdays(e *WeekdayExpr) string {
+	switch e.Span() {
+	case 1:
+		return e.From.String()
+	case 7:
+		return "every day"
+	}
+	return e.From.String() + " through " + e.To.String()
+}
+
+// describeOrdinal returns the description of an ordinal expression.
+func describeOrdinal(e *OrdinalExpr) string {
+	x := unnamed(e.X)
+
+	if _, ok := unnamed(e.Unit).(*WeekExpr); ok && e.N > 0 {
+		switch x := x.(type) {
+		case *WeekExpr:
+			s := "every " + everyNth(e.N) + "week"
+			if x.Weekday >= 0 {
+				s += " starting on " + x.Weekday.String()
+			}
+			return s
+		case *WeekdayExpr:
+			if x.Span() == 1 {
+				return "every " + everyNth(e.N) + x.From.String()
+			}
+		}
+		return describe(x) + ", every " + everyNth(e.N) + "week"
+	}
+
+	var unit string
+	switch u := unnamed(e.Unit).(type) {
+	case *MonthExpr, *YearExpr:
+		unit = describe(u)
+	case *WeekExpr:
+		unit = "the week"
+		if u.Weekday >= 0 {
+			unit += " starting on " + u.Weekday.String()
+		}
+	case *DayExpr:
+		unit = "the day"
+		if len(u.Args) != 0 {
+			unit = describe(u)
+		}
+	case *RangeExpr:
+		unit = "the range"
+	default:
+		unit = describe(u)
+	}
+
+	switch x := x.(type) {
+	case *DayExpr:
+		switch {
+		case len(x.Args) == 0:
+			return "the " + ordinal(e.N) + " day of " + unit
+		case len(x.Args) == 1 && e.N == 1:
+			return "the " + numberth(x.Args[0]) + " of " + unit
+		}
+	case *WeekdayExpr:
+		return "the " + ordinal(e.N) + " " + describe(x) + " of " + unit
+	}
+	return "the " + ordinal(e.N) + " occurrence of " + describe(x) + " in " + unit
+}
+
+// everyNth returns the word between "every" and the unit of an nth
+// repetition, such as "other " for two.
+func everyNth(n int) string {
+	switch n {
+	case 1:
+		return ""
+	case 2:
+		return "other "
+	}
+	return ordinal(n) + " "
+}
+
+// ordinal returns the English ordinal word of a number, counting negative
+// numbers from the end as in "last" and "second to last".
+func ordinal(n int) string {
+	switch {
+	case n == -1:
+		return "last"
+	case n < 0:
+		return ordinal(-n) + " to last"
+	case n < len(ordinalWords):
+		return ordinalWords[n]
+	}
+	return numberth(n)
+}
+
+// numberth returns the number with its English ordinal suffix, as in "15th".
+func numberth(n int) string {
+	suffix := "th"
+	switch n % 100 {
+	case 11, 12, 13:
+	default:
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// clockTime returns a "1504" time as "15:04".
+func clockTime(s string) string {
+	t, err := time.Parse(timefmt, s)
+	if err != nil {
+		return s
+	}
+	return t.Format("15:04")
+}
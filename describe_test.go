@@ -0,0 +1,68 @@
+package timewarp_test
+
+import (
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/takeinitiative/timewarp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Describe", func() {
+	// golden returns the expressions of the golden file with their
+	// descriptions
+	golden := func() map[string]string {
+		data, err := ioutil.ReadFile("testdata/describe.golden")
+		Expect(err).NotTo(HaveOccurred())
+
+		cases := make(map[string]string)
+		for _, block := range strings.Split(string(data), "\n\n") {
+			lines := strings.Split(strings.TrimSpace(block), "\n")
+			if strings.HasPrefix(lines[0], "#") {
+				continue
+			}
+			Expect(lines).To(HaveLen(2), "case %q", block)
+			cases[lines[0]] = lines[1]
+		}
+		return cases
+	}
+
+	It("should match the golden file", func() {
+		for s, desc := range golden() {
+			e, err := timewarp.ParseExprString(s)
+			Expect(err).NotTo(HaveOccurred(), s)
+			Expect(timewarp.Describe(e)).To(Equal(desc), s)
+		}
+	})
+
+	It("should cover every README example", func() {
+		data, err := ioutil.ReadFile("README.md")
+		Expect(err).NotTo(HaveOccurred())
+
+		cases := golden()
+		examples := regexp.MustCompile("(?m)^Syntax: `(.*)`$").FindAllStringSubmatch(string(data), -1)
+		Expect(examples).NotTo(BeEmpty())
+		for _, m := range examples {
+			Expect(cases).To(HaveKey(m[1]))
+		}
+	})
+
+	It("should count negative ordinals from the end", func() {
+		for n, desc := range map[int]string{
+			-1: "the last Friday of every month",
+			-2: "the second to last Friday of every month",
+			-3: "the third to last Friday of every month",
+		} {
+			e := &timewarp.OrdinalExpr{X: &timewarp.WeekdayExpr{From: 5, To: 5}, N: n, Unit: &timewarp.MonthExpr{}}
+			Expect(timewarp.Describe(e)).To(Equal(desc))
+		}
+	})
+
+	It("should describe named expressions by their definition", func() {
+		e := &timewarp.NamedExpr{Name: "weekend", X: &timewarp.WeekdayExpr{From: 6, To: 0}}
+		Expect(timewarp.Describe(e)).To(Equal("Saturday through Sunday"))
+	})
+})
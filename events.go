@@ -0,0 +1,175 @@
+package timewarp
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// partitionChunk is the span of events evaluated at once by Partition, and
+// partitionLeadIn is how far before each chunk the filter is evaluated so
+// that ranges already in progress are found.
+const (
+	partitionChunk  = 7 * 24 * time.Hour
+	partitionLeadIn = 7 * 24 * time.Hour
+)
+
+// Event is a timestamped value.
+type Event struct {
+	Time time.Time
+	Data interface{}
+}
+
+// BucketCount is the number of timestamps within a time range.
+type BucketCount struct {
+	Range TimeRange
+	Count int
+}
+
+// MarshalJSON encodes the bucket as an object of its start and end times and
+// its count.
+func (b BucketCount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonRange
+		Count int `json:"count"`
+	}{newJSONRange(b.Range), b.Count})
+}
+
+// UnmarshalJSON decodes an object of start and end times and a count.
+func (b *BucketCount) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Count int `json:"count"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := b.Range.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	b.Count = v.Count
+	return nil
+}
+
+// FilterTimes returns the timestamps that are within the results of the
+// filter, in their original order.  The filter is evaluated once, from a week
+// before the day of the earliest timestamp so that ranges in progress are
+// found, like Partition, to the latest timestamp.
+func FilterTimes(f Filter, ts []time.Time) []time.Time {
+	if len(ts) == 0 {
+		return nil
+	}
+
+	order := make([]int, len(ts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return ts[order[i]].Before(ts[order[j]])
+	})
+
+	var (
+		y, mo, d = ts[order[0]].Date()
+		start    = time.Date(y, mo, d, 0, 0, 0, 0, ts[order[0]].Location())
+		last     = ts[order[len(order)-1]]
+		ranges   = matchedRanges(f, TimeRange{start.Add(-partitionLeadIn), last.Add(1)})
+		match    = make([]bool, len(ts))
+	)
+
+	// sweep the sorted timestamps and ranges together
+	var r int
+	for _, i := range order {
+		for r < len(ranges) && !ranges[r].End.After(ts[i]) {
+			r++
+		}
+		if r == len(ranges) {
+			break
+		}
+		match[i] = !ts[i].Before(ranges[r].Start)
+	}
+
+	var result []time.Time
+	for i, t := range ts {
+		if match[i] {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// Partition sends each event to the matched channel if its time is within
+// the results of the filter, or to the unmatched channel otherwise.  Events
+// should arrive in time order; the filter is evaluated for a week of events
+// at a time, starting a week earlier so that ranges in progress are found.
+// Both channels must be drained, and are closed once the events channel is
+// closed.
+func Partition(f Filter, events <-chan Event) (matched, unmatched <-chan Event) {
+	var (
+		m = make(chan Event)
+		u = make(chan Event)
+	)
+
+	go func() {
+		defer close(m)
+		defer close(u)
+
+		var (
+			chunk  TimeRange
+			ranges []*TimeRange
+		)
+		for e := range events {
+			if e.Time.Before(chunk.Start) || !e.Time.Before(chunk.End) {
+				y, mo, d := e.Time.Date()
+				start := time.Date(y, mo, d, 0, 0, 0, 0, e.Time.Location())
+				chunk = TimeRange{start, start.Add(partitionChunk)}
+				ranges = matchedRanges(f, TimeRange{start.Add(-partitionLeadIn), chunk.End})
+			}
+
+			// find the first range that ends after the event
+			i := sort.Search(len(ranges), func(i int) bool {
+				return ranges[i].End.After(e.Time)
+			})
+			if i < len(ranges) && !e.Time.Before(ranges[i].Start) {
+				m <- e
+			} else {
+				u <- e
+			}
+		}
+	}()
+
+	return m, u
+}
+
+// Bucket returns the number of timestamps within each result of the filter
+// over the window, in the order of the results.  Results with no timestamps
+// have a zero count.
+func Bucket(f Filter, window TimeRange, ts []time.Time) []*BucketCount {
+	sorted := append([]time.Time(nil), ts...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Before(sorted[j])
+	})
+
+	// index returns the number of timestamps before t
+	index := func(t time.Time) int {
+		return sort.Search(len(sorted), func(i int) bool {
+			return !sorted[i].Before(t)
+		})
+	}
+
+	var result []*BucketCount
+	for _, r := range f(window) {
+		result = append(result, &BucketCount{Range: *r, Count: index(r.End) - index(r.Start)})
+	}
+	return result
+}
+
+// matchedRanges returns the sorted and merged results of the filter, without
+// changing the results of the filter.
+func matchedRanges(f Filter, input TimeRange) []*TimeRange {
+	var ranges []*TimeRange
+	for _, r := range f(input) {
+		tr := *r
+		ranges = append(ranges, &tr)
+	}
+	Merge(&ranges)
+	return ranges
+}
@@ -0,0 +1,102 @@
+package timewarp_test
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/takeinitiative/timewarp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Events", func() {
+	var (
+		business timewarp.Filter
+		at       = func(d, h, m int) time.Time {
+			// 11-07-16 is a Monday
+			return time.Date(2016, 11, d, h, m, 0, 0, time.UTC)
+		}
+	)
+
+	BeforeEach(func() {
+		var err error
+		business, err = timewarp.ParseString(`DAY MONDAY FRIDAY IN TIME 0900 1700`)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("FilterTimes", func() {
+		It("should keep the timestamps in business hours in order", func() {
+			ts := []time.Time{at(9, 12, 0), at(12, 10, 0), at(7, 8, 59), at(7, 9, 0), at(11, 16, 59), at(11, 17, 0)}
+			Expect(timewarp.FilterTimes(business, ts)).To(Equal([]time.Time{at(9, 12, 0), at(7, 9, 0), at(11, 16, 59)}))
+		})
+
+		It("should not depend on the other timestamps", func() {
+			Expect(timewarp.FilterTimes(business, []time.Time{at(9, 12, 0)})).To(Equal([]time.Time{at(9, 12, 0)}))
+			Expect(timewarp.FilterTimes(business, []time.Time{at(9, 18, 0)})).To(BeEmpty())
+		})
+
+		It("should handle no timestamps", func() {
+			Expect(timewarp.FilterTimes(business, nil)).To(BeEmpty())
+		})
+	})
+
+	Describe("Partition", func() {
+		It("should split the events", func() {
+			events := make(chan timewarp.Event)
+			go func() {
+				// the second event is in a weekday range already in progress
+				for _, t := range []time.Time{at(5, 10, 0), at(9, 10, 0), at(9, 20, 0), at(21, 9, 30)} {
+					events <- timewarp.Event{Time: t, Data: t.Day()}
+				}
+				close(events)
+			}()
+
+			var matched, unmatched []interface{}
+			m, u := timewarp.Partition(business, events)
+			for m != nil || u != nil {
+				select {
+				case e, ok := <-m:
+					if !ok {
+						m = nil
+						continue
+					}
+					matched = append(matched, e.Data)
+				case e, ok := <-u:
+					if !ok {
+						u = nil
+						continue
+					}
+					unmatched = append(unmatched, e.Data)
+				}
+			}
+
+			Expect(matched).To(Equal([]interface{}{9, 21}))
+			Expect(unmatched).To(Equal([]interface{}{5, 9}))
+		})
+	})
+
+	Describe("Bucket", func() {
+		It("should count the events per range", func() {
+			ts := []time.Time{at(8, 10, 0), at(7, 9, 0), at(8, 16, 0), at(8, 18, 0), at(10, 12, 0)}
+			counts := timewarp.Bucket(business, timewarp.TimeRange{at(7, 0, 0), at(10, 0, 0)}, ts)
+
+			Expect(counts).To(HaveLen(3))
+			Expect(counts[0].Range.Start).To(Equal(at(7, 9, 0)))
+			Expect(counts[0].Count).To(Equal(1))
+			Expect(counts[1].Count).To(Equal(2))
+			Expect(counts[2].Count).To(Equal(0))
+		})
+
+		It("should marshal the count with the range", func() {
+			count := timewarp.BucketCount{timewarp.TimeRange{at(7, 9, 0), at(7, 17, 0)}, 3}
+			b, err := json.Marshal(count)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(b)).To(Equal(`{"start":"2016-11-07T09:00:00Z","end":"2016-11-07T17:00:00Z","count":3}`))
+
+			var out timewarp.BucketCount
+			Expect(json.Unmarshal(b, &out)).To(Succeed())
+			Expect(out).To(Equal(count))
+		})
+	})
+})
@@ -0,0 +1,108 @@
+package timewarp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Trace records the evaluation of an expression node and its children.
+type Trace struct {
+	Expr     string       `json:"expr"`
+	Pos      Pos          `json:"pos"`
+	Calls    []*TraceCall `json:"calls"`
+	Children []*Trace     `json:"children,omitempty"`
+}
+
+// TraceCall records a single evaluation of an expression node.
+type TraceCall struct {
+	Input   TimeRange    `json:"input"`
+	Output  []*TimeRange `json:"output"`
+	Dropped []*Dropped   `json:"dropped,omitempty"`
+}
+
+// Dropped describes a candidate that did not produce a result.
+type Dropped struct {
+	Range  TimeRange `json:"range"`
+	Reason string    `json:"reason"`
+}
+
+// Explain evaluates the expression over the window and records the input
+// windows, the produced ranges and the dropped candidates of every node.
+func Explain(e Expr, window TimeRange) *Trace {
+	f, t := explain(e, func(e Expr) Filter { return e.Filter() })
+	f(window)
+	return t
+}
+
+// explain compiles the expression into a filter that records its
+// evaluation.  The compile function compiles leaf expressions.
+func explain(e Expr, compile func(Expr) Filter) (Filter, *Trace) {
+	var (
+		t = &Trace{Expr: e.String(), Pos: e.Pos()}
+		f Filter
+	)
+
+	switch e := e.(type) {
+	case *BinaryExpr:
+		fx, tx := explain(e.X, compile)
+		fy, ty := explain(e.Y, compile)
+		t.Children = []*Trace{tx, ty}
+		if e.Op == IN {
+			f = fx.Intersect(fy)
+		} else {
+			f = fx.Union(fy)
+		}
+	case *OrdinalExpr:
+		fx, tx := explain(e.X, compile)
+		fu, tu := explain(e.Unit, func(unit Expr) Filter { return unitFilter(unit, e.N) })
+		t.Children = []*Trace{tx, tu}
+		f = fx.ordinal(e.N, fu, func(unit TimeRange, reason string) {
+			call := t.Calls[len(t.Calls)-1]
+			call.Dropped = append(call.Dropped, &Dropped{Range: unit, Reason: reason})
+		})
+	case *NotExpr:
+		fx, tx := explain(e.X, compile)
+		t.Children = []*Trace{tx}
+		f = fx.Negate()
+	case *NamedExpr:
+		fx, tx := explain(e.X, compile)
+		t.Expr = e.Name
+		t.Children = []*Trace{tx}
+		f = fx
+	default:
+		f = compile(e)
+	}
+
+	return func(input TimeRange) []*TimeRange {
+		call := &TraceCall{Input: input}
+		t.Calls = append(t.Calls, call)
+
+		call.Output = f(input)
+		return call.Output
+	}, t
+}
+
+// String returns the trace as an indented text tree.
+func (t *Trace) String() string {
+	var buf strings.Builder
+	t.write(&buf, "")
+	return buf.String()
+}
+
+// write writes the trace to the buffer with the given indentation.
+func (t *Trace) write(buf *strings.Builder, indent string) {
+	fmt.Fprintf(buf, "%s%s (%s)\n", indent, t.Expr, t.Pos)
+	for _, call := range t.Calls {
+		fmt.Fprintf(buf, "%s  in %s: %d result(s)\n", indent, &call.Input, len(call.Output))
+		for _, r := range call.Output {
+			fmt.Fprintf(buf, "%s    %s\n", indent, r)
+		}
+		for _, d := range call.Dropped {
+			fmt.Fprintf(buf, "%s    dropped %s: %s\n", indent, &d.Range, d.Reason)
+		}
+	}
+
+	for _, child := range t.Children {
+		child.write(buf, indent+"  ")
+	}
+}
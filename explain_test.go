@@ -0,0 +1,86 @@
+package timewarp_test
+
+import (
+	"encoding/json"
+
+	"github.com/takeinitiative/timewarp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Explain", func() {
+	const datefmt = "01-02-06"
+
+	var (
+		in    string
+		r     *timewarp.TimeRange
+		trace *timewarp.Trace
+	)
+
+	JustBeforeEach(func() {
+		e, err := timewarp.ParseExprString(in)
+		Expect(err).NotTo(HaveOccurred())
+		trace = timewarp.Explain(e, *r)
+	})
+
+	Context("Fifth Tuesday of the month", func() {
+		BeforeEach(func() {
+			in = `DAY TUESDAY OF 5 MONTH`
+			r, _ = timewarp.Parse(datefmt, "01-01-18", "05-01-18")
+		})
+
+		It("should record the result", func() {
+			Expect(trace.Expr).To(Equal(in))
+			Expect(trace.Calls).To(HaveLen(1))
+			Expect(trace.Calls[0].Input).To(Equal(*r))
+
+			slot, _ := timewarp.Parse(datefmt, "01-30-18", "01-31-18")
+			Expect(trace.Calls[0].Output).To(Equal([]*timewarp.TimeRange{slot}))
+		})
+
+		It("should record the dropped months", func() {
+			feb, _ := timewarp.Parse(datefmt, "02-01-18", "03-01-18")
+			Expect(trace.Calls[0].Dropped).To(HaveLen(3))
+			Expect(trace.Calls[0].Dropped[0].Range).To(Equal(*feb))
+			Expect(trace.Calls[0].Dropped[0].Reason).To(Equal("ordinal 5 exceeds 4 results"))
+		})
+
+		It("should record the evaluation of each node", func() {
+			Expect(trace.Children).To(HaveLen(2))
+			Expect(trace.Children[0].Expr).To(Equal("DAY TUESDAY"))
+			Expect(trace.Children[0].Calls).To(HaveLen(4))
+			Expect(trace.Children[1].Expr).To(Equal("MONTH"))
+			Expect(trace.Children[1].Calls).To(HaveLen(1))
+			Expect(trace.Children[1].Calls[0].Output).To(HaveLen(4))
+		})
+
+		It("should render as text", func() {
+			Expect(trace.String()).To(ContainSubstring("DAY TUESDAY OF 5 MONTH (1 col 1)\n  in 2018-01-01T00:00:00Z/2018-05-01T00:00:00Z: 1 result(s)\n    2018-01-30T00:00:00Z/2018-01-31T00:00:00Z\n    dropped 2018-02-01T00:00:00Z/2018-03-01T00:00:00Z: ordinal 5 exceeds 4 results\n"))
+			Expect(trace.String()).To(ContainSubstring("\n  DAY TUESDAY (1 col 1)\n"))
+		})
+
+		It("should render as JSON", func() {
+			b, err := json.Marshal(trace)
+			Expect(err).NotTo(HaveOccurred())
+
+			var v map[string]interface{}
+			Expect(json.Unmarshal(b, &v)).To(Succeed())
+			Expect(v["expr"]).To(Equal(in))
+			Expect(v["children"]).To(HaveLen(2))
+		})
+	})
+
+	Context("Out of scope ordinal", func() {
+		BeforeEach(func() {
+			in = `DAY OF MONTH`
+			r, _ = timewarp.Parse(datefmt, "01-15-18", "02-15-18")
+		})
+
+		It("should record the reason", func() {
+			Expect(trace.Calls[0].Output).To(HaveLen(1))
+			Expect(trace.Calls[0].Dropped).To(HaveLen(1))
+			Expect(trace.Calls[0].Dropped[0].Reason).To(ContainSubstring("is outside the input"))
+		})
+	})
+})
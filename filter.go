@@ -1,6 +1,11 @@
 package timewarp
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
 
 // Query is a function that finds the first matching slot in a time range.
 type Query func(input TimeRange) (output *TimeRange)
@@ -49,9 +54,13 @@ type Filter func(input TimeRange) []*TimeRange
 // Negate returns a filter that returns the inverse results
 func (f Filter) Negate() Filter {
 	return func(input TimeRange) []*TimeRange {
-		var result []*TimeRange
+		var (
+			result []*TimeRange
+			ranges = f(input)
+		)
 
-		for _, s := range f(input) {
+		Merge(&ranges)
+		for _, s := range ranges {
 			if input.Start.Before(s.Start) {
 				result = append(result, &TimeRange{input.Start, s.Start})
 			}
@@ -66,10 +75,18 @@ func (f Filter) Negate() Filter {
 	}
 }
 
-// Union returns a filter that's result comprises of multiple filters
+// Union returns a filter that's result comprises of multiple filters.  The
+// results are sorted and overlapping ranges are merged.
 func (f Filter) Union(filters ...Filter) Filter {
+	return Raw(append([]Filter{f}, filters...)...).merged()
+}
+
+// Raw returns a filter that concatenates the results of each filter in order,
+// without sorting or merging them.  Use it instead of Union to tell which
+// filter produced each range.
+func Raw(filters ...Filter) Filter {
 	return func(input TimeRange) []*TimeRange {
-		var result = f(input)
+		var result []*TimeRange
 
 		for _, f := range filters {
 			result = append(result, f(input)...)
@@ -79,6 +96,31 @@ func (f Filter) Union(filters ...Filter) Filter {
 	}
 }
 
+// Ranges returns a filter of fixed time ranges, such as busy times read from
+// a calendar.  The ranges are copied, and the results are clipped to the
+// input, sorted and merged.
+func Ranges(ranges ...*TimeRange) Filter {
+	var fixed []*TimeRange
+	for _, r := range ranges {
+		tr := *r
+		fixed = append(fixed, &tr)
+	}
+	Merge(&fixed)
+
+	return func(input TimeRange) []*TimeRange {
+		return clipAll(fixed, input)
+	}
+}
+
+// merged returns a filter that sorts and merges the results of the filter.
+func (f Filter) merged() Filter {
+	return func(input TimeRange) []*TimeRange {
+		var result = f(input)
+		Merge(&result)
+		return result
+	}
+}
+
 // And is same as Union, but passes a query instead of a filter
 func (f Filter) And(queries ...Query) Filter {
 	var filters []Filter
@@ -88,9 +130,10 @@ func (f Filter) And(queries ...Query) Filter {
 	return f.Union(filters...)
 }
 
-// Intersect returns a filter that's result must satisfy all filters
+// Intersect returns a filter that's result must satisfy all filters.  The
+// results are sorted and overlapping ranges are merged.
 func (f Filter) Intersect(filters ...Filter) Filter {
-	return func(input TimeRange) []*TimeRange {
+	return Filter(func(input TimeRange) []*TimeRange {
 		var result = f(input)
 
 		for _, f := range filters {
@@ -104,7 +147,7 @@ func (f Filter) Intersect(filters ...Filter) Filter {
 		}
 
 		return result
-	}
+	}).merged()
 }
 
 // In is the same as Intersect but passes a query instead of a filter
@@ -116,46 +159,73 @@ func (f Filter) In(queries ...Query) Filter {
 	return f.Intersect(filters...)
 }
 
-// Ordinal returns a filter of ranges within the ordinal range
+// Ordinal returns a filter of ranges within the ordinal range.  The results
+// are sorted and overlapping ranges are merged.
 func (f Filter) Ordinal(order int, filter Filter) Filter {
+	return f.ordinal(order, filter, nil)
+}
+
+// ordinal implements Ordinal.  If drop is not nil, it is called with the unit
+// and the reason for every unit that does not produce a result.
+func (f Filter) ordinal(order int, filter Filter, drop func(unit TimeRange, reason string)) Filter {
 	if order == 0 {
 		panic("ordinal cannot be zero")
 	}
 
-	return func(input TimeRange) (result []*TimeRange) {
+	return Filter(func(input TimeRange) (result []*TimeRange) {
 		for _, v := range filter(input) {
 			var r = f(*v)
 
 			// find the range that satisfies the ordinal
-			var output = &TimeRange{}
-			if size := len(r); order < 0 {
-				if -order > size {
-					continue
-				}
-				output = r[order+size]
-			} else {
-				if order > size {
-					continue
+			i := ordinalIndex(order, len(r))
+			if i < 0 {
+				if drop != nil {
+					drop(*v, fmt.Sprintf("ordinal %d exceeds %d results", order, len(r)))
 				}
-				output = r[order-1]
-			}
-
-			// continue if the objective value exists, but out of scope
-			if !output.Start.Before(input.End) || !output.End.After(input.Start) {
 				continue
 			}
 
-			// adjust start and end to meet input criteria
-			if output.Start.Before(input.Start) {
-				output.Start = input.Start
-			}
-			if output.End.After(input.End) {
-				output.End = input.End
+			// continue if the objective value exists, but out of scope
+			if output := r[i]; clip(output, input) {
+				result = append(result, output)
+			} else if drop != nil {
+				drop(*v, fmt.Sprintf("result %s is outside the input", output))
 			}
-			result = append(result, output)
 		}
 		return
+	}).merged()
+}
+
+// ordinalIndex returns the index of the ordinal within a number of results.
+// Negative ordinals count from the end.  Returns -1 if there are not enough
+// results.
+func ordinalIndex(order, size int) int {
+	if order < 0 {
+		if -order > size {
+			return -1
+		}
+		return order + size
+	}
+	if order > size {
+		return -1
+	}
+	return order - 1
+}
+
+// clip adjusts the start and end of the time range to meet the input
+// criteria.  Returns false if the time range is out of scope.
+func clip(tr *TimeRange, input TimeRange) bool {
+	if !tr.Start.Before(input.End) || !tr.End.After(input.Start) {
+		return false
+	}
+
+	if tr.Start.Before(input.Start) {
+		tr.Start = input.Start
 	}
+	if tr.End.After(input.End) {
+		tr.End = input.End
+	}
+	return true
 }
 
 // Of is the same as Ordinal, but passes a query instead of a filter
@@ -172,3 +242,291 @@ func (f Filter) Apply(start, end time.Time) []*TimeRange {
 func (f Filter) ApplySeconds(start, end int64) []*TimeRange {
 	return f.Apply(time.Unix(start, 0), time.Unix(end, 0))
 }
+
+// InLocation returns a filter that divides days in the location.  Filters
+// divide days in UTC, so the input is evaluated at its wall clock times in UTC
+// and the results are returned at the same wall clock times in the location.
+func (f Filter) InLocation(loc *time.Location) Filter {
+	return func(input TimeRange) []*TimeRange {
+		var result []*TimeRange
+
+		start, end := wallClock(input.Start.In(loc), time.UTC), wallClock(input.End.In(loc), time.UTC)
+		for _, r := range f(TimeRange{start, end}) {
+			result = append(result, &TimeRange{wallClock(r.Start, loc), wallClock(r.End, loc)})
+		}
+
+		return result
+	}
+}
+
+// Within returns the sorted and merged results of the filter clipped to the
+// window.  The filter is evaluated from a week before the window so that
+// ranges in progress at its start are included.
+func (f Filter) Within(window TimeRange) []*TimeRange {
+	return clipAll(matchedRanges(f, TimeRange{window.Start.AddDate(0, 0, -7), window.End}), window)
+}
+
+// wallClock returns the time with the same wall clock time in the location.
+func wallClock(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
+
+// LabeledRange is a time range annotated with the labels of the filters that
+// produced it.
+type LabeledRange struct {
+	Range  TimeRange
+	Labels []string
+}
+
+// MarshalJSON encodes the range as an object of its start and end times and
+// its labels.
+func (r LabeledRange) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonRange
+		Labels []string `json:"labels"`
+	}{newJSONRange(r.Range), r.Labels})
+}
+
+// UnmarshalJSON decodes an object of start and end times and labels.
+func (r *LabeledRange) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Labels []string `json:"labels"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := r.Range.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	r.Labels = v.Labels
+	return nil
+}
+
+// LabeledFilter is a filter that reports which labeled filters produced each
+// time range.  Results are split where their labels change, so adjacent
+// results with different labels are parts of a single range of the unlabeled
+// filter, while adjacent results with the same labels are separate ranges.
+type LabeledFilter func(input TimeRange) []*LabeledRange
+
+// Label returns a labeled filter that annotates each result with the labels.
+func (f Filter) Label(labels ...string) LabeledFilter {
+	return func(input TimeRange) []*LabeledRange {
+		var result []*LabeledRange
+
+		for _, r := range f(input) {
+			result = append(result, &LabeledRange{Range: *r, Labels: labels})
+		}
+
+		return result
+	}
+}
+
+// Label returns a labeled filter that adds the labels to each result.  Parts
+// of a range that end up with the same labels are joined.
+func (f LabeledFilter) Label(labels ...string) LabeledFilter {
+	return func(input TimeRange) []*LabeledRange {
+		var result []*LabeledRange
+
+		for _, parts := range labeledGroups(f(input)) {
+			first := len(result)
+			for _, r := range parts {
+				r.Labels = joinLabels(r.Labels, labels)
+				if n := len(result); n > first && sameLabels(result[n-1].Labels, r.Labels) {
+					result[n-1].Range.End = r.Range.End
+				} else {
+					result = append(result, r)
+				}
+			}
+		}
+
+		return result
+	}
+}
+
+// Filter drops the labels from the results and joins the parts of each
+// range, producing the results of the unlabeled filter.
+func (f LabeledFilter) Filter() Filter {
+	return func(input TimeRange) []*TimeRange {
+		var result []*TimeRange
+
+		for _, parts := range labeledGroups(f(input)) {
+			result = append(result, &TimeRange{parts[0].Range.Start, parts[len(parts)-1].Range.End})
+		}
+
+		return result
+	}
+}
+
+// Union returns a labeled filter that's result comprises of multiple labeled
+// filters.  The results are sorted and split wherever the labels change, so
+// overlapping ranges carry the labels of every filter that produced them.
+func (f LabeledFilter) Union(filters ...LabeledFilter) LabeledFilter {
+	return func(input TimeRange) []*LabeledRange {
+		var result = f(input)
+
+		for _, f := range filters {
+			result = append(result, f(input)...)
+		}
+
+		return mergeLabeled(result)
+	}
+}
+
+// Intersect returns a labeled filter that's result must satisfy all labeled
+// filters.  Each result carries the labels of all filters.  Like Intersect,
+// each filter is evaluated within whole ranges of the previous results, not
+// within their parts.
+func (f LabeledFilter) Intersect(filters ...LabeledFilter) LabeledFilter {
+	return func(input TimeRange) []*LabeledRange {
+		var result = f(input)
+
+		for _, f := range filters {
+			var output []*LabeledRange
+
+			for _, parts := range labeledGroups(result) {
+				for _, r := range f(TimeRange{parts[0].Range.Start, parts[len(parts)-1].Range.End}) {
+					// split the result by the parts it overlaps
+					for _, s := range parts {
+						part := &LabeledRange{Range: r.Range, Labels: joinLabels(s.Labels, r.Labels)}
+						if clip(&part.Range, s.Range) {
+							output = append(output, part)
+						}
+					}
+				}
+			}
+
+			result = output
+		}
+
+		return mergeLabeled(result)
+	}
+}
+
+// Ordinal returns a labeled filter of ranges within the ordinal range.  Like
+// Ordinal, whole ranges are counted rather than their parts.
+func (f LabeledFilter) Ordinal(order int, filter Filter) LabeledFilter {
+	if order == 0 {
+		panic("ordinal cannot be zero")
+	}
+
+	return func(input TimeRange) (result []*LabeledRange) {
+		for _, v := range filter(input) {
+			var ranges = labeledGroups(f(*v))
+
+			i := ordinalIndex(order, len(ranges))
+			if i < 0 {
+				continue
+			}
+
+			for _, part := range ranges[i] {
+				if clip(&part.Range, input) {
+					result = append(result, part)
+				}
+			}
+		}
+		return mergeLabeled(result)
+	}
+}
+
+// labeledGroups groups sorted labeled results into the parts of each range:
+// adjacent results with different labels.
+func labeledGroups(ranges []*LabeledRange) [][]*LabeledRange {
+	var groups [][]*LabeledRange
+
+	for i, r := range ranges {
+		if n := len(groups); n > 0 && ranges[i-1].Range.End.Equal(r.Range.Start) && !sameLabels(ranges[i-1].Labels, r.Labels) {
+			groups[n-1] = append(groups[n-1], r)
+		} else {
+			groups = append(groups, []*LabeledRange{r})
+		}
+	}
+
+	return groups
+}
+
+// joinLabels returns the labels of both slices without duplicates.
+func joinLabels(a, b []string) []string {
+	var result = append([]string(nil), a...)
+
+	for _, label := range b {
+		var found bool
+		for _, l := range result {
+			if l == label {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, label)
+		}
+	}
+
+	return result
+}
+
+// mergeLabeled sorts labeled ranges and merges them so no two results
+// overlap.  Overlapping ranges are split where the labels change, and
+// adjacent ranges with the same labels are joined.
+func mergeLabeled(ranges []*LabeledRange) []*LabeledRange {
+	type edge struct {
+		at    time.Time
+		start bool
+		r     *LabeledRange
+	}
+
+	var edges []edge
+	for _, r := range ranges {
+		if r.Range.Start.Before(r.Range.End) {
+			edges = append(edges, edge{r.Range.Start, true, r}, edge{r.Range.End, false, r})
+		}
+	}
+	sort.SliceStable(edges, func(i, j int) bool {
+		return edges[i].at.Before(edges[j].at)
+	})
+
+	var (
+		result []*LabeledRange
+		active []*LabeledRange
+	)
+	for i := 0; i < len(edges); {
+		at := edges[i].at
+
+		// apply every edge at this instant
+		for ; i < len(edges) && edges[i].at.Equal(at); i++ {
+			if edges[i].start {
+				active = append(active, edges[i].r)
+				continue
+			}
+			for j, r := range active {
+				if r == edges[i].r {
+					active = append(active[:j], active[j+1:]...)
+					break
+				}
+			}
+		}
+
+		if len(active) == 0 || i == len(edges) {
+			continue
+		}
+
+		var labels []string
+		for _, r := range active {
+			labels = joinLabels(labels, r.Labels)
+		}
+
+		// extend the previous range if it is adjacent with the same labels
+		end := edges[i].at
+		if n := len(result); n > 0 && result[n-1].Range.End.Equal(at) && sameLabels(result[n-1].Labels, labels) {
+			result[n-1].Range.End = end
+		} else {
+			result = append(result, &LabeledRange{Range: TimeRange{at, end}, Labels: labels})
+		}
+	}
+
+	return result
+}
+
+// sameLabels returns true if both slices contain the same labels.
+func sameLabels(a, b []string) bool {
+	return len(a) == len(b) && len(joinLabels(a, b)) == len(a)
+}
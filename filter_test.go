@@ -1,9 +1,10 @@
 package timewarp_test
 
 import (
+	"encoding/json"
 	"time"
 
-	. "github.com/takeinitiative/timewarp"
+	"github.com/takeinitiative/timewarp"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -13,10 +14,11 @@ var _ = Describe("Filter", func() {
 	const datefmt = "01-02-06"
 
 	var (
-		f      Filter
-		in     *TimeRange
-		out    []*TimeRange
+		f      timewarp.Filter
+		in     *timewarp.TimeRange
+		out    []*timewarp.TimeRange
 		result []interface{}
+		sorted []*timewarp.TimeRange
 	)
 
 	JustBeforeEach(func() {
@@ -26,12 +28,12 @@ var _ = Describe("Filter", func() {
 	Context("Filter from Query", func() {
 
 		BeforeEach(func() {
-			f = Month(time.June).Filter()
-			in, _ = Parse(datefmt, "06-12-13", "06-20-15")
+			f = timewarp.Month(time.June).Filter()
+			in, _ = timewarp.Parse(datefmt, "06-12-13", "06-20-15")
 
-			slot1, _ := Parse(datefmt, "06-12-13", "07-01-13")
-			slot2, _ := Parse(datefmt, "06-01-14", "07-01-14")
-			slot3, _ := Parse(datefmt, "06-01-15", "06-20-15")
+			slot1, _ := timewarp.Parse(datefmt, "06-12-13", "07-01-13")
+			slot2, _ := timewarp.Parse(datefmt, "06-01-14", "07-01-14")
+			slot3, _ := timewarp.Parse(datefmt, "06-01-15", "06-20-15")
 			result = []interface{}{slot1, slot2, slot3}
 		})
 
@@ -44,11 +46,11 @@ var _ = Describe("Filter", func() {
 	Context("Negate", func() {
 
 		BeforeEach(func() {
-			f = Month(time.June).Not()
-			in, _ = Parse(datefmt, "06-12-13", "06-20-15")
+			f = timewarp.Month(time.June).Not()
+			in, _ = timewarp.Parse(datefmt, "06-12-13", "06-20-15")
 
-			slot1, _ := Parse(datefmt, "07-01-13", "06-01-14")
-			slot2, _ := Parse(datefmt, "07-01-14", "06-01-15")
+			slot1, _ := timewarp.Parse(datefmt, "07-01-13", "06-01-14")
+			slot2, _ := timewarp.Parse(datefmt, "07-01-14", "06-01-15")
 			result = []interface{}{slot1, slot2}
 		})
 
@@ -60,34 +62,100 @@ var _ = Describe("Filter", func() {
 	Context("Union", func() {
 
 		BeforeEach(func() {
-			mf1 := Month(time.June).Filter()
-			mf2 := Month(time.July).Filter()
-			mf3 := Month(time.November).Filter()
+			mf1 := timewarp.Month(time.June).Filter()
+			mf2 := timewarp.Month(time.July).Filter()
+			mf3 := timewarp.Month(time.November).Filter()
 			f = mf1.Union(mf2, mf3)
 
-			in, _ = Parse(datefmt, "11-04-13", "08-01-14")
+			in, _ = timewarp.Parse(datefmt, "11-04-13", "08-01-14")
 
-			slot1, _ := Parse(datefmt, "11-04-13", "12-01-13")
-			slot2, _ := Parse(datefmt, "06-01-14", "07-01-14")
-			slot3, _ := Parse(datefmt, "07-01-14", "08-01-14")
-			result = []interface{}{slot1, slot2, slot3}
+			slot1, _ := timewarp.Parse(datefmt, "11-04-13", "12-01-13")
+			slot2, _ := timewarp.Parse(datefmt, "06-01-14", "08-01-14")
+			sorted = []*timewarp.TimeRange{slot1, slot2}
 		})
 
-		It("should return the union of the results", func() {
-			Expect(out).To(ConsistOf(result...))
+		It("should return the sorted and merged union of the results", func() {
+			Expect(out).To(Equal(sorted))
+		})
+	})
+
+	Context("Union of overlapping weekdays", func() {
+
+		BeforeEach(func() {
+			f = timewarp.Week(time.Monday, 5).And(timewarp.Week(time.Wednesday, 1))
+			in, _ = timewarp.Parse(datefmt, "11-07-16", "11-21-16")
+
+			slot1, _ := timewarp.Parse(datefmt, "11-07-16", "11-12-16")
+			slot2, _ := timewarp.Parse(datefmt, "11-14-16", "11-19-16")
+			sorted = []*timewarp.TimeRange{slot1, slot2}
+		})
+
+		It("should return the sorted and merged union of the results", func() {
+			Expect(out).To(Equal(sorted))
+		})
+	})
+
+	Context("Raw", func() {
+
+		BeforeEach(func() {
+			f = timewarp.Raw(timewarp.Month(time.July).Filter(), timewarp.Month(time.June).Filter())
+			in, _ = timewarp.Parse(datefmt, "11-04-13", "08-01-14")
+
+			slot1, _ := timewarp.Parse(datefmt, "07-01-14", "08-01-14")
+			slot2, _ := timewarp.Parse(datefmt, "06-01-14", "07-01-14")
+			sorted = []*timewarp.TimeRange{slot1, slot2}
+		})
+
+		It("should return the results of each filter in order", func() {
+			Expect(out).To(Equal(sorted))
+		})
+	})
+
+	Context("Ranges", func() {
+
+		BeforeEach(func() {
+			a, _ := timewarp.Parse(datefmt, "11-10-16", "11-14-16")
+			b, _ := timewarp.Parse(datefmt, "11-01-16", "11-08-16")
+			c, _ := timewarp.Parse(datefmt, "11-07-16", "11-09-16")
+			f = timewarp.Ranges(a, b, c)
+			in, _ = timewarp.Parse(datefmt, "11-05-16", "11-12-16")
+
+			slot1, _ := timewarp.Parse(datefmt, "11-05-16", "11-09-16")
+			slot2, _ := timewarp.Parse(datefmt, "11-10-16", "11-12-16")
+			sorted = []*timewarp.TimeRange{slot1, slot2}
+		})
+
+		It("should return the ranges merged and clipped to the input", func() {
+			Expect(out).To(Equal(sorted))
+		})
+	})
+
+	Context("Negate a union", func() {
+
+		BeforeEach(func() {
+			f = timewarp.Raw(timewarp.Week(time.Wednesday, 1).Filter(), timewarp.Week(time.Monday, 5).Filter()).Negate()
+			in, _ = timewarp.Parse(datefmt, "11-07-16", "11-21-16")
+
+			slot1, _ := timewarp.Parse(datefmt, "11-12-16", "11-14-16")
+			slot2, _ := timewarp.Parse(datefmt, "11-19-16", "11-21-16")
+			sorted = []*timewarp.TimeRange{slot1, slot2}
+		})
+
+		It("should return the inverse of the unsorted results", func() {
+			Expect(out).To(Equal(sorted))
 		})
 	})
 
 	Context("Intersect", func() {
 
 		BeforeEach(func() {
-			mf := Month(time.June).Filter()
-			yf := Year(2013).Filter()
+			mf := timewarp.Month(time.June).Filter()
+			yf := timewarp.Year(2013).Filter()
 			f = mf.Intersect(yf)
 
-			in, _ = Parse(datefmt, "03-13-13", "04-10-15")
+			in, _ = timewarp.Parse(datefmt, "03-13-13", "04-10-15")
 
-			slot1, _ := Parse(datefmt, "06-01-13", "07-01-13")
+			slot1, _ := timewarp.Parse(datefmt, "06-01-13", "07-01-13")
 			result = []interface{}{slot1}
 		})
 
@@ -99,10 +167,10 @@ var _ = Describe("Filter", func() {
 	Context("Ordinal", func() {
 
 		BeforeEach(func() {
-			f = Week(time.Thursday, 1).Of(4, TheMonth(time.November))
-			in, _ = Parse(datefmt, "11-11-16", "11-30-16")
+			f = timewarp.Week(time.Thursday, 1).Of(4, timewarp.TheMonth(time.November))
+			in, _ = timewarp.Parse(datefmt, "11-11-16", "11-30-16")
 
-			slot1, _ := Parse(datefmt, "11-24-16", "11-25-16")
+			slot1, _ := timewarp.Parse(datefmt, "11-24-16", "11-25-16")
 			result = []interface{}{slot1}
 		})
 
@@ -110,4 +178,132 @@ var _ = Describe("Filter", func() {
 			Expect(out).To(ConsistOf(result...))
 		})
 	})
+
+	Context("InLocation", func() {
+		loc := time.FixedZone("UTC+10", 10*60*60)
+
+		BeforeEach(func() {
+			f = timewarp.Week(time.Monday, 1).Filter().InLocation(loc)
+			in = &timewarp.TimeRange{time.Date(2016, 11, 13, 0, 0, 0, 0, loc), time.Date(2016, 11, 20, 0, 0, 0, 0, loc)}
+
+			slot1 := &timewarp.TimeRange{time.Date(2016, 11, 14, 0, 0, 0, 0, loc), time.Date(2016, 11, 15, 0, 0, 0, 0, loc)}
+			result = []interface{}{slot1}
+		})
+
+		It("should divide days in the location", func() {
+			Expect(out).To(ConsistOf(result...))
+		})
+	})
+
+	Context("Within", func() {
+
+		BeforeEach(func() {
+			f = timewarp.Week(time.Monday, 5).Filter()
+			in, _ = timewarp.Parse(datefmt, "11-09-16", "11-16-16")
+
+			slot1, _ := timewarp.Parse(datefmt, "11-09-16", "11-12-16")
+			slot2, _ := timewarp.Parse(datefmt, "11-14-16", "11-16-16")
+			sorted = []*timewarp.TimeRange{slot1, slot2}
+		})
+
+		It("should include the range in progress clipped to the window", func() {
+			Expect(f.Within(*in)).To(Equal(sorted))
+		})
+	})
+
+	Describe("LabeledFilter", func() {
+		var (
+			lf      timewarp.LabeledFilter
+			labeled []*timewarp.LabeledRange
+		)
+
+		JustBeforeEach(func() {
+			labeled = lf(*in)
+		})
+
+		slot := func(start, end string, labels ...string) *timewarp.LabeledRange {
+			tr, _ := timewarp.Parse(datefmt, start, end)
+			return &timewarp.LabeledRange{Range: *tr, Labels: labels}
+		}
+
+		Context("Union", func() {
+			BeforeEach(func() {
+				weekdays := timewarp.Week(time.Monday, 5).Filter().Label("weekdays")
+				wednesday := timewarp.Week(time.Wednesday, 1).Filter().Label("wednesday")
+				lf = weekdays.Union(wednesday)
+				in, _ = timewarp.Parse(datefmt, "11-07-16", "11-14-16")
+			})
+
+			It("should split the results where the labels change", func() {
+				Expect(labeled).To(Equal([]*timewarp.LabeledRange{
+					slot("11-07-16", "11-09-16", "weekdays"),
+					slot("11-09-16", "11-10-16", "weekdays", "wednesday"),
+					slot("11-10-16", "11-12-16", "weekdays"),
+				}))
+			})
+		})
+
+		Context("Adjacent union", func() {
+			BeforeEach(func() {
+				lf = timewarp.Month(time.June).Filter().Label("summer").Union(timewarp.Month(time.July).Filter().Label("summer"))
+				in, _ = timewarp.Parse(datefmt, "05-01-14", "08-01-14")
+			})
+
+			It("should join adjacent results with the same labels", func() {
+				Expect(labeled).To(Equal([]*timewarp.LabeledRange{
+					slot("06-01-14", "08-01-14", "summer"),
+				}))
+			})
+		})
+
+		Context("Intersect", func() {
+			BeforeEach(func() {
+				lf = timewarp.Month(time.June).Filter().Label("june").Intersect(timewarp.Year(2013).Filter().Label("2013"))
+				in, _ = timewarp.Parse(datefmt, "03-13-13", "04-10-15")
+			})
+
+			It("should carry the labels of every filter", func() {
+				Expect(labeled).To(Equal([]*timewarp.LabeledRange{
+					slot("06-01-13", "07-01-13", "june", "2013"),
+				}))
+			})
+		})
+
+		Context("Ordinal", func() {
+			BeforeEach(func() {
+				lf = timewarp.Week(time.Thursday, 1).Filter().Label("thursday").Ordinal(4, timewarp.TheMonth(time.November).Filter())
+				in, _ = timewarp.Parse(datefmt, "11-11-16", "11-30-16")
+			})
+
+			It("should keep the labels of the selected result", func() {
+				Expect(labeled).To(Equal([]*timewarp.LabeledRange{
+					slot("11-24-16", "11-25-16", "thursday"),
+				}))
+			})
+		})
+
+		Context("Filter", func() {
+			BeforeEach(func() {
+				lf = timewarp.Week(time.Monday, 5).Filter().Label("weekdays")
+				in, _ = timewarp.Parse(datefmt, "11-07-16", "11-14-16")
+			})
+
+			It("should drop the labels", func() {
+				Expect(lf.Filter()(*in)).To(Equal(timewarp.Week(time.Monday, 5).Filter()(*in)))
+			})
+		})
+
+		Context("JSON", func() {
+			It("should marshal the labels with the range", func() {
+				r := slot("11-07-16", "11-09-16", "weekdays", "wednesday")
+				b, err := json.Marshal(r)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(b)).To(Equal(`{"start":"2016-11-07T00:00:00Z","end":"2016-11-09T00:00:00Z","labels":["weekdays","wednesday"]}`))
+
+				var out timewarp.LabeledRange
+				Expect(json.Unmarshal(b, &out)).To(Succeed())
+				Expect(&out).To(Equal(r))
+			})
+		})
+	})
 })
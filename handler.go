@@ -0,0 +1,458 @@
+package timewarp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// HandlerOptions limits the requests served by a handler.
+type HandlerOptions struct {
+	// MaxWindow is the longest window evaluated, how far ahead next
+	// occurrences are searched, and how far either side of a time the range
+	// containing it is searched.  Defaults to 366 days.
+	MaxWindow time.Duration
+
+	// MaxResults is the largest number of ranges returned.  Defaults to 1000.
+	MaxResults int
+
+	// MaxBodyBytes is the largest request body accepted.  Defaults to 64 KiB.
+	MaxBodyBytes int64
+
+	// Clock is the source of the current time, used when a request omits
+	// it.  Defaults to the system clock.
+	Clock Clock
+}
+
+const (
+	defaultMaxWindow    = 366 * 24 * time.Hour
+	defaultMaxResults   = 1000
+	defaultMaxBodyBytes = 64 << 10
+)
+
+// handler serves the endpoints of NewHandler.
+type handler struct {
+	opts HandlerOptions
+}
+
+// NewHandler returns a handler evaluating expressions for clients that cannot
+// link this package.  Each endpoint takes a POST of a JSON object and responds
+// with a JSON object, or with {"error": {message, pos}} and a 4xx status.  Times are
+// RFC 3339 and windows are either {"start", "end"} objects or ISO 8601
+// interval strings.  Days are counted in the optional "zone", UTC by default.
+//
+//	/validate  {expr} → {valid, expr, description, diagnostics, error}
+//	/format    {expr} → {expr}
+//	/evaluate  {expr, window, zone, limit} → {ranges, truncated}
+//	/next      {expr, after, count, zone} → {ranges}
+//	/contains  {expr, time, zone} → {contains, range}
+//	/elapsed   {expr, window, zone} → {elapsed, seconds}
+//
+// The paths are relative to the root, so mount the handler under a prefix
+// with http.StripPrefix.
+func NewHandler(opts HandlerOptions) http.Handler {
+	if opts.MaxWindow <= 0 {
+		opts.MaxWindow = defaultMaxWindow
+	}
+	if opts.MaxResults <= 0 {
+		opts.MaxResults = defaultMaxResults
+	}
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = defaultMaxBodyBytes
+	}
+	if opts.Clock == nil {
+		opts.Clock = RealClock{}
+	}
+	return &handler{opts: opts}
+}
+
+// requestError is an error response.
+type requestError struct {
+	status int
+	msg    string
+	pos    *Pos
+}
+
+// Error returns the message of the error.
+func (e *requestError) Error() string {
+	return e.msg
+}
+
+// badRequest returns a 400 error with the formatted message.
+func badRequest(format string, args ...interface{}) *requestError {
+	return &requestError{status: http.StatusBadRequest, msg: fmt.Sprintf(format, args...)}
+}
+
+// jsonPos is the JSON form of a position.
+type jsonPos struct {
+	Line int `json:"line"`
+	Char int `json:"char"`
+}
+
+// jsonError is the JSON form of an error response or an invalid expression.
+type jsonError struct {
+	Message string   `json:"message"`
+	Pos     *jsonPos `json:"pos,omitempty"`
+}
+
+// jsonDiagnostic is the JSON form of a lint diagnostic.
+type jsonDiagnostic struct {
+	Severity string  `json:"severity"`
+	Message  string  `json:"message"`
+	Pos      jsonPos `json:"pos"`
+}
+
+// exprRequest holds the fields shared by the requests.
+type exprRequest struct {
+	Expr string `json:"expr"`
+	Zone string `json:"zone"`
+}
+
+// parse parses the expression and loads the zone of the request.
+func (r *exprRequest) parse() (Expr, *time.Location, error) {
+	loc := time.UTC
+	if r.Zone != "" {
+		var err error
+		if loc, err = time.LoadLocation(r.Zone); err != nil {
+			return nil, nil, badRequest("invalid zone %q", r.Zone)
+		}
+	}
+
+	e, err := ParseExprString(r.Expr)
+	if err != nil {
+		return nil, nil, exprError(err)
+	}
+	return e, loc, nil
+}
+
+// exprError returns a 400 error for an invalid expression, at the position of
+// a parse error.
+func exprError(err error) *requestError {
+	rerr := &requestError{status: http.StatusBadRequest, msg: err.Error()}
+	if perr, ok := err.(*ParseError); ok {
+		rerr.pos = &perr.Pos
+	}
+	return rerr
+}
+
+// ServeHTTP routes the request to its endpoint.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var serve func(*http.Request) (interface{}, error)
+	switch r.URL.Path {
+	case "/validate":
+		serve = h.validate
+	case "/format":
+		serve = h.format
+	case "/evaluate":
+		serve = h.evaluate
+	case "/next":
+		serve = h.next
+	case "/contains":
+		serve = h.contains
+	case "/elapsed":
+		serve = h.elapsed
+	default:
+		writeError(w, &requestError{status: http.StatusNotFound, msg: fmt.Sprintf("no endpoint %s", r.URL.Path)})
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, &requestError{status: http.StatusMethodNotAllowed, msg: "method not allowed, expected POST"})
+		return
+	}
+
+	// read a byte past the limit to tell an oversized body from a full one
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, h.opts.MaxBodyBytes+1))
+	if err != nil {
+		writeError(w, badRequest("invalid request: %s", err))
+		return
+	} else if int64(len(body)) > h.opts.MaxBodyBytes {
+		writeError(w, &requestError{status: http.StatusRequestEntityTooLarge, msg: fmt.Sprintf("request body exceeds %d bytes", h.opts.MaxBodyBytes)})
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	v, err := serve(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+// decode decodes the JSON body of the request into the value.
+func decode(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return badRequest("invalid request: %s", err)
+	}
+	return nil
+}
+
+// writeJSON writes the value as the JSON body of the response.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes the error as the JSON body of the response.
+func writeError(w http.ResponseWriter, err error) {
+	rerr, ok := err.(*requestError)
+	if !ok {
+		rerr = &requestError{status: http.StatusInternalServerError, msg: err.Error()}
+	}
+	writeJSON(w, rerr.status, struct {
+		Error jsonError `json:"error"`
+	}{newJSONError(rerr.msg, rerr.pos)})
+}
+
+// newJSONError returns the JSON form of an error at the optional position.
+func newJSONError(msg string, pos *Pos) jsonError {
+	e := jsonError{Message: msg}
+	if pos != nil {
+		e.Pos = &jsonPos{pos.Line, pos.Char}
+	}
+	return e
+}
+
+// validate parses and lints an expression.  An invalid expression is not an
+// error of the request.
+func (h *handler) validate(r *http.Request) (interface{}, error) {
+	var req struct {
+		Expr string `json:"expr"`
+	}
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Valid       bool             `json:"valid"`
+		Expr        string           `json:"expr,omitempty"`
+		Description string           `json:"description,omitempty"`
+		Diagnostics []jsonDiagnostic `json:"diagnostics"`
+		Error       *jsonError       `json:"error,omitempty"`
+	}
+	resp.Diagnostics = []jsonDiagnostic{}
+
+	e, err := ParseExprString(req.Expr)
+	if err != nil {
+		rerr := exprError(err)
+		jerr := newJSONError(rerr.msg, rerr.pos)
+		resp.Error = &jerr
+		return resp, nil
+	}
+
+	resp.Valid = true
+	resp.Expr, resp.Description = e.String(), Describe(e)
+	for _, d := range Lint(e) {
+		resp.Diagnostics = append(resp.Diagnostics, jsonDiagnostic{d.Severity.String(), d.Message, jsonPos{d.Pos.Line, d.Pos.Char}})
+		if d.Severity == SeverityError {
+			resp.Valid = false
+		}
+	}
+	return resp, nil
+}
+
+// format returns the canonical form of an expression.
+func (h *handler) format(r *http.Request) (interface{}, error) {
+	var req struct {
+		Expr string `json:"expr"`
+	}
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+
+	e, err := ParseExprString(req.Expr)
+	if err != nil {
+		return nil, exprError(err)
+	}
+	return struct {
+		Expr string `json:"expr"`
+	}{e.String()}, nil
+}
+
+// evaluate returns the ranges of an expression within a window, up to the
+// limit.
+func (h *handler) evaluate(r *http.Request) (interface{}, error) {
+	var req struct {
+		exprRequest
+		Window *TimeRange `json:"window"`
+		Limit  int        `json:"limit"`
+	}
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+
+	e, loc, err := req.parse()
+	if err != nil {
+		return nil, err
+	}
+	if err := h.checkWindow(req.Window); err != nil {
+		return nil, err
+	}
+
+	limit := req.Limit
+	if limit < 0 || limit > h.opts.MaxResults {
+		return nil, badRequest("limit must be between 1 and %d", h.opts.MaxResults)
+	} else if limit == 0 {
+		limit = h.opts.MaxResults
+	}
+
+	resp := struct {
+		Ranges    []*TimeRange `json:"ranges"`
+		Truncated bool         `json:"truncated"`
+	}{Ranges: within(e.Filter(), *req.Window, loc)}
+	if len(resp.Ranges) > limit {
+		resp.Ranges, resp.Truncated = resp.Ranges[:limit], true
+	}
+	return resp, nil
+}
+
+// next returns the next occurrences of an expression after a time, including
+// an occurrence in progress.
+func (h *handler) next(r *http.Request) (interface{}, error) {
+	var req struct {
+		exprRequest
+		After *time.Time `json:"after"`
+		Count int        `json:"count"`
+	}
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+
+	e, loc, err := req.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	count := req.Count
+	if count < 0 || count > h.opts.MaxResults {
+		return nil, badRequest("count must be between 1 and %d", h.opts.MaxResults)
+	} else if count == 0 {
+		count = 1
+	}
+
+	after := h.opts.Clock.Now()
+	if req.After != nil {
+		after = *req.After
+	}
+
+	// an occurrence in progress is found from up to a week earlier
+	ranges := []*TimeRange{}
+	for _, tr := range within(e.Filter(), TimeRange{after.AddDate(0, 0, -7), after.Add(h.opts.MaxWindow)}, loc) {
+		if len(ranges) == count {
+			break
+		} else if tr.End.After(after) {
+			ranges = append(ranges, tr)
+		}
+	}
+	return struct {
+		Ranges []*TimeRange `json:"ranges"`
+	}{ranges}, nil
+}
+
+// contains returns whether an expression matches a time, and the whole range
+// containing it.  The range is searched in a window around the time, which is
+// doubled until the range ends within it, up to the maximum window on either
+// side; longer ranges are clipped to it.
+func (h *handler) contains(r *http.Request) (interface{}, error) {
+	var req struct {
+		exprRequest
+		Time *time.Time `json:"time"`
+	}
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+
+	e, loc, err := req.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	t := h.opts.Clock.Now()
+	if req.Time != nil {
+		t = *req.Time
+	}
+
+	resp := struct {
+		Contains bool       `json:"contains"`
+		Range    *TimeRange `json:"range"`
+	}{}
+	for d := 7 * 24 * time.Hour; ; d *= 2 {
+		window := TimeRange{t.Add(-d), t.Add(d)}
+		resp.Contains, resp.Range = false, nil
+		for _, tr := range within(e.Filter(), window, loc) {
+			if !tr.Start.After(t) && tr.End.After(t) {
+				resp.Contains, resp.Range = true, tr
+				break
+			}
+		}
+
+		// a range touching the window may continue beyond it
+		if tr := resp.Range; tr == nil || d >= h.opts.MaxWindow ||
+			tr.Start.After(window.Start) && tr.End.Before(window.End) {
+			break
+		}
+	}
+	return resp, nil
+}
+
+// elapsed returns how much of a window an expression matches, such as the
+// business time between two times.
+func (h *handler) elapsed(r *http.Request) (interface{}, error) {
+	var req struct {
+		exprRequest
+		Window *TimeRange `json:"window"`
+	}
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+
+	e, loc, err := req.parse()
+	if err != nil {
+		return nil, err
+	}
+	if err := h.checkWindow(req.Window); err != nil {
+		return nil, err
+	}
+
+	var d time.Duration
+	for _, tr := range within(e.Filter(), *req.Window, loc) {
+		d += tr.Duration()
+	}
+	return struct {
+		Elapsed string  `json:"elapsed"`
+		Seconds float64 `json:"seconds"`
+	}{d.String(), d.Seconds()}, nil
+}
+
+// checkWindow returns an error if the window is missing, empty or longer than
+// the maximum.
+func (h *handler) checkWindow(window *TimeRange) error {
+	switch {
+	case window == nil:
+		return badRequest("missing window")
+	case !window.Start.Before(window.End):
+		return badRequest("the window must end after it starts")
+	case window.Duration() > h.opts.MaxWindow:
+		return badRequest("the window exceeds %s", h.opts.MaxWindow)
+	}
+	return nil
+}
+
+// within returns the ranges of the filter within the window, dividing days in
+// the location.  The ranges are never nil, so they encode as a JSON array.
+func within(f Filter, window TimeRange, loc *time.Location) []*TimeRange {
+	ranges := f.InLocation(loc).Within(TimeRange{window.Start.In(loc), window.End.In(loc)})
+	if ranges == nil {
+		ranges = []*TimeRange{}
+	}
+	return ranges
+}
@@ -0,0 +1,323 @@
+package timewarp_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/takeinitiative/timewarp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Handler", func() {
+	var (
+		opts   timewarp.HandlerOptions
+		method string
+		path   string
+		body   string
+		rec    *httptest.ResponseRecorder
+		resp   map[string]interface{}
+	)
+
+	ranges := func(s ...string) []interface{} {
+		var v []interface{}
+		for i := 0; i < len(s); i += 2 {
+			v = append(v, map[string]interface{}{"start": s[i], "end": s[i+1]})
+		}
+		return v
+	}
+
+	BeforeEach(func() {
+		opts = timewarp.HandlerOptions{Clock: timewarp.NewFakeClock(time.Date(2018, 3, 7, 12, 0, 0, 0, time.UTC))}
+		method = http.MethodPost
+	})
+
+	JustBeforeEach(func() {
+		rec = httptest.NewRecorder()
+		timewarp.NewHandler(opts).ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+
+		Expect(rec.Header().Get("Content-Type")).To(Equal("application/json"))
+		resp = nil
+		Expect(json.Unmarshal(rec.Body.Bytes(), &resp)).To(Succeed())
+	})
+
+	Context("validate", func() {
+		BeforeEach(func() {
+			path, body = "/validate", `{"expr": "not not day monday"}`
+		})
+
+		It("should return the canonical form and the diagnostics", func() {
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(resp["valid"]).To(BeTrue())
+			Expect(resp["expr"]).To(Equal("NOT NOT DAY MONDAY"))
+			Expect(resp["description"]).NotTo(BeEmpty())
+			Expect(resp["diagnostics"]).To(ConsistOf(map[string]interface{}{
+				"severity": "warning",
+				"message":  "double negation has no effect",
+				"pos":      map[string]interface{}{"line": 0.0, "char": 0.0},
+			}))
+		})
+
+		Context("an expression with lint errors", func() {
+			BeforeEach(func() {
+				body = `{"expr": "DAY 32"}`
+			})
+
+			It("should not be valid", func() {
+				Expect(rec.Code).To(Equal(http.StatusOK))
+				Expect(resp["valid"]).To(BeFalse())
+				Expect(resp["diagnostics"]).To(ConsistOf(HaveKeyWithValue("message", "DAY 32 is invalid, day 32 is outside 1 to 31")))
+			})
+		})
+
+		Context("an invalid expression", func() {
+			BeforeEach(func() {
+				body = `{"expr": "DAY AND"}`
+			})
+
+			It("should return the parse error", func() {
+				Expect(rec.Code).To(Equal(http.StatusOK))
+				Expect(resp["valid"]).To(BeFalse())
+				Expect(resp["diagnostics"]).To(BeEmpty())
+				Expect(resp["error"]).To(HaveKeyWithValue("pos", map[string]interface{}{"line": 0.0, "char": 7.0}))
+			})
+		})
+	})
+
+	Context("format", func() {
+		BeforeEach(func() {
+			path, body = "/format", `{"expr": "day tuesday in time 9:00 10:00"}`
+		})
+
+		It("should return the canonical form", func() {
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(resp).To(Equal(map[string]interface{}{"expr": "DAY TUESDAY IN TIME 0900 1000"}))
+		})
+
+		Context("an invalid expression", func() {
+			BeforeEach(func() {
+				body = `{"expr": "DAY AND"}`
+			})
+
+			It("should fail with the position", func() {
+				Expect(rec.Code).To(Equal(http.StatusBadRequest))
+				Expect(resp["error"]).To(HaveKeyWithValue("pos", map[string]interface{}{"line": 0.0, "char": 7.0}))
+			})
+		})
+	})
+
+	Context("evaluate", func() {
+		BeforeEach(func() {
+			path, body = "/evaluate", `{"expr": "DAY TUESDAY", "window": "2018-03-01T00:00:00Z/2018-04-01T00:00:00Z", "limit": 2}`
+		})
+
+		It("should return the ranges up to the limit", func() {
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(resp["ranges"]).To(Equal(ranges(
+				"2018-03-06T00:00:00Z", "2018-03-07T00:00:00Z",
+				"2018-03-13T00:00:00Z", "2018-03-14T00:00:00Z",
+			)))
+			Expect(resp["truncated"]).To(BeTrue())
+		})
+
+		Context("in a zone", func() {
+			BeforeEach(func() {
+				body = `{"expr": "DAY MONDAY", "window": {"start": "2018-03-05T00:00:00-05:00", "end": "2018-03-12T00:00:00-04:00"}, "zone": "America/New_York"}`
+			})
+
+			It("should count days in the zone", func() {
+				Expect(resp["ranges"]).To(Equal(ranges("2018-03-05T00:00:00-05:00", "2018-03-06T00:00:00-05:00")))
+				Expect(resp["truncated"]).To(BeFalse())
+			})
+		})
+
+		Context("a long window", func() {
+			BeforeEach(func() {
+				opts.MaxWindow = 7 * 24 * time.Hour
+			})
+
+			It("should fail", func() {
+				Expect(rec.Code).To(Equal(http.StatusBadRequest))
+				Expect(resp["error"]).To(HaveKeyWithValue("message", "the window exceeds 168h0m0s"))
+			})
+		})
+
+		Context("a missing window", func() {
+			BeforeEach(func() {
+				body = `{"expr": "DAY TUESDAY"}`
+			})
+
+			It("should fail", func() {
+				Expect(rec.Code).To(Equal(http.StatusBadRequest))
+				Expect(resp["error"]).To(HaveKeyWithValue("message", "missing window"))
+			})
+		})
+
+		Context("a large limit", func() {
+			BeforeEach(func() {
+				opts.MaxResults = 1
+			})
+
+			It("should fail", func() {
+				Expect(rec.Code).To(Equal(http.StatusBadRequest))
+				Expect(resp["error"]).To(HaveKeyWithValue("message", "limit must be between 1 and 1"))
+			})
+		})
+	})
+
+	Context("next", func() {
+		BeforeEach(func() {
+			path, body = "/next", `{"expr": "DAY MONDAY FRIDAY IN TIME 0900 1700", "count": 2}`
+		})
+
+		It("should return the occurrences after now, including the one in progress", func() {
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(resp["ranges"]).To(Equal(ranges(
+				"2018-03-07T09:00:00Z", "2018-03-07T17:00:00Z",
+				"2018-03-08T09:00:00Z", "2018-03-08T17:00:00Z",
+			)))
+		})
+
+		Context("after a time", func() {
+			BeforeEach(func() {
+				body = `{"expr": "DAY MONDAY FRIDAY IN TIME 0900 1700", "after": "2018-03-09T17:00:00Z"}`
+			})
+
+			It("should return the next occurrence", func() {
+				Expect(resp["ranges"]).To(Equal(ranges("2018-03-12T09:00:00Z", "2018-03-12T17:00:00Z")))
+			})
+		})
+
+		Context("with no occurrences", func() {
+			BeforeEach(func() {
+				body = `{"expr": "YEAR 2017"}`
+			})
+
+			It("should return no ranges", func() {
+				Expect(rec.Code).To(Equal(http.StatusOK))
+				Expect(resp["ranges"]).To(BeEmpty())
+			})
+		})
+	})
+
+	Context("contains", func() {
+		BeforeEach(func() {
+			path, body = "/contains", `{"expr": "DAY MONDAY FRIDAY IN TIME 0900 1700", "time": "2018-03-07T10:00:00Z"}`
+		})
+
+		It("should return the range containing the time", func() {
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(resp["contains"]).To(BeTrue())
+			Expect(resp["range"]).To(Equal(ranges("2018-03-07T09:00:00Z", "2018-03-07T17:00:00Z")[0]))
+		})
+
+		Context("at the end of a range", func() {
+			BeforeEach(func() {
+				body = `{"expr": "DAY MONDAY FRIDAY IN TIME 0900 1700", "time": "2018-03-07T17:00:00Z"}`
+			})
+
+			It("should not contain the time", func() {
+				Expect(resp["contains"]).To(BeFalse())
+				Expect(resp).To(HaveKeyWithValue("range", BeNil()))
+			})
+		})
+
+		Context("within a long range", func() {
+			BeforeEach(func() {
+				body = `{"expr": "MONTH JULY", "time": "2018-07-20T12:00:00Z"}`
+			})
+
+			It("should return the whole range", func() {
+				Expect(resp["contains"]).To(BeTrue())
+				Expect(resp["range"]).To(Equal(ranges("2018-07-01T00:00:00Z", "2018-08-01T00:00:00Z")[0]))
+			})
+		})
+
+		Context("within a range longer than the maximum window", func() {
+			BeforeEach(func() {
+				opts.MaxWindow = 14 * 24 * time.Hour
+				body = `{"expr": "YEAR 2018", "time": "2018-07-20T12:00:00Z"}`
+			})
+
+			It("should clip the range to the maximum window", func() {
+				Expect(resp["contains"]).To(BeTrue())
+				Expect(resp["range"]).To(Equal(ranges("2018-07-06T12:00:00Z", "2018-08-03T12:00:00Z")[0]))
+			})
+		})
+	})
+
+	Context("elapsed", func() {
+		BeforeEach(func() {
+			path, body = "/elapsed", `{"expr": "DAY MONDAY FRIDAY IN TIME 0900 1700", "window": "2018-03-09T15:00:00Z/2018-03-12T10:00:00Z"}`
+		})
+
+		It("should return the business time within the window", func() {
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(resp).To(Equal(map[string]interface{}{"elapsed": "3h0m0s", "seconds": 10800.0}))
+		})
+	})
+
+	Context("requests", func() {
+		BeforeEach(func() {
+			path, body = "/format", `{"expr": "DAY MONDAY"}`
+		})
+
+		Context("of another method", func() {
+			BeforeEach(func() {
+				method = http.MethodGet
+			})
+
+			It("should not be allowed", func() {
+				Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+				Expect(rec.Header().Get("Allow")).To(Equal(http.MethodPost))
+			})
+		})
+
+		Context("of an unknown path", func() {
+			BeforeEach(func() {
+				path = "/parse"
+			})
+
+			It("should not be found", func() {
+				Expect(rec.Code).To(Equal(http.StatusNotFound))
+				Expect(resp["error"]).To(HaveKeyWithValue("message", "no endpoint /parse"))
+			})
+		})
+
+		Context("with unknown fields", func() {
+			BeforeEach(func() {
+				body = `{"expr": "DAY MONDAY", "zone": "UTC"}`
+			})
+
+			It("should fail", func() {
+				Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("with an invalid zone", func() {
+			BeforeEach(func() {
+				path, body = "/contains", `{"expr": "DAY MONDAY", "zone": "Mars/Olympus_Mons"}`
+			})
+
+			It("should fail", func() {
+				Expect(rec.Code).To(Equal(http.StatusBadRequest))
+				Expect(resp["error"]).To(HaveKeyWithValue("message", `invalid zone "Mars/Olympus_Mons"`))
+			})
+		})
+
+		Context("with a large body", func() {
+			BeforeEach(func() {
+				opts.MaxBodyBytes = 16
+			})
+
+			It("should be too large", func() {
+				Expect(rec.Code).To(Equal(http.StatusRequestEntityTooLarge))
+				Expect(resp["error"]).To(HaveKeyWithValue("message", "request body exceeds 16 bytes"))
+			})
+		})
+	})
+})
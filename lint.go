@@ -0,0 +1,369 @@
+package timewarp
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Severity describes how serious a lint diagnostic is.
+type Severity int
+
+const (
+	// SeverityInfo and the following are lint severities in increasing order
+	// of importance.
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+var severities = [...]string{
+	SeverityInfo:    "info",
+	SeverityWarning: "warning",
+	SeverityError:   "error",
+}
+
+// String returns the string representation of the severity
+func (s Severity) String() string {
+	if s >= 0 && s < Severity(len(severities)) {
+		return severities[s]
+	}
+	return ""
+}
+
+// Diagnostic describes a problem found in an expression.
+type Diagnostic struct {
+	Pos      Pos
+	Severity Severity
+	Message  string
+}
+
+// String returns the string representation of the diagnostic.
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s at %s", d.Severity, d.Message, d.Pos)
+}
+
+// Lint inspects a parsed expression for invalid day numbers and for
+// subexpressions that never match anything or that have no effect.
+// Diagnostics are ordered by position.
+func Lint(e Expr) []Diagnostic {
+	var l linter
+	l.walk(e, 0)
+
+	sort.SliceStable(l.diags, func(i, j int) bool {
+		return l.diags[i].Pos.before(l.diags[j].Pos)
+	})
+	return l.diags
+}
+
+// linter collects diagnostics while walking an expression.
+type linter struct {
+	diags []Diagnostic
+}
+
+// report adds a diagnostic.
+func (l *linter) report(pos Pos, sev Severity, format string, args ...interface{}) {
+	l.diags = append(l.diags, Diagnostic{Pos: pos, Severity: sev, Message: fmt.Sprintf(format, args...)})
+}
+
+// walk lints the expression and returns true if it is known to always be
+// empty.  Emptiness is only reported once, at the innermost node that causes
+// it.  The year is non-zero when the expression is restricted to a year.
+func (l *linter) walk(e Expr, year int) (empty bool) {
+	switch e := e.(type) {
+	case *BinaryExpr:
+		if e.Op == AND {
+			return l.walkUnion(e, year)
+		}
+		return l.walkIntersect(e, year)
+	case *OrdinalExpr:
+		return l.walkOrdinal(e, year)
+	case *NamedExpr:
+		return l.walk(e.X, year)
+	case *NotExpr:
+		if _, ok := e.X.(*NotExpr); ok {
+			l.report(e.NotPos, SeverityWarning, "double negation has no effect")
+		}
+		l.walk(e.X, year)
+		return false
+	case *DayExpr:
+		for _, d := range e.Args {
+			if d < 1 || d > 31 {
+				l.report(e.DayPos, SeverityError, "%s is invalid, day %d is outside 1 to 31", e, d)
+				return e.Args[0] > 31 || dayExprEmpty(e)
+			}
+		}
+		if dayExprEmpty(e) {
+			l.report(e.DayPos, SeverityError, "%s never matches, the last day precedes the first", e)
+			return true
+		}
+	case *TimeExpr:
+		if e.From == e.To {
+			l.report(e.TimePos, SeverityError, "%s never matches, the times are equal", e)
+			return true
+		}
+	}
+	return false
+}
+
+// walkUnion lints each arm of a chain of unions and reports duplicate arms.
+func (l *linter) walkUnion(e *BinaryExpr, year int) bool {
+	var (
+		arms  = unionArms(e, nil)
+		seen  = make(map[string]bool)
+		empty = true
+	)
+
+	for _, arm := range arms {
+		s := arm.String()
+		if seen[s] {
+			l.report(arm.Pos(), SeverityWarning, "duplicate union arm %s", s)
+		}
+		seen[s] = true
+
+		if !l.walk(arm, year) {
+			empty = false
+		}
+	}
+	return empty
+}
+
+// walkIntersect lints both sides of an intersection and reports
+// intersections that are always empty or have no effect.
+func (l *linter) walkIntersect(e *BinaryExpr, year int) bool {
+	if y, ok := e.Y.(*YearExpr); ok && year == 0 {
+		year = y.Year
+	} else if x, ok := e.X.(*YearExpr); ok && year == 0 {
+		year = x.Year
+	}
+
+	emptyX := l.walk(e.X, year)
+	emptyY := l.walk(e.Y, year)
+	if emptyX || emptyY {
+		return true
+	}
+
+	switch disjoint, noop := compareIntersect(e.X, e.Y); {
+	case disjoint:
+		l.report(e.OpPos, SeverityError, "%s never matches, the operands have nothing in common", e)
+		return true
+	case noop:
+		l.report(e.OpPos, SeverityWarning, "intersection with %s has no effect", e.Y)
+	}
+	return false
+}
+
+// walkOrdinal lints an ordinal and reports ordinals that exceed the number of
+// times the operand can occur within the unit.
+func (l *linter) walkOrdinal(e *OrdinalExpr, year int) bool {
+	if l.walk(e.X, year) {
+		return true
+	}
+	if unitEmpty(e.Unit, e.N) {
+		l.report(e.Unit.Pos(), SeverityError, "%s is always empty", e.Unit)
+		return true
+	}
+
+	n := e.N
+	if n < 0 {
+		n = -n
+	}
+
+	size := unitDays(e.Unit, e.N, year)
+	if size == 0 {
+		return false
+	}
+
+	max := maxOccurrences(e.X, size)
+	if max < 0 {
+		return false
+	} else if n <= max {
+		// February in an unknown year may only fit the ordinal in leap years
+		if m, ok := e.Unit.(*MonthExpr); ok && m.Month == time.February && year == 0 && n > maxOccurrences(e.X, size-1) {
+			l.report(e.OfPos, SeverityInfo, "%s only matches in leap years", e)
+		}
+		return false
+	}
+
+	if max == 0 {
+		l.report(e.OfPos, SeverityError, "%s never matches, %s does not fit within %s", e, e.X, e.Unit)
+	} else {
+		l.report(e.OfPos, SeverityError, "%s never matches, %s occurs at most %d times within %s", e, e.X, max, e.Unit)
+	}
+	return true
+}
+
+// unionArms flattens a chain of unions into its arms.
+func unionArms(e Expr, arms []Expr) []Expr {
+	if b, ok := e.(*BinaryExpr); ok && b.Op == AND {
+		arms = unionArms(b.X, arms)
+		return unionArms(b.Y, arms)
+	}
+	return append(arms, e)
+}
+
+// dayExprEmpty returns true if the day expression can never match.
+func dayExprEmpty(e *DayExpr) bool {
+	switch len(e.Args) {
+	case 1:
+		return e.Args[0] <= 0
+	case 2:
+		first := e.Args[0]
+		if first < 1 {
+			first = 1
+		}
+		return e.Args[1] < first
+	}
+	return false
+}
+
+// unitEmpty returns true if the ordinal unit can never match.
+func unitEmpty(unit Expr, n int) bool {
+	if d, ok := unit.(*DayExpr); ok {
+		switch len(d.Args) {
+		case 0:
+			return 2*n-1 <= 0
+		case 2:
+			return d.Args[1] <= 0
+		}
+	}
+	return false
+}
+
+// unitDays returns the largest number of days spanned by an ordinal unit or
+// zero if unbounded.
+func unitDays(unit Expr, n, year int) int {
+	switch u := unit.(type) {
+	case *MonthExpr:
+		switch {
+		case u.Month == 0:
+			return 31
+		case u.Month == time.February && year == 0:
+			return 29
+		case year == 0:
+			return daysIn(u.Month, 2001)
+		default:
+			return daysIn(u.Month, year)
+		}
+	case *WeekExpr:
+		if n > 0 {
+			return (2*n - 1) * 7
+		}
+		return 7
+	case *DayExpr:
+		switch len(u.Args) {
+		case 0:
+			return 2*n - 1
+		case 1:
+			return 1
+		default:
+			return u.Args[1]
+		}
+	}
+	return 0
+}
+
+// maxOccurrences returns the largest number of results the expression can
+// produce within the given number of days or -1 if unknown.  Weekday ranges
+// already in progress at the start of the days are counted.
+func maxOccurrences(e Expr, days int) int {
+	switch e := e.(type) {
+	case *WeekdayExpr:
+		return (days + e.Span() + 5) / 7
+	case *WeekExpr:
+		return (days + 12) / 7
+	case *DayExpr:
+		switch len(e.Args) {
+		case 0:
+			return days
+		case 1:
+			if e.Args[0] < 1 {
+				return -1
+			}
+			return days / e.Args[0]
+		default:
+			first, last := e.Args[0], e.Args[1]
+			if first < 1 || last < first {
+				return -1
+			}
+			if days < first {
+				return 0
+			}
+			return (days-first)/last + 1
+		}
+	}
+	return -1
+}
+
+// compareIntersect reports whether the intersection of two expressions is
+// always empty or equal to the left operand.
+func compareIntersect(x, y Expr) (disjoint, noop bool) {
+	if xs, ok := weekdaySet(x); ok {
+		if ys, ok := weekdaySet(y); ok && xs&ys == 0 {
+			return true, false
+		}
+	}
+
+	switch x := x.(type) {
+	case *YearExpr:
+		if y, ok := y.(*YearExpr); ok {
+			return x.Year != y.Year, x.Year == y.Year
+		}
+	case *MonthExpr:
+		if y, ok := y.(*MonthExpr); ok && x.Month > 0 && y.Month > 0 {
+			return x.Month != y.Month, x.Month == y.Month
+		}
+	case *WeekdayExpr:
+		if y, ok := y.(*WeekdayExpr); ok {
+			return false, x.From == y.From && x.Span() <= y.Span()
+		}
+	case *TimeExpr:
+		if y, ok := y.(*TimeExpr); ok {
+			x1, x2, xok := timeSpan(x)
+			y1, y2, yok := timeSpan(y)
+			if xok && yok {
+				return x2 <= y1 || y2 <= x1, y1 <= x1 && x2 <= y2
+			}
+		}
+	}
+	return false, false
+}
+
+// weekdaySet returns a bit set of the weekdays matched by a weekday
+// expression or a union of weekday expressions.
+func weekdaySet(e Expr) (set uint8, ok bool) {
+	switch e := e.(type) {
+	case *WeekdayExpr:
+		for i := 0; i < e.Span(); i++ {
+			set |= 1 << uint((int(e.From)+i)%7)
+		}
+		return set, true
+	case *BinaryExpr:
+		if e.Op != AND {
+			return 0, false
+		}
+		xs, xok := weekdaySet(e.X)
+		ys, yok := weekdaySet(e.Y)
+		return xs | ys, xok && yok
+	}
+	return 0, false
+}
+
+// timeSpan returns the minutes of the day covered by a time expression.  It
+// is not ok if the expression wraps past midnight.
+func timeSpan(e *TimeExpr) (from, to int, ok bool) {
+	f, err1 := time.Parse(timefmt, e.From)
+	t, err2 := time.Parse(timefmt, e.To)
+	if err1 != nil || err2 != nil {
+		return 0, 0, false
+	}
+
+	from = f.Hour()*60 + f.Minute()
+	to = t.Hour()*60 + t.Minute()
+	return from, to, from < to
+}
+
+// daysIn returns the number of days in the month of the given year.
+func daysIn(m time.Month, year int) int {
+	return time.Date(year, m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
@@ -0,0 +1,105 @@
+package timewarp_test
+
+import (
+	"github.com/takeinitiative/timewarp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lint", func() {
+
+	// lint returns the severity and position of each diagnostic
+	lint := func(in string) []timewarp.Diagnostic {
+		e, err := timewarp.ParseExprString(in)
+		Expect(err).NotTo(HaveOccurred())
+
+		var result []timewarp.Diagnostic
+		for _, d := range timewarp.Lint(e) {
+			Expect(d.Message).NotTo(BeEmpty())
+			result = append(result, timewarp.Diagnostic{Pos: d.Pos, Severity: d.Severity})
+		}
+		return result
+	}
+
+	DescribeTable("Diagnostics",
+		func(in string, expected ...timewarp.Diagnostic) {
+			if len(expected) == 0 {
+				Expect(lint(in)).To(BeEmpty())
+			} else {
+				Expect(lint(in)).To(Equal(expected))
+			}
+		},
+		Entry("clean expression", `DAY MONDAY FRIDAY IN TIME 0900 1700 AND DAY SATURDAY`),
+		Entry("README example", `DAY TUESDAY OF 2 MONTH MARCH IN TIME 1200 1400`),
+		Entry("day exceeds month", `DAY 31 OF MONTH FEBRUARY`,
+			timewarp.Diagnostic{Pos: timewarp.Pos{0, 7}, Severity: timewarp.SeverityError}),
+		Entry("day exceeds month in a leap year", `DAY 30 OF MONTH FEBRUARY IN YEAR 2020`,
+			timewarp.Diagnostic{Pos: timewarp.Pos{0, 7}, Severity: timewarp.SeverityError}),
+		Entry("leap day in a common year", `DAY 29 OF MONTH FEBRUARY IN YEAR 2021`,
+			timewarp.Diagnostic{Pos: timewarp.Pos{0, 7}, Severity: timewarp.SeverityError}),
+		Entry("leap day in an unknown year", `DAY 29 OF MONTH FEBRUARY`,
+			timewarp.Diagnostic{Pos: timewarp.Pos{0, 7}, Severity: timewarp.SeverityInfo}),
+		Entry("day of a shorter month", `DAY 31 OF MONTH APRIL`,
+			timewarp.Diagnostic{Pos: timewarp.Pos{0, 7}, Severity: timewarp.SeverityError}),
+		Entry("sixth week of the month", `WEEK OF 6 MONTH`),
+		Entry("seventh week of the month", `WEEK OF 7 MONTH`,
+			timewarp.Diagnostic{Pos: timewarp.Pos{0, 5}, Severity: timewarp.SeverityError}),
+		Entry("sixth week of February", `WEEK OF 6 MONTH FEBRUARY`,
+			timewarp.Diagnostic{Pos: timewarp.Pos{0, 5}, Severity: timewarp.SeverityError}),
+		Entry("sixth tuesday of the month", `DAY TUESDAY OF 6 MONTH`,
+			timewarp.Diagnostic{Pos: timewarp.Pos{0, 12}, Severity: timewarp.SeverityError}),
+		Entry("fifth tuesday of the month", `DAY TUESDAY OF 5 MONTH`),
+		Entry("empty time", `TIME 0900 0900`,
+			timewarp.Diagnostic{Pos: timewarp.Pos{0, 0}, Severity: timewarp.SeverityError}),
+		Entry("reversed days", `DAY 5 3`,
+			timewarp.Diagnostic{Pos: timewarp.Pos{0, 0}, Severity: timewarp.SeverityError}),
+		Entry("day past the end of any month", `DAY 32`,
+			timewarp.Diagnostic{Pos: timewarp.Pos{0, 0}, Severity: timewarp.SeverityError}),
+		Entry("day range past the end of any month", `DAY 15 40 IN TIME 0900 1700`,
+			timewarp.Diagnostic{Pos: timewarp.Pos{0, 0}, Severity: timewarp.SeverityError}),
+		Entry("day zero", `DAY 0 OF MONTH JUNE`,
+			timewarp.Diagnostic{Pos: timewarp.Pos{0, 0}, Severity: timewarp.SeverityError}),
+		Entry("last day of any month", `DAY 1 31`),
+		Entry("disjoint weekdays", `DAY MONDAY IN DAY TUESDAY`,
+			timewarp.Diagnostic{Pos: timewarp.Pos{0, 11}, Severity: timewarp.SeverityError}),
+		Entry("disjoint weekday union", `(DAY MONDAY AND DAY FRIDAY) IN DAY TUESDAY THURSDAY`,
+			timewarp.Diagnostic{Pos: timewarp.Pos{0, 28}, Severity: timewarp.SeverityError}),
+		Entry("overlapping weekdays", `DAY MONDAY FRIDAY IN DAY TUESDAY`),
+		Entry("disjoint years", `YEAR 2020 IN YEAR 2021`,
+			timewarp.Diagnostic{Pos: timewarp.Pos{0, 10}, Severity: timewarp.SeverityError}),
+		Entry("disjoint times", `TIME 0900 1000 IN TIME 1100 1200`,
+			timewarp.Diagnostic{Pos: timewarp.Pos{0, 15}, Severity: timewarp.SeverityError}),
+		Entry("no-op time", `TIME 0900 1000 IN TIME 0800 1200`,
+			timewarp.Diagnostic{Pos: timewarp.Pos{0, 15}, Severity: timewarp.SeverityWarning}),
+		Entry("no-op weekday", `DAY MONDAY IN DAY MONDAY WEDNESDAY`,
+			timewarp.Diagnostic{Pos: timewarp.Pos{0, 11}, Severity: timewarp.SeverityWarning}),
+		Entry("no-op month", `MONTH JUNE IN MONTH JUNE`,
+			timewarp.Diagnostic{Pos: timewarp.Pos{0, 11}, Severity: timewarp.SeverityWarning}),
+		Entry("duplicate arm", `DAY MONDAY AND DAY TUESDAY AND (DAY MONDAY)`,
+			timewarp.Diagnostic{Pos: timewarp.Pos{0, 32}, Severity: timewarp.SeverityWarning}),
+		Entry("nested duplicate arm", `DAY MONDAY AND (DAY TUESDAY AND DAY MONDAY)`,
+			timewarp.Diagnostic{Pos: timewarp.Pos{0, 32}, Severity: timewarp.SeverityWarning}),
+		Entry("double negation", `DAY MONDAY IN NOT NOT DAY TUESDAY`,
+			timewarp.Diagnostic{Pos: timewarp.Pos{0, 14}, Severity: timewarp.SeverityWarning}),
+		Entry("ordered by position", "NOT NOT TIME 0900 0900\nAND (DAY 31 OF MONTH JUNE)",
+			timewarp.Diagnostic{Pos: timewarp.Pos{0, 0}, Severity: timewarp.SeverityWarning},
+			timewarp.Diagnostic{Pos: timewarp.Pos{0, 8}, Severity: timewarp.SeverityError},
+			timewarp.Diagnostic{Pos: timewarp.Pos{1, 12}, Severity: timewarp.SeverityError}),
+	)
+
+	Describe("Diagnostic", func() {
+		It("should describe the problem and position", func() {
+			e, err := timewarp.ParseExprString(`TIME 0900 0900`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(timewarp.Lint(e)[0].String()).To(Equal("error: TIME 0900 0900 never matches, the times are equal at 1 col 1"))
+		})
+
+		It("should name the invalid day", func() {
+			e, err := timewarp.ParseExprString(`DAY MONDAY AND DAY 32`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(timewarp.Lint(e)[0].String()).To(Equal("error: DAY 32 is invalid, day 32 is outside 1 to 31 at 1 col 16"))
+		})
+	})
+})
@@ -0,0 +1,141 @@
+package timewarp
+
+import "strings"
+
+// Locale maps the words of a language to the tokens of timerangeQL, and
+// describes expressions in the language.
+type Locale struct {
+	// Name is the language tag of the locale, such as "fr".
+	Name string
+
+	// Keywords maps the lower case words of the language to tokens.  Several
+	// words may map to the same token, such as spellings with and without
+	// accents.
+	Keywords map[string]Token
+
+	// Describer describes expressions in the language.  Locales without a
+	// describer are described in English.
+	Describer Describer
+}
+
+// Describer describes expressions in a human language.
+type Describer interface {
+	Describe(e Expr) string
+}
+
+// DescriberFunc adapts a function to the Describer interface.
+type DescriberFunc func(e Expr) string
+
+// Describe calls the function.
+func (f DescriberFunc) Describe(e Expr) string {
+	return f(e)
+}
+
+// Describe describes the expression with the describer of the locale.
+func (l *Locale) Describe(e Expr) string {
+	if l.Describer == nil {
+		return describe(e)
+	}
+	return l.Describer.Describe(e)
+}
+
+// Lookup returns the token of a word of the locale, ignoring case.
+func (l *Locale) Lookup(word string) Token {
+	if tok, ok := l.Keywords[strings.ToLower(word)]; ok {
+		return tok
+	}
+	return IDENT
+}
+
+// canonical is the locale of the canonical keywords, recognized by scanners
+// without locales.
+var canonical = &Locale{Name: "en", Keywords: keywords}
+
+// English is the locale of the canonical keywords.  It holds its own copy of
+// the keywords, so changing them does not change the canonical language.
+var English = &Locale{
+	Name:      "en",
+	Keywords:  canonicalKeywords(),
+	Describer: DescriberFunc(describe),
+}
+
+// French is the French locale.
+var French = &Locale{
+	Name: "fr",
+	Keywords: map[string]Token{
+		"et": AND, "dans": IN, "de": OF, "sauf": NOT, "non": NOT,
+
+		"année": YEAR, "annee": YEAR, "an": YEAR, "mois": MONTH,
+		"semaine": WEEK, "jour": DAY, "heure": TIME, "période": RANGE,
+		"periode": RANGE,
+
+		"janvier": JANUARY, "février": FEBRUARY, "fevrier": FEBRUARY,
+		"mars": MARCH, "avril": APRIL, "mai": MAY, "juin": JUNE,
+		"juillet": JULY, "août": AUGUST, "aout": AUGUST,
+		"septembre": SEPTEMBER, "octobre": OCTOBER, "novembre": NOVEMBER,
+		"décembre": DECEMBER, "decembre": DECEMBER,
+
+		"lundi": MONDAY, "mardi": TUESDAY, "mercredi": WEDNESDAY,
+		"jeudi": THURSDAY, "vendredi": FRIDAY, "samedi": SATURDAY,
+		"dimanche": SUNDAY,
+	},
+}
+
+// German is the German locale.
+var German = &Locale{
+	Name: "de",
+	Keywords: map[string]Token{
+		"und": AND, "in": IN, "von": OF, "nicht": NOT,
+
+		"jahr": YEAR, "monat": MONTH, "woche": WEEK, "tag": DAY,
+		"zeit": TIME, "zeitraum": RANGE,
+
+		"januar": JANUARY, "februar": FEBRUARY, "märz": MARCH,
+		"maerz": MARCH, "april": APRIL, "mai": MAY, "juni": JUNE,
+		"juli": JULY, "august": AUGUST, "september": SEPTEMBER,
+		"oktober": OCTOBER, "november": NOVEMBER, "dezember": DECEMBER,
+
+		"montag": MONDAY, "dienstag": TUESDAY, "mittwoch": WEDNESDAY,
+		"donnerstag": THURSDAY, "freitag": FRIDAY, "samstag": SATURDAY,
+		"sonnabend": SATURDAY, "sonntag": SUNDAY,
+	},
+}
+
+// Spanish is the Spanish locale.
+var Spanish = &Locale{
+	Name: "es",
+	Keywords: map[string]Token{
+		"y": AND, "en": IN, "de": OF, "no": NOT,
+
+		"año": YEAR, "mes": MONTH, "semana": WEEK, "día": DAY, "dia": DAY,
+		"hora": TIME, "periodo": RANGE, "período": RANGE,
+
+		"enero": JANUARY, "febrero": FEBRUARY, "marzo": MARCH,
+		"abril": APRIL, "mayo": MAY, "junio": JUNE, "julio": JULY,
+		"agosto": AUGUST, "septiembre": SEPTEMBER, "setiembre": SEPTEMBER,
+		"octubre": OCTOBER, "noviembre": NOVEMBER, "diciembre": DECEMBER,
+
+		"lunes": MONDAY, "martes": TUESDAY, "miércoles": WEDNESDAY,
+		"miercoles": WEDNESDAY, "jueves": THURSDAY, "viernes": FRIDAY,
+		"sábado": SATURDAY, "sabado": SATURDAY, "domingo": SUNDAY,
+	},
+}
+
+// locales are the built in locales by name.
+var locales = map[string]*Locale{
+	English.Name: English,
+	French.Name:  French,
+	German.Name:  German,
+	Spanish.Name: Spanish,
+}
+
+// LookupLocale returns the built in locale of the language tag, such as "fr"
+// or "fr-CA".
+func LookupLocale(tag string) (*Locale, bool) {
+	tag = strings.ToLower(tag)
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	l, ok := locales[tag]
+	return l, ok
+}
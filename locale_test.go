@@ -0,0 +1,106 @@
+package timewarp_test
+
+import (
+	"bytes"
+
+	"github.com/takeinitiative/timewarp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Locale", func() {
+	parse := func(s string, locales ...*timewarp.Locale) (timewarp.Expr, error) {
+		return timewarp.NewLocalizedParser(bytes.NewBufferString(s), locales...).ParseExpr()
+	}
+
+	DescribeTable("Parse localized expressions",
+		func(l *timewarp.Locale, s, canonical string) {
+			e, err := parse(s, l)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(e.String()).To(Equal(canonical))
+		},
+		Entry("English", timewarp.English, "day tuesday of 2 month march in time 1200 1400", "DAY TUESDAY OF 2 MONTH MARCH IN TIME 1200 1400"),
+		Entry("French", timewarp.French, "JOUR MARDI DE 2 MOIS MARS DANS HEURE 1200 1400", "DAY TUESDAY OF 2 MONTH MARCH IN TIME 1200 1400"),
+		Entry("French accents", timewarp.French, "JOUR 15 DE MOIS AOÛT DANS ANNÉE 2008", "DAY 15 OF MONTH AUGUST IN YEAR 2008"),
+		Entry("French without accents", timewarp.French, "jour 1 de mois fevrier", "DAY 1 OF MONTH FEBRUARY"),
+		Entry("German", timewarp.German, "TAG DIENSTAG UND TAG FREITAG SONNTAG IN ZEIT 0900 1700", "DAY TUESDAY AND DAY FRIDAY SUNDAY IN TIME 0900 1700"),
+		Entry("German accents", timewarp.German, "Tag Montag von Monat März", "DAY MONDAY OF MONTH MARCH"),
+		Entry("Spanish", timewarp.Spanish, "DÍA SÁBADO DE 2 SEMANA SÁBADO", "DAY SATURDAY OF 2 WEEK SATURDAY"),
+		Entry("Spanish negation", timewarp.Spanish, "dia lunes viernes en no dia miércoles", "DAY MONDAY FRIDAY IN NOT DAY WEDNESDAY"),
+	)
+
+	It("should only recognize the words of the locales", func() {
+		_, err := parse("DAY TUESDAY", timewarp.French)
+		Expect(err).To(HaveOccurred())
+
+		_, err = parse("JOUR MARDI", timewarp.English)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should recognize the words of several locales", func() {
+		e, err := parse("JOUR MARDI AND DAY FRIDAY", timewarp.French, timewarp.English)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(e.String()).To(Equal("DAY TUESDAY AND DAY FRIDAY"))
+	})
+
+	It("should define accented names", func() {
+		p := timewarp.NewLocalizedParser(bytes.NewBufferString("férié"), timewarp.French)
+		Expect(p.Define("Férié", &timewarp.WeekdayExpr{From: 0, To: 0})).To(Succeed())
+		Expect(p.Define("août", &timewarp.WeekdayExpr{})).NotTo(Succeed())
+
+		e, err := p.ParseExpr()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(e.String()).To(Equal("DAY SUNDAY"))
+	})
+
+	It("should not share the English keywords with the canonical language", func() {
+		timewarp.English.Keywords["jour"] = timewarp.DAY
+		defer delete(timewarp.English.Keywords, "jour")
+
+		e, err := parse("jour monday", timewarp.English)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(e.String()).To(Equal("DAY MONDAY"))
+
+		_, err = timewarp.ParseExprString("jour monday")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should look up the locales by language tag", func() {
+		for tag, l := range map[string]*timewarp.Locale{"fr-CA": timewarp.French, "DE": timewarp.German, "es_MX": timewarp.Spanish, "en": timewarp.English} {
+			found, ok := timewarp.LookupLocale(tag)
+			Expect(ok).To(BeTrue())
+			Expect(found).To(Equal(l))
+		}
+
+		_, ok := timewarp.LookupLocale("it")
+		Expect(ok).To(BeFalse())
+	})
+
+	Describe("Describe", func() {
+		var e timewarp.Expr
+
+		BeforeEach(func() {
+			var err error
+			e, err = timewarp.ParseExprString("DAY TUESDAY OF 2 MONTH MARCH")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should describe in English by default", func() {
+			Expect(timewarp.English.Describe(e)).To(Equal("the second Tuesday of March"))
+			Expect(timewarp.German.Describe(e)).To(Equal("the second Tuesday of March"))
+		})
+
+		It("should use the describer of the locale", func() {
+			l := &timewarp.Locale{
+				Name:     "fr",
+				Keywords: timewarp.French.Keywords,
+				Describer: timewarp.DescriberFunc(func(e timewarp.Expr) string {
+					return "le deuxième mardi de mars"
+				}),
+			}
+			Expect(l.Describe(e)).To(Equal("le deuxième mardi de mars"))
+		})
+	})
+})
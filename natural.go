@@ -0,0 +1,996 @@
+package timewarp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ParseNatural parses an English phrase, such as "every other Saturday",
+// "weekdays 9 to 5 except Tuesdays", "the last Friday of each month" or
+// "July 15th 2008 from noon to 2pm", into a schedule holding the compiled
+// filter and the equivalent canonical expression.  Relative words such as
+// "today", "next Monday" or "this year" are resolved against the reference
+// time.  Phrases that are already timerangeQL are parsed as such.
+//
+// Hours without am or pm are read as business hours, so "9 to 5" is from
+// 09:00 to 17:00 and "1 to 3" is from 13:00 to 15:00.  Phrases that never
+// match, such as "the 31st of February", are rejected with the first error
+// reported by Lint.  The schedule is compiled from its canonical expression,
+// so that it reads back from its text unchanged.
+func ParseNatural(s string, ref time.Time) (*Schedule, error) {
+	if e, err := ParseExprString(s); err == nil {
+		return &Schedule{expr: e, filter: e.Filter()}, nil
+	}
+
+	toks, err := naturalTokenize(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &naturalParser{toks: toks, ref: ref}
+	e, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range Lint(e) {
+		if d.Severity == SeverityError {
+			return nil, p.errorf(toks[0], "%s", d.Message)
+		}
+	}
+
+	if e, err = ParseExprString(e.String()); err != nil {
+		return nil, p.errorf(toks[0], "cannot express the phrase: %s", err)
+	}
+	return &Schedule{expr: e, filter: e.Filter()}, nil
+}
+
+// naturalToken is a word, number or punctuation of a natural phrase.
+type naturalToken struct {
+	word string // lower case word, "," or "-", or empty for a number
+	col  int
+
+	num    int
+	min    int    // minutes of a clock time, or -1
+	mer    string // "am" or "pm"
+	suffix bool   // ordinal suffix, as in "15th"
+	zero   bool   // leading zero, as in "09:00"
+}
+
+// isNumber returns true for numbers.
+func (t *naturalToken) isNumber() bool {
+	return t.word == ""
+}
+
+// is returns true if the token is one of the words.
+func (t *naturalToken) is(words ...string) bool {
+	for _, w := range words {
+		if t.word == w {
+			return true
+		}
+	}
+	return false
+}
+
+// isClock returns true for numbers that can only be times of day.
+func (t *naturalToken) isClock() bool {
+	return t.isNumber() && !t.suffix && (t.min >= 0 || t.mer != "")
+}
+
+// naturalTokenize splits a phrase into tokens.
+func naturalTokenize(s string) ([]*naturalToken, error) {
+	var (
+		toks  []*naturalToken
+		runes = []rune(strings.ToLower(s))
+	)
+	for i := 0; i < len(runes); {
+		ch := runes[i]
+		switch {
+		case unicode.IsSpace(ch), ch == '.', ch == '\'':
+			i++
+		case ch == ',' || ch == ';':
+			toks = append(toks, &naturalToken{word: ",", col: i})
+			i++
+		case ch == '-' || ch == '–' || ch == '—':
+			toks = append(toks, &naturalToken{word: "-", col: i})
+			i++
+		case ch == '&':
+			toks = append(toks, &naturalToken{word: "and", col: i})
+			i++
+		case unicode.IsLetter(ch):
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			word := strings.Replace(string(runes[start:i]), ".", "", -1)
+
+			// "9 am" and "9 a.m." qualify the number before them
+			if n := len(toks); (word == "am" || word == "pm") && n > 0 && toks[n-1].isNumber() && toks[n-1].mer == "" {
+				toks[n-1].mer = word
+				continue
+			}
+			toks = append(toks, &naturalToken{word: word, col: start})
+		case ch >= '0' && ch <= '9':
+			t := &naturalToken{col: i, min: -1, zero: ch == '0'}
+			start := i
+			for i < len(runes) && runes[i] >= '0' && runes[i] <= '9' {
+				i++
+			}
+			t.num, _ = strconv.Atoi(string(runes[start:i]))
+			if i < len(runes) && runes[i] == ':' {
+				start = i + 1
+				for i = start; i < len(runes) && runes[i] >= '0' && runes[i] <= '9'; i++ {
+				}
+				if i-start != 2 {
+					return nil, &ParseError{Message: "invalid time", Pos: Pos{Char: t.col}}
+				}
+				t.min, _ = strconv.Atoi(string(runes[start:i]))
+			}
+
+			start = i
+			for i < len(runes) && unicode.IsLetter(runes[i]) {
+				i++
+			}
+			switch suffix := string(runes[start:i]); suffix {
+			case "":
+			case "st", "nd", "rd", "th":
+				t.suffix = true
+			case "am", "pm":
+				t.mer = suffix
+			default:
+				return nil, &ParseError{Message: fmt.Sprintf("unrecognized %q", string(runes[t.col:i])), Pos: Pos{Char: t.col}}
+			}
+			toks = append(toks, t)
+		default:
+			return nil, &ParseError{Message: fmt.Sprintf("unexpected %q", ch), Pos: Pos{Char: i}}
+		}
+	}
+	return toks, nil
+}
+
+// naturalWeekdays are the names of the days of the week and their
+// abbreviations.
+var naturalWeekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday, "weds": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+// naturalMonths are the names of the months and their abbreviations.
+var naturalMonths = map[string]time.Month{
+	"january": time.January, "jan": time.January,
+	"february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March,
+	"april": time.April, "apr": time.April,
+	"may":  time.May,
+	"june": time.June, "jun": time.June,
+	"july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August,
+	"september": time.September, "sep": time.September, "sept": time.September,
+	"october": time.October, "oct": time.October,
+	"november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+}
+
+// naturalPeriods are the times of day named by words.
+var naturalPeriods = map[string]*TimeExpr{
+	"morning":   {From: "0600", To: "1200"},
+	"afternoon": {From: "1200", To: "1700"},
+	"evening":   {From: "1700", To: "2100"},
+	"night":     {From: "2100", To: "0600"},
+}
+
+// naturalWeekday returns the day of the week named by a word, which may be
+// plural.
+func naturalWeekday(word string) (time.Weekday, bool) {
+	if d, ok := naturalWeekdays[word]; ok {
+		return d, true
+	}
+	d, ok := naturalWeekdays[strings.TrimSuffix(word, "s")]
+	return d, ok
+}
+
+// naturalPeriod returns the time of day named by a word, which may be plural.
+func naturalPeriod(word string) (*TimeExpr, bool) {
+	if t, ok := naturalPeriods[word]; ok {
+		return t, true
+	}
+	t, ok := naturalPeriods[strings.TrimSuffix(word, "s")]
+	return t, ok
+}
+
+// naturalParser parses the tokens of a natural phrase into an expression.
+type naturalParser struct {
+	toks []*naturalToken
+	i    int
+	ref  time.Time
+}
+
+// naturalClause holds the parts of a clause, which are intersected.
+type naturalClause struct {
+	parts  []Expr
+	month  Expr
+	year   Expr
+	times  Expr
+	except []Expr
+	daily  bool
+}
+
+// expr returns the intersection of the parts of the clause, or nil if it is
+// empty.
+func (c *naturalClause) expr() Expr {
+	var e Expr
+	add := func(x Expr) {
+		if x == nil {
+			return
+		}
+		if e == nil {
+			e = x
+			return
+		}
+		e = &BinaryExpr{Op: IN, X: e, Y: x}
+	}
+
+	for _, x := range c.parts {
+		add(x)
+	}
+	add(c.month)
+	add(c.year)
+	add(c.times)
+	if e == nil && c.daily {
+		e = &DayExpr{}
+	}
+	for _, x := range c.except {
+		if e == nil {
+			e = &NotExpr{X: x}
+			continue
+		}
+		add(&NotExpr{X: x})
+	}
+	return e
+}
+
+// peek returns the token at the offset from the current token, or nil past
+// the end.
+func (p *naturalParser) peek(offset int) *naturalToken {
+	if p.i+offset >= len(p.toks) {
+		return nil
+	}
+	return p.toks[p.i+offset]
+}
+
+// next consumes and returns the current token.
+func (p *naturalParser) next() *naturalToken {
+	t := p.peek(0)
+	if t != nil {
+		p.i++
+	}
+	return t
+}
+
+// accept consumes the current token if it is one of the words.
+func (p *naturalParser) accept(words ...string) bool {
+	if t := p.peek(0); t != nil && t.is(words...) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+// errorf returns a parse error at the token, or at the end of the phrase.
+func (p *naturalParser) errorf(t *naturalToken, format string, args ...interface{}) error {
+	var pos Pos
+	if t != nil {
+		pos.Char = t.col
+	} else if n := len(p.toks); n > 0 {
+		pos.Char = p.toks[n-1].col + len(p.toks[n-1].word) + 1
+	}
+	return &ParseError{Message: fmt.Sprintf(format, args...), Pos: pos}
+}
+
+// unexpected returns an error for the token.
+func (p *naturalParser) unexpected(t *naturalToken) error {
+	switch {
+	case t == nil:
+		return p.errorf(t, "unexpected end of phrase")
+	case t.isNumber():
+		return p.errorf(t, "unexpected number %d", t.num)
+	}
+	return p.errorf(t, "unrecognized %q", t.word)
+}
+
+// parse parses a union of clauses.
+func (p *naturalParser) parse() (Expr, error) {
+	var e Expr
+	for {
+		x, err := p.clause()
+		if err != nil {
+			return nil, err
+		}
+		if x == nil {
+			return nil, p.unexpected(p.peek(0))
+		}
+		if e == nil {
+			e = x
+		} else {
+			e = &BinaryExpr{Op: AND, X: e, Y: x}
+		}
+
+		if p.peek(0) == nil {
+			return e, nil
+		}
+		if !p.accept(",", "and", "or", "plus") {
+			return nil, p.unexpected(p.peek(0))
+		}
+		p.accept("and", "also")
+	}
+}
+
+// clause parses the parts of a clause until a token that cannot continue it.
+func (p *naturalParser) clause() (Expr, error) {
+	var c naturalClause
+
+	for {
+		t := p.peek(0)
+		if t == nil {
+			return c.expr(), nil
+		}
+
+		var err error
+		switch {
+		case t.is("every", "each"):
+			p.next()
+			if n, ok := p.repeat(); ok {
+				var x Expr
+				if x, err = p.everyNth(n); err == nil {
+					c.parts = append(c.parts, x)
+				}
+			} else {
+				// "every month on the 15th"
+				p.accept("month", "week", "year")
+			}
+		case t.is("daily"):
+			p.next()
+			c.daily = true
+		case t.is("day", "days"):
+			p.next()
+			c.daily = true
+		case t.is("all") && p.peek(1) != nil && p.peek(1).is("day"):
+			p.i += 2
+		case t.is("the", "on", "in", "at", "during", "of", "s"):
+			p.next()
+		case t.is("this", "next", "last") && p.relative(1):
+			err = p.relativeDate(&c)
+		case t.is("today", "tomorrow", "yesterday"):
+			err = p.relativeDate(&c)
+		case p.isOrdinal(t):
+			err = p.ordinal(&c)
+		case t.is("weekday", "weekdays", "weekend", "weekends") || isWeekdayWord(t):
+			var x Expr
+			if x, err = p.weekdays(); err == nil {
+				c.parts = append(c.parts, x)
+			}
+		case t.is("business", "office", "working") && p.peek(1) != nil && p.peek(1).is("hours"):
+			p.i += 2
+			c.parts = append(c.parts, &WeekdayExpr{From: time.Monday, To: time.Friday})
+			err = c.setTimes(p, t, &TimeExpr{From: "0900", To: "1700"})
+		case isMonthWord(t):
+			err = p.month(&c)
+		case t.isNumber() && !t.suffix && t.num >= 1000 && t.min < 0 && t.mer == "":
+			p.next()
+			err = c.setYear(p, t, &YearExpr{Year: t.num})
+		case t.isNumber() && !t.clockRange(p.peek(1)) && p.monthFollows(1):
+			err = p.dayOfMonth(&c)
+		case t.isNumber() || t.is("noon", "midnight"):
+			err = p.times(&c)
+		case t.is("from", "between"):
+			if u := p.peek(1); u != nil && (isWeekdayWord(u) || isMonthWord(u) || u.is("weekday", "weekdays")) {
+				p.next()
+				continue
+			}
+			err = p.times(&c)
+		case naturalPeriodWord(t):
+			p.next()
+			period, _ := naturalPeriod(t.word)
+			err = c.setTimes(p, t, &TimeExpr{From: period.From, To: period.To})
+		case t.is("except", "excluding", "without", "not") || (t.is("but") && p.peek(1) != nil && p.peek(1).is("not")):
+			p.next()
+			p.accept("not")
+			x, err := p.clause()
+			if err != nil {
+				return nil, err
+			}
+			if x == nil {
+				return nil, p.unexpected(p.peek(0))
+			}
+			c.except = append(c.except, x)
+		case t.is(","):
+			// a comma may separate the parts of a clause, as in "Mondays,
+			// 9 to 5"
+			if u := p.peek(1); u != nil && c.times == nil && p.startsTimes(1) {
+				p.next()
+				continue
+			}
+			return c.expr(), nil
+		default:
+			return c.expr(), nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// clockRange returns true if the number starts a range of times, as in "9 to
+// 5".
+func (t *naturalToken) clockRange(next *naturalToken) bool {
+	return t.isClock() || (next != nil && next.is("to", "-", "until", "till"))
+}
+
+// startsTimes returns true if the token at the offset starts a time of day.
+func (p *naturalParser) startsTimes(offset int) bool {
+	t := p.peek(offset)
+	switch {
+	case t == nil:
+		return false
+	case t.is("from", "between", "noon", "midnight"):
+		return true
+	case t.isNumber() && !t.suffix:
+		return t.clockRange(p.peek(offset + 1))
+	}
+	return naturalPeriodWord(t)
+}
+
+// setTimes sets the times of day of the clause.
+func (c *naturalClause) setTimes(p *naturalParser, t *naturalToken, x Expr) error {
+	if c.times != nil {
+		return p.errorf(t, "times of day are already given")
+	}
+	c.times = x
+	return nil
+}
+
+// setYear sets the year of the clause.
+func (c *naturalClause) setYear(p *naturalParser, t *naturalToken, x Expr) error {
+	if c.year != nil {
+		return p.errorf(t, "the year is already given")
+	}
+	c.year = x
+	return nil
+}
+
+// setMonth sets the month of the clause.
+func (c *naturalClause) setMonth(p *naturalParser, t *naturalToken, x Expr) error {
+	if c.month != nil {
+		return p.errorf(t, "the month is already given")
+	}
+	c.month = x
+	return nil
+}
+
+// isWeekdayWord returns true if the token names a day of the week.
+func isWeekdayWord(t *naturalToken) bool {
+	_, ok := naturalWeekday(t.word)
+	return ok
+}
+
+// isMonthWord returns true if the token names a month.
+func isMonthWord(t *naturalToken) bool {
+	_, ok := naturalMonths[t.word]
+	return ok
+}
+
+// naturalPeriodWord returns true if the token names a time of day.
+func naturalPeriodWord(t *naturalToken) bool {
+	_, ok := naturalPeriod(t.word)
+	return ok
+}
+
+// ordinalValue returns the value of an ordinal word or number, counting from
+// the end for "last".
+func ordinalValue(t *naturalToken) (int, bool) {
+	if t.isNumber() {
+		return t.num, t.suffix && t.num > 0
+	}
+	switch t.word {
+	case "last":
+		return -1, true
+	case "penultimate":
+		return -2, true
+	}
+	for n, w := range ordinalWords {
+		if n > 0 && t.word == w {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// isOrdinal returns true if the token is an ordinal word or number.
+func (p *naturalParser) isOrdinal(t *naturalToken) bool {
+	_, ok := ordinalValue(t)
+	return ok
+}
+
+// repeat consumes the count of an "every nth" repetition, such as "other" or
+// "third".
+func (p *naturalParser) repeat() (int, bool) {
+	t := p.peek(0)
+	if t == nil {
+		return 0, false
+	}
+	if t.is("other") {
+		p.next()
+		return 2, true
+	}
+	if n, ok := ordinalValue(t); ok && n > 1 {
+		// "every 15th" is a day of the month
+		if u := p.peek(1); u != nil && (isWeekdayWord(u) || u.is("week", "weeks")) {
+			p.next()
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// everyNth parses what repeats every nth week: a day of the week or the week
+// itself.
+func (p *naturalParser) everyNth(n int) (Expr, error) {
+	t := p.next()
+	switch {
+	case t == nil:
+		return nil, p.unexpected(t)
+	case t.is("week", "weeks"):
+		return &OrdinalExpr{X: &WeekExpr{Weekday: -1}, N: n, Unit: &WeekExpr{Weekday: -1}}, nil
+	case isWeekdayWord(t):
+		d, _ := naturalWeekday(t.word)
+		return &OrdinalExpr{X: &WeekdayExpr{From: d, To: d}, N: n, Unit: &WeekExpr{Weekday: d}}, nil
+	}
+	return nil, p.errorf(t, "cannot repeat %q", t.word)
+}
+
+// ordinal parses the ordinals of days of the week in a month, as in "the
+// first and third Monday of each month", or days of the month, as in "the
+// 1st and 15th of July".
+func (p *naturalParser) ordinal(c *naturalClause) error {
+	var (
+		first = p.peek(0)
+		ns    []int
+	)
+	for {
+		t := p.next()
+		n, ok := ordinalValue(t)
+		if !ok {
+			return p.unexpected(t)
+		}
+		// "second to last"
+		if u, v := p.peek(0), p.peek(1); n > 0 && u != nil && v != nil && u.is("to") && v.is("last") {
+			p.i += 2
+			n = -n
+		}
+		ns = append(ns, n)
+
+		if u, v := p.peek(0), p.peek(1); u != nil && v != nil && u.is("and", ",", "or") && p.isOrdinal(v) {
+			p.next()
+			continue
+		}
+		break
+	}
+
+	// the days of the week or of the month
+	var (
+		x    Expr
+		days bool
+	)
+	t := p.peek(0)
+	switch {
+	case t != nil && isWeekdayWord(t):
+		p.next()
+		d, _ := naturalWeekday(t.word)
+		x = &WeekdayExpr{From: d, To: d}
+	case t != nil && t.is("day", "days"):
+		p.next()
+		x = &DayExpr{}
+	default:
+		days = true
+	}
+
+	unit, err := p.monthUnit(c)
+	if err != nil {
+		return err
+	}
+
+	var e Expr
+	for _, n := range ns {
+		var o Expr
+		switch {
+		case !days:
+			o = &OrdinalExpr{X: x, N: n, Unit: unit}
+		case n < 0:
+			o = &OrdinalExpr{X: &DayExpr{}, N: n, Unit: unit}
+		case n > 31:
+			return p.errorf(first, "invalid day of the month %d", n)
+		default:
+			o = &OrdinalExpr{X: &DayExpr{Args: []int{n}}, N: 1, Unit: unit}
+		}
+
+		if e == nil {
+			e = o
+		} else {
+			e = &BinaryExpr{Op: AND, X: e, Y: o}
+		}
+	}
+	c.parts = append(c.parts, e)
+	return nil
+}
+
+// monthUnit parses the month of an ordinal, as in "of each month" or "in
+// November 2016", which defaults to every month.
+func (p *naturalParser) monthUnit(c *naturalClause) (*MonthExpr, error) {
+	save := p.i
+	if !p.accept("of", "in") {
+		return &MonthExpr{}, nil
+	}
+	p.accept("the", "each", "every")
+
+	t := p.peek(0)
+	switch {
+	case t == nil:
+	case t.is("month"):
+		p.next()
+		return &MonthExpr{}, nil
+	case isMonthWord(t):
+		p.next()
+		if err := p.year(c); err != nil {
+			return nil, err
+		}
+		return &MonthExpr{Month: naturalMonths[t.word]}, nil
+	}
+
+	// the words belong to the next part
+	p.i = save
+	return &MonthExpr{}, nil
+}
+
+// year parses an optional year following a date, as in "July 15th, 2008".
+func (p *naturalParser) year(c *naturalClause) error {
+	offset := 0
+	if t := p.peek(0); t != nil && t.is(",") {
+		offset = 1
+	}
+	t := p.peek(offset)
+	if t == nil || !t.isNumber() || t.suffix || t.num < 1000 || t.min >= 0 || t.mer != "" {
+		return nil
+	}
+	p.i += offset + 1
+	return c.setYear(p, t, &YearExpr{Year: t.num})
+}
+
+// monthFollows returns true if a month follows the day at the offset, as in
+// "15 July" or "15th of July".
+func (p *naturalParser) monthFollows(offset int) bool {
+	t := p.peek(offset)
+	if t != nil && t.is("of") {
+		t = p.peek(offset + 1)
+	}
+	return t != nil && isMonthWord(t)
+}
+
+// dayOfMonth parses a day of the month, followed or preceded by its month.
+func (p *naturalParser) dayOfMonth(c *naturalClause) error {
+	t := p.next()
+	if t.num < 1 || t.num > 31 {
+		return p.errorf(t, "invalid day of the month %d", t.num)
+	}
+
+	month := &MonthExpr{}
+	p.accept("of")
+	if p.accept("the", "each", "every") {
+		if !p.accept("month") {
+			return p.unexpected(p.peek(0))
+		}
+	} else if u := p.peek(0); u != nil && u.is("month") {
+		p.next()
+	} else if u != nil && isMonthWord(u) {
+		p.next()
+		month.Month = naturalMonths[u.word]
+		if err := p.year(c); err != nil {
+			return err
+		}
+	}
+
+	c.parts = append(c.parts, &OrdinalExpr{X: &DayExpr{Args: []int{t.num}}, N: 1, Unit: month})
+	return nil
+}
+
+// month parses a month, which may be followed by a day of the month, or a
+// list or range of months.
+func (p *naturalParser) month(c *naturalClause) error {
+	t := p.next()
+	m := naturalMonths[t.word]
+
+	// a date, as in "July 15th"
+	if u := p.peek(0); u != nil && u.isNumber() && !u.isClock() && u.num >= 1 && u.num <= 31 {
+		d := p.next()
+		c.parts = append(c.parts, &OrdinalExpr{X: &DayExpr{Args: []int{d.num}}, N: 1, Unit: &MonthExpr{Month: m}})
+		return p.year(c)
+	}
+
+	months := []time.Month{m}
+	for {
+		u, v := p.peek(0), p.peek(1)
+		if u == nil || v == nil || !isMonthWord(v) {
+			break
+		}
+		switch {
+		case u.is("to", "through", "thru", "until", "-"):
+			p.i += 2
+			for n := months[len(months)-1]; n != naturalMonths[v.word]; {
+				n = n%12 + 1
+				months = append(months, n)
+			}
+		case u.is("and", ",", "or"):
+			p.i += 2
+			months = append(months, naturalMonths[v.word])
+		default:
+			return p.unexpected(u)
+		}
+	}
+
+	var e Expr
+	for _, m := range months {
+		var x Expr = &MonthExpr{Month: m}
+		if e == nil {
+			e = x
+		} else {
+			e = &BinaryExpr{Op: AND, X: e, Y: x}
+		}
+	}
+	if err := c.setMonth(p, t, e); err != nil {
+		return err
+	}
+	return p.year(c)
+}
+
+// weekdays parses a list of days of the week, each of which may be a range
+// as in "Monday to Friday".
+func (p *naturalParser) weekdays() (Expr, error) {
+	var e Expr
+	for {
+		t := p.next()
+
+		var x *WeekdayExpr
+		switch {
+		case t.is("weekday", "weekdays"):
+			x = &WeekdayExpr{From: time.Monday, To: time.Friday}
+		case t.is("weekend", "weekends"):
+			x = &WeekdayExpr{From: time.Saturday, To: time.Sunday}
+		default:
+			d, _ := naturalWeekday(t.word)
+			x = &WeekdayExpr{From: d, To: d}
+			if u, v := p.peek(0), p.peek(1); u != nil && v != nil && u.is("to", "through", "thru", "until", "-") && isWeekdayWord(v) {
+				p.i += 2
+				x.To, _ = naturalWeekday(v.word)
+			}
+		}
+
+		if e == nil {
+			e = x
+		} else {
+			e = &BinaryExpr{Op: AND, X: e, Y: x}
+		}
+
+		u, v := p.peek(0), p.peek(1)
+		if u == nil || v == nil || !u.is("and", ",", "or") || !(isWeekdayWord(v) || v.is("weekday", "weekdays", "weekend", "weekends")) {
+			return e, nil
+		}
+		p.next()
+	}
+}
+
+// naturalTime is a time of day as written.
+type naturalTime struct {
+	hour, min int
+	mer       string
+	explicit  bool // a 24-hour time, noon or midnight
+}
+
+// clock parses a time of day.
+func (p *naturalParser) clock() (naturalTime, error) {
+	t := p.next()
+	switch {
+	case t == nil:
+		return naturalTime{}, p.unexpected(t)
+	case t.is("noon", "midday"):
+		return naturalTime{hour: 12, explicit: true}, nil
+	case t.is("midnight"):
+		return naturalTime{hour: 0, explicit: true}, nil
+	case !t.isNumber() || t.suffix:
+		return naturalTime{}, p.unexpected(t)
+	}
+
+	nt := naturalTime{hour: t.num, mer: t.mer}
+	if t.min > 0 {
+		nt.min = t.min
+	}
+	switch {
+	case t.mer != "" && (t.num < 1 || t.num > 12):
+		return naturalTime{}, p.errorf(t, "invalid time %d%s", t.num, t.mer)
+	case t.num > 24 || nt.min > 59 || (t.num == 24 && nt.min > 0):
+		return naturalTime{}, p.errorf(t, "invalid time %d", t.num)
+	}
+	nt.explicit = t.mer == "" && (t.zero || t.num == 0 || t.num > 12)
+	return nt, nil
+}
+
+// times parses one or more ranges of times of day, as in "from 9 to 12 and
+// 1 to 5".
+func (p *naturalParser) times(c *naturalClause) error {
+	start := p.peek(0)
+
+	var e Expr
+	for {
+		between := p.accept("between")
+		if !between {
+			p.accept("from")
+		}
+
+		from, err := p.clock()
+		if err != nil {
+			return err
+		}
+		switch {
+		case between && p.accept("and"):
+		case p.accept("to", "until", "till", "thru", "through", "-"):
+		default:
+			return p.unexpected(p.peek(0))
+		}
+		to, err := p.clock()
+		if err != nil {
+			return err
+		}
+
+		var x Expr = resolveTimes(from, to)
+		if e == nil {
+			e = x
+		} else {
+			e = &BinaryExpr{Op: AND, X: e, Y: x}
+		}
+
+		if u := p.peek(0); u == nil || !u.is("and", ",") || !p.startsTimes(1) {
+			break
+		}
+		p.next()
+	}
+	return c.setTimes(p, start, e)
+}
+
+// resolveTimes returns the time expression of a range of times, resolving
+// hours written without am or pm.
+func resolveTimes(from, to naturalTime) *TimeExpr {
+	h24 := func(t naturalTime, mer string) int {
+		switch {
+		case mer == "am" && t.hour == 12:
+			return 0
+		case mer == "pm" && t.hour < 12:
+			return t.hour + 12
+		}
+		return t.hour
+	}
+
+	var start, end int
+	switch {
+	case from.mer == "" && to.mer != "" && !from.explicit:
+		end = h24(to, to.mer)
+		start = h24(from, to.mer)
+		if start >= end && to.mer == "pm" {
+			start = h24(from, "am")
+		}
+	case from.mer != "" && to.mer == "" && !to.explicit:
+		start = h24(from, from.mer)
+		end = h24(to, from.mer)
+		if end <= start {
+			end = h24(to, "pm")
+		}
+	default:
+		start, end = h24(from, from.mer), h24(to, to.mer)
+		// business hours: "1 to 3" is in the afternoon, and "9 to 5" ends
+		// in the afternoon
+		if from.mer == "" && !from.explicit && start >= 1 && start <= 6 {
+			start += 12
+		}
+		if to.mer == "" && !to.explicit && end < 12 && end*60+to.min <= start*60+from.min {
+			end += 12
+		}
+	}
+
+	format := func(h, m int) string {
+		return fmt.Sprintf("%02d%02d", h%24, m)
+	}
+	return &TimeExpr{From: format(start, from.min), To: format(end, to.min)}
+}
+
+// relative returns true if the word after "this", "next" or "last" at the
+// offset makes a relative date.  "The last Friday" and "last Friday of the
+// month" are ordinals.
+func (p *naturalParser) relative(offset int) bool {
+	t := p.peek(offset)
+	switch {
+	case t == nil:
+		return false
+	case t.is("year", "month"):
+		return true
+	case !isWeekdayWord(t):
+		return false
+	case p.peek(0).is("last"):
+		if p.i > 0 && p.toks[p.i-1].is("the") {
+			return false
+		}
+		if u := p.peek(offset + 1); u != nil && u.is("of", "in") {
+			return false
+		}
+	}
+	return true
+}
+
+// relativeDate parses a date relative to the reference time.
+func (p *naturalParser) relativeDate(c *naturalClause) error {
+	t := p.next()
+	ref := p.ref
+
+	date := func(d time.Time) error {
+		y, m, day := d.Date()
+		c.parts = append(c.parts, &OrdinalExpr{X: &DayExpr{Args: []int{day}}, N: 1, Unit: &MonthExpr{Month: m}})
+		return c.setYear(p, t, &YearExpr{Year: y})
+	}
+
+	switch t.word {
+	case "today":
+		return date(ref)
+	case "tomorrow":
+		return date(ref.AddDate(0, 0, 1))
+	case "yesterday":
+		return date(ref.AddDate(0, 0, -1))
+	}
+
+	offset := map[string]int{"this": 0, "next": 1, "last": -1}[t.word]
+	u := p.next()
+	switch {
+	case u.is("year"):
+		return c.setYear(p, t, &YearExpr{Year: ref.Year() + offset})
+	case u.is("month"):
+		y, m, _ := ref.Date()
+		first := time.Date(y, m, 1, 0, 0, 0, 0, ref.Location()).AddDate(0, offset, 0)
+		if err := c.setMonth(p, t, &MonthExpr{Month: first.Month()}); err != nil {
+			return err
+		}
+		return c.setYear(p, t, &YearExpr{Year: first.Year()})
+	}
+
+	// the day of the week on or after the reference time for "this", after
+	// it for "next", and before it for "last"
+	d, _ := naturalWeekday(u.word)
+	day := ref
+	switch offset {
+	case 0:
+		day = day.AddDate(0, 0, getWeekdayDelta(day.Weekday(), d))
+	case 1:
+		day = day.AddDate(0, 0, getWeekdayDelta(day.Weekday(), d))
+		if day.Day() == ref.Day() && day.Month() == ref.Month() {
+			day = day.AddDate(0, 0, 7)
+		}
+	default:
+		day = day.AddDate(0, 0, -getWeekdayDelta(d, day.Weekday()))
+		if day.Day() == ref.Day() && day.Month() == ref.Month() {
+			day = day.AddDate(0, 0, -7)
+		}
+	}
+	return date(day)
+}
@@ -0,0 +1,211 @@
+package timewarp_test
+
+import (
+	"time"
+
+	"github.com/takeinitiative/timewarp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseNatural", func() {
+	// 03-14-18 is a Wednesday
+	ref := time.Date(2018, 3, 14, 10, 0, 0, 0, time.UTC)
+
+	DescribeTable("Phrases",
+		func(phrase, expr string) {
+			s, err := timewarp.ParseNatural(phrase, ref)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(s.String()).To(Equal(expr))
+
+			// the canonical expression is valid timerangeQL
+			_, err = timewarp.ParseExprString(expr)
+			Expect(err).NotTo(HaveOccurred())
+		},
+
+		// README examples
+		Entry("the second Tuesday of March from 12 to 2pm", "the second Tuesday of March from 12 to 2pm", "DAY TUESDAY OF 2 MONTH MARCH IN TIME 1200 1400"),
+		Entry("Fridays through Sundays and Wednesdays", "Fridays through Sundays and Wednesdays", "DAY FRIDAY SUNDAY AND DAY WEDNESDAY"),
+		Entry("July 15, 2008", "July 15, 2008", "DAY 15 OF MONTH JULY IN YEAR 2008"),
+		Entry("weekdays from 5 to 11am except Tuesdays", "weekdays from 5 to 11am except Tuesdays", "DAY MONDAY FRIDAY IN TIME 0500 1100 IN NOT DAY TUESDAY"),
+		Entry("every other Saturday", "every other Saturday", "DAY SATURDAY OF 2 WEEK SATURDAY"),
+
+		// days of the week
+		Entry("Mondays", "Mondays", "DAY MONDAY"),
+		Entry("on Tuesday", "on Tuesday", "DAY TUESDAY"),
+		Entry("every Wednesday", "every Wednesday", "DAY WEDNESDAY"),
+		Entry("thurs", "thurs", "DAY THURSDAY"),
+		Entry("Monday to Friday", "Monday to Friday", "DAY MONDAY FRIDAY"),
+		Entry("from Monday through Friday", "from Monday through Friday", "DAY MONDAY FRIDAY"),
+		Entry("mon-fri", "mon-fri", "DAY MONDAY FRIDAY"),
+		Entry("Friday to Sunday and Wednesday", "Friday to Sunday and Wednesday", "DAY FRIDAY SUNDAY AND DAY WEDNESDAY"),
+		Entry("Mondays, Wednesdays and Fridays", "Mondays, Wednesdays and Fridays", "DAY MONDAY AND DAY WEDNESDAY AND DAY FRIDAY"),
+		Entry("Tuesdays or Thursdays", "Tuesdays or Thursdays", "DAY TUESDAY AND DAY THURSDAY"),
+		Entry("weekdays", "weekdays", "DAY MONDAY FRIDAY"),
+		Entry("weekends", "weekends", "DAY SATURDAY SUNDAY"),
+		Entry("weekends and Mondays", "weekends and Mondays", "DAY SATURDAY SUNDAY AND DAY MONDAY"),
+		Entry("every day", "every day", "DAY"),
+		Entry("daily", "daily", "DAY"),
+
+		// times of day
+		Entry("9 to 5", "9 to 5", "TIME 0900 1700"),
+		Entry("9am to 5pm", "9am to 5pm", "TIME 0900 1700"),
+		Entry("9 a.m. to 5 p.m.", "9 a.m. to 5 p.m.", "TIME 0900 1700"),
+		Entry("9 to 5pm", "9 to 5pm", "TIME 0900 1700"),
+		Entry("9am to 5", "9am to 5", "TIME 0900 1700"),
+		Entry("1 to 3pm", "1 to 3pm", "TIME 1300 1500"),
+		Entry("1 to 3", "1 to 3", "TIME 1300 1500"),
+		Entry("10 to 2", "10 to 2", "TIME 1000 1400"),
+		Entry("11am-1pm", "11am-1pm", "TIME 1100 1300"),
+		Entry("9:30 to 17:00", "9:30 to 17:00", "TIME 0930 1700"),
+		Entry("08:00 to 12:00", "08:00 to 12:00", "TIME 0800 1200"),
+		Entry("from noon to 2pm", "from noon to 2pm", "TIME 1200 1400"),
+		Entry("between 9 and 5", "between 9 and 5", "TIME 0900 1700"),
+		Entry("10pm to 6am", "10pm to 6am", "TIME 2200 0600"),
+		Entry("8 to midnight", "8 to midnight", "TIME 0800 0000"),
+		Entry("12am to 12pm", "12am to 12pm", "TIME 0000 1200"),
+		Entry("9 to 12 and 1 to 5", "9 to 12 and 1 to 5", "TIME 0900 1200 AND TIME 1300 1700"),
+		Entry("mornings", "mornings", "TIME 0600 1200"),
+		Entry("in the afternoon", "in the afternoon", "TIME 1200 1700"),
+		Entry("evenings", "evenings", "TIME 1700 2100"),
+		Entry("daily from 9am to 5pm", "daily from 9am to 5pm", "TIME 0900 1700"),
+
+		// days and times
+		Entry("weekdays 9 to 5", "weekdays 9 to 5", "DAY MONDAY FRIDAY IN TIME 0900 1700"),
+		Entry("weekdays 9 to 5 except Tuesdays", "weekdays 9 to 5 except Tuesdays", "DAY MONDAY FRIDAY IN TIME 0900 1700 IN NOT DAY TUESDAY"),
+		Entry("9 to 5 on weekdays", "9 to 5 on weekdays", "DAY MONDAY FRIDAY IN TIME 0900 1700"),
+		Entry("Mondays, 9 to 5", "Mondays, 9 to 5", "DAY MONDAY IN TIME 0900 1700"),
+		Entry("weekdays from 10pm to 6am", "weekdays from 10pm to 6am", "DAY MONDAY FRIDAY IN TIME 2200 0600"),
+		Entry("weekend afternoons", "weekend afternoons", "DAY SATURDAY SUNDAY IN TIME 1200 1700"),
+		Entry("weekdays 9 to 12 and 1 to 5", "weekdays 9 to 12 and 1 to 5", "DAY MONDAY FRIDAY IN (TIME 0900 1200 AND TIME 1300 1700)"),
+		Entry("weekdays 9 to 5 and Saturdays 10 to 2", "weekdays 9 to 5 and Saturdays 10 to 2", "DAY MONDAY FRIDAY IN TIME 0900 1700 AND (DAY SATURDAY IN TIME 1000 1400)"),
+		Entry("business hours", "business hours", "DAY MONDAY FRIDAY IN TIME 0900 1700"),
+		Entry("office hours except Fridays", "office hours except Fridays", "DAY MONDAY FRIDAY IN TIME 0900 1700 IN NOT DAY FRIDAY"),
+		Entry("weekdays but not Mondays or Fridays", "weekdays but not Mondays or Fridays", "DAY MONDAY FRIDAY IN NOT (DAY MONDAY AND DAY FRIDAY)"),
+		Entry("except Tuesdays", "except Tuesdays", "NOT DAY TUESDAY"),
+
+		// ordinals
+		Entry("the last Friday of each month", "the last Friday of each month", "DAY FRIDAY OF -1 MONTH"),
+		Entry("the first Monday of September", "the first Monday of September", "DAY MONDAY OF MONTH SEPTEMBER"),
+		Entry("the 3rd Friday", "the 3rd Friday", "DAY FRIDAY OF 3 MONTH"),
+		Entry("the fourth Thursday of November 2016", "the fourth Thursday of November 2016", "DAY THURSDAY OF 4 MONTH NOVEMBER IN YEAR 2016"),
+		Entry("the second to last Friday of November", "the second to last Friday of November", "DAY FRIDAY OF -2 MONTH NOVEMBER"),
+		Entry("the penultimate Sunday of every month", "the penultimate Sunday of every month", "DAY SUNDAY OF -2 MONTH"),
+		Entry("the last Monday of May", "the last Monday of May", "DAY MONDAY OF -1 MONTH MAY"),
+		Entry("last Friday of the month", "last Friday of the month", "DAY FRIDAY OF -1 MONTH"),
+		Entry("the first and third Monday of the month", "the first and third Monday of the month", "DAY MONDAY OF MONTH AND (DAY MONDAY OF 3 MONTH)"),
+		Entry("the 2nd and 4th Tuesdays", "the 2nd and 4th Tuesdays", "DAY TUESDAY OF 2 MONTH AND (DAY TUESDAY OF 4 MONTH)"),
+		Entry("the last day of the month", "the last day of the month", "DAY OF -1 MONTH"),
+		Entry("the tenth day of each month", "the tenth day of each month", "DAY OF 10 MONTH"),
+		Entry("every third week", "every third week", "WEEK OF 3 WEEK"),
+		Entry("every other week", "every other week", "WEEK OF 2 WEEK"),
+		Entry("every 2nd Monday", "every 2nd Monday", "DAY MONDAY OF 2 WEEK MONDAY"),
+		Entry("every other Saturday from 10am to noon", "every other Saturday from 10am to noon", "DAY SATURDAY OF 2 WEEK SATURDAY IN TIME 1000 1200"),
+
+		// dates
+		Entry("July 15th 2008 from noon to 2pm", "July 15th 2008 from noon to 2pm", "DAY 15 OF MONTH JULY IN YEAR 2008 IN TIME 1200 1400"),
+		Entry("July 15th, 2008", "July 15th, 2008", "DAY 15 OF MONTH JULY IN YEAR 2008"),
+		Entry("Jul 4", "Jul 4", "DAY 4 OF MONTH JULY"),
+		Entry("15 July", "15 July", "DAY 15 OF MONTH JULY"),
+		Entry("the 15th of July 2016", "the 15th of July 2016", "DAY 15 OF MONTH JULY IN YEAR 2016"),
+		Entry("the first of July", "the first of July", "DAY 1 OF MONTH JULY"),
+		Entry("the 15th", "the 15th", "DAY 15 OF MONTH"),
+		Entry("on the 1st and 15th of each month", "on the 1st and 15th of each month", "DAY 1 OF MONTH AND (DAY 15 OF MONTH)"),
+		Entry("every month on the 1st", "every month on the 1st", "DAY 1 OF MONTH"),
+		Entry("December 25th and January 1st", "December 25th and January 1st", "DAY 25 OF MONTH DECEMBER AND (DAY 1 OF MONTH JANUARY)"),
+
+		// months and years
+		Entry("in December", "in December", "MONTH DECEMBER"),
+		Entry("Sept", "Sept", "MONTH SEPTEMBER"),
+		Entry("June through August", "June through August", "MONTH JUNE AND MONTH JULY AND MONTH AUGUST"),
+		Entry("November to February", "November to February", "MONTH NOVEMBER AND MONTH DECEMBER AND MONTH JANUARY AND MONTH FEBRUARY"),
+		Entry("June and August", "June and August", "MONTH JUNE AND MONTH AUGUST"),
+		Entry("Mondays in July", "Mondays in July", "DAY MONDAY IN MONTH JULY"),
+		Entry("Mondays except in July", "Mondays except in July", "DAY MONDAY IN NOT MONTH JULY"),
+		Entry("weekends in June through August", "weekends in June through August", "DAY SATURDAY SUNDAY IN (MONTH JUNE AND MONTH JULY AND MONTH AUGUST)"),
+		Entry("2008", "2008", "YEAR 2008"),
+		Entry("December 2016", "December 2016", "MONTH DECEMBER IN YEAR 2016"),
+		Entry("weekdays in 2018", "weekdays in 2018", "DAY MONDAY FRIDAY IN YEAR 2018"),
+
+		// relative to the reference time
+		Entry("today", "today", "DAY 14 OF MONTH MARCH IN YEAR 2018"),
+		Entry("tomorrow from 9 to 10", "tomorrow from 9 to 10", "DAY 15 OF MONTH MARCH IN YEAR 2018 IN TIME 0900 1000"),
+		Entry("yesterday", "yesterday", "DAY 13 OF MONTH MARCH IN YEAR 2018"),
+		Entry("this Wednesday", "this Wednesday", "DAY 14 OF MONTH MARCH IN YEAR 2018"),
+		Entry("this Friday", "this Friday", "DAY 16 OF MONTH MARCH IN YEAR 2018"),
+		Entry("next Wednesday", "next Wednesday", "DAY 21 OF MONTH MARCH IN YEAR 2018"),
+		Entry("next Monday", "next Monday", "DAY 19 OF MONTH MARCH IN YEAR 2018"),
+		Entry("last Friday", "last Friday", "DAY 9 OF MONTH MARCH IN YEAR 2018"),
+		Entry("last Wednesday", "last Wednesday", "DAY 7 OF MONTH MARCH IN YEAR 2018"),
+		Entry("this year", "this year", "YEAR 2018"),
+		Entry("next year", "next year", "YEAR 2019"),
+		Entry("last month", "last month", "MONTH FEBRUARY IN YEAR 2018"),
+		Entry("weekdays next month", "weekdays next month", "DAY MONDAY FRIDAY IN MONTH APRIL IN YEAR 2018"),
+
+		// timerangeQL
+		Entry("DAY MONDAY IN TIME 0900 1000", "DAY MONDAY IN TIME 0900 1000", "DAY MONDAY IN TIME 0900 1000"),
+	)
+
+	DescribeTable("Invalid phrases",
+		func(phrase, message string) {
+			_, err := timewarp.ParseNatural(phrase, ref)
+			Expect(err).To(MatchError(message))
+		},
+		Entry("everything", "everything", `unrecognized "everything" at 1 col 1`),
+		Entry("Mondays 9", "Mondays 9", "unexpected end of phrase at 1 col 10"),
+		Entry("Mondays at 25 to 26", "Mondays at 25 to 26", "invalid time 25 at 1 col 12"),
+		Entry("13pm to 2pm", "13pm to 2pm", "invalid time 13pm at 1 col 1"),
+		Entry("the 32nd", "the 32nd", "invalid day of the month 32 at 1 col 5"),
+		Entry("the 31st of February", "the 31st of February", "DAY 31 OF MONTH FEBRUARY never matches, DAY 31 does not fit within MONTH FEBRUARY at 1 col 1"),
+		Entry("Mondays from 9 to 9am", "Mondays from 9 to 9am", "TIME 0900 0900 never matches, the times are equal at 1 col 1"),
+		Entry("empty", "", "unexpected end of phrase at 1 col 1"),
+		Entry("9 to 5 in the evening", "9 to 5 in the evening", "times of day are already given at 1 col 15"),
+		Entry("every other day", "every other day", `cannot repeat "day" at 1 col 13`),
+		Entry("Mondays / Fridays", "Mondays / Fridays", `unexpected '/' at 1 col 9`),
+		Entry("Mondays and", "Mondays and", "unexpected end of phrase at 1 col 13"),
+	)
+
+	It("should read back the schedule from its text", func() {
+		for _, phrase := range []string{"the last Friday of each month", "weekdays 9 to 5 except Tuesdays", "every other Saturday"} {
+			s, err := timewarp.ParseNatural(phrase, ref)
+			Expect(err).NotTo(HaveOccurred())
+
+			text, err := s.MarshalText()
+			Expect(err).NotTo(HaveOccurred())
+
+			var read timewarp.Schedule
+			Expect(read.UnmarshalText(text)).To(Succeed())
+			Expect(read.String()).To(Equal(s.String()), phrase)
+			Expect(read.Filter()(timewarp.TimeRange{ref, ref.AddDate(0, 3, 0)})).To(Equal(s.Filter()(timewarp.TimeRange{ref, ref.AddDate(0, 3, 0)})), phrase)
+		}
+	})
+
+	Describe("Filter", func() {
+		apply := func(phrase string, from, to time.Time) []string {
+			s, err := timewarp.ParseNatural(phrase, ref)
+			Expect(err).NotTo(HaveOccurred())
+
+			var result []string
+			for _, r := range s.Filter()(timewarp.TimeRange{from, to}) {
+				result = append(result, r.String())
+			}
+			return result
+		}
+
+		It("should find the last Friday of each month", func() {
+			Expect(apply("the last Friday of each month", time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2018, 4, 1, 0, 0, 0, 0, time.UTC))).To(Equal([]string{
+				"2018-01-26T00:00:00Z/2018-01-27T00:00:00Z",
+				"2018-02-23T00:00:00Z/2018-02-24T00:00:00Z",
+				"2018-03-30T00:00:00Z/2018-03-31T00:00:00Z",
+			}))
+		})
+
+		It("should find a date at a time", func() {
+			Expect(apply("July 15th 2008 from noon to 2pm", time.Date(2008, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2009, 1, 1, 0, 0, 0, 0, time.UTC))).To(Equal([]string{
+				"2008-07-15T12:00:00Z/2008-07-15T14:00:00Z",
+			}))
+		})
+	})
+})
@@ -0,0 +1,731 @@
+package timewarp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ohWeekdays are the opening_hours abbreviations of the days of the week.
+var ohWeekdays = [...]string{"Su", "Mo", "Tu", "We", "Th", "Fr", "Sa"}
+
+// ohMonths are the opening_hours abbreviations of the months.
+var ohMonths = [...]string{"", "Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+
+// ohRule is a single rule of an opening_hours value.
+type ohRule struct {
+	additional bool
+	months     uint16    // bit per month, or zero for every month
+	weeks      []bool    // indexed by ISO week, or nil for every week
+	weekdays   []ohRange // or nil for every day
+	off        bool
+	spans      [][2]int // minutes from midnight; the end may pass midnight
+}
+
+// ohRange is a weekday range, optionally limited to the nth occurrences
+// within the month.
+type ohRange struct {
+	from, to time.Weekday
+	nth      []int
+}
+
+// ParseOpeningHours parses an OpenStreetMap opening_hours value into a filter,
+// as in "Mo-Fr 08:00-18:00; Sa 09:00-13:00; PH off".
+//
+// Rules are evaluated for each day.  A rule following ";" replaces the hours
+// of the days it selects, while a rule following "," adds to them.  Rules may
+// select months, ISO week numbers and weekdays, including the nth weekday of
+// the month as in "Su[1]" or "Su[-1]".  Public and school holidays are not
+// known, so rules that only select them are ignored.  Times use the location
+// of the input.
+func ParseOpeningHours(s string) (Filter, error) {
+	p := ohParser{toks: ohTokenize(s)}
+	rules, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	return dailyFilter(func(day time.Time) [][2]int {
+		return ohSpans(rules, day)
+	}), nil
+}
+
+// dailyFilter returns a filter of the spans of each day, in minutes from
+// midnight.  Spans may pass midnight into the next day.
+func dailyFilter(spans func(day time.Time) [][2]int) Filter {
+	return func(input TimeRange) []*TimeRange {
+		var (
+			result  []*TimeRange
+			loc     = input.Start.Location()
+			y, m, d = input.Start.Date()
+		)
+
+		// start the day before in case its hours pass midnight
+		for day := time.Date(y, m, d-1, 0, 0, 0, 0, loc); day.Before(input.End); day = day.AddDate(0, 0, 1) {
+			y, m, d := day.Date()
+			for _, span := range spans(day) {
+				tr := TimeRange{
+					Start: time.Date(y, m, d, 0, span[0], 0, 0, loc),
+					End:   time.Date(y, m, d, 0, span[1], 0, 0, loc),
+				}
+				if clip(&tr, input) {
+					result = append(result, &tr)
+				}
+			}
+		}
+
+		Merge(&result)
+		return result
+	}
+}
+
+// ohSpans returns the open spans of the day after applying every rule.
+func ohSpans(rules []*ohRule, day time.Time) [][2]int {
+	var spans [][2]int
+
+	for _, r := range rules {
+		switch {
+		case !r.matches(day):
+		case r.off:
+			spans = nil
+		case r.additional:
+			spans = append(spans, r.spans...)
+		default:
+			spans = append([][2]int(nil), r.spans...)
+		}
+	}
+
+	return spans
+}
+
+// matches returns true if the rule selects the day.
+func (r *ohRule) matches(day time.Time) bool {
+	if r.months != 0 && r.months&(1<<uint(day.Month())) == 0 {
+		return false
+	}
+	if r.weeks != nil {
+		if _, week := day.ISOWeek(); !r.weeks[week] {
+			return false
+		}
+	}
+	if r.weekdays == nil {
+		return true
+	}
+
+	for _, w := range r.weekdays {
+		if getWeekdayDelta(w.from, day.Weekday()) > getWeekdayDelta(w.from, w.to) {
+			continue
+		}
+		if len(w.nth) == 0 {
+			return true
+		}
+		for _, n := range w.nth {
+			if n > 0 && (day.Day()-1)/7+1 == n {
+				return true
+			}
+			if n < 0 && (daysIn(day.Month(), day.Year())-day.Day())/7+1 == -n {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ohTokenize splits the value into words, numbers, times, strings and
+// punctuation.
+func ohTokenize(s string) []string {
+	var (
+		toks []string
+		rs   = []rune(s)
+	)
+
+	for i := 0; i < len(rs); {
+		switch r := rs[i]; {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsLetter(r):
+			j := i
+			for j < len(rs) && unicode.IsLetter(rs[j]) {
+				j++
+			}
+			toks = append(toks, string(rs[i:j]))
+			i = j
+		case unicode.IsDigit(r):
+			j := i
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == ':' && j+1 < len(rs) && unicode.IsDigit(rs[j+1])) {
+				j++
+			}
+			toks = append(toks, string(rs[i:j]))
+			i = j
+		case r == '"':
+			j := i + 1
+			for j < len(rs) && rs[j] != '"' {
+				j++
+			}
+			if j < len(rs) {
+				j++
+			}
+			toks = append(toks, string(rs[i:j]))
+			i = j
+		case r == '|' && i+1 < len(rs) && rs[i+1] == '|':
+			toks = append(toks, "||")
+			i += 2
+		default:
+			toks = append(toks, string(r))
+			i++
+		}
+	}
+
+	return toks
+}
+
+// ohParser parses the tokens of an opening_hours value.
+type ohParser struct {
+	toks []string
+	i    int
+}
+
+// peek returns the token n positions ahead, or an empty string at the end.
+func (p *ohParser) peek(n int) string {
+	if p.i+n < len(p.toks) {
+		return p.toks[p.i+n]
+	}
+	return ""
+}
+
+// next consumes and returns the next token.
+func (p *ohParser) next() string {
+	tok := p.peek(0)
+	p.i++
+	return tok
+}
+
+// errorf returns an error describing the current token.
+func (p *ohParser) errorf(format string, args ...interface{}) error {
+	tok := p.peek(0)
+	if tok == "" {
+		tok = "end of input"
+	}
+	return fmt.Errorf("opening_hours: "+format+" at %q", append(args, tok)...)
+}
+
+// parse returns the rules of the value.  Rules that only select holidays are
+// dropped.
+func (p *ohParser) parse() ([]*ohRule, error) {
+	var (
+		rules      []*ohRule
+		additional bool
+	)
+
+	for {
+		r, holiday, err := p.parseRule()
+		if err != nil {
+			return nil, err
+		}
+		if !holiday {
+			r.additional = additional
+			rules = append(rules, r)
+		}
+
+		switch tok := p.next(); tok {
+		case "":
+			return rules, nil
+		case ";":
+			additional = false
+		case ",":
+			additional = true
+		case "||":
+			p.i--
+			return nil, p.errorf("fallback rules are not supported")
+		default:
+			p.i--
+			return nil, p.errorf("unexpected token")
+		}
+	}
+}
+
+// parseRule parses the selectors, times and modifier of a rule.  Returns
+// true if the rule only selects holidays.
+func (p *ohParser) parseRule() (r *ohRule, holiday bool, err error) {
+	r = new(ohRule)
+
+	if p.peek(0) == "24" && p.peek(1) == "/" && p.peek(2) == "7" {
+		p.i += 3
+		r.spans = [][2]int{{0, 24 * 60}}
+		return r, false, p.parseModifier(r)
+	}
+
+	if ohMonth(p.peek(0)) > 0 {
+		if err := p.parseMonths(r); err != nil {
+			return nil, false, err
+		}
+	}
+	if strings.EqualFold(p.peek(0), "week") {
+		p.i++
+		if err := p.parseWeeks(r); err != nil {
+			return nil, false, err
+		}
+	}
+	if _, ok := ohWeekday(p.peek(0)); ok || ohHoliday(p.peek(0)) {
+		if holiday, err = p.parseWeekdays(r); err != nil {
+			return nil, false, err
+		}
+	}
+	if p.peek(0) == ":" {
+		p.i++
+	}
+
+	if ohTime(p.peek(0)) >= 0 {
+		if err := p.parseTimes(r); err != nil {
+			return nil, false, err
+		}
+	} else {
+		r.spans = [][2]int{{0, 24 * 60}}
+	}
+
+	return r, holiday, p.parseModifier(r)
+}
+
+// parseModifier parses the optional state and comment of a rule.
+func (p *ohParser) parseModifier(r *ohRule) error {
+	switch strings.ToLower(p.peek(0)) {
+	case "off", "closed":
+		p.i++
+		r.off = true
+	case "open":
+		p.i++
+	case "unknown":
+		return p.errorf("unknown state is not supported")
+	}
+
+	if strings.HasPrefix(p.peek(0), `"`) {
+		p.i++
+	}
+	return nil
+}
+
+// parseMonths parses a list of months and month ranges.
+func (p *ohParser) parseMonths(r *ohRule) error {
+	for {
+		from := ohMonth(p.next())
+		to := from
+		if p.peek(0) == "-" {
+			p.i++
+			if to = ohMonth(p.peek(0)); to == 0 {
+				return p.errorf("expected month")
+			}
+			p.i++
+		}
+
+		for m := from; ; m = m%12 + 1 {
+			r.months |= 1 << uint(m)
+			if m == to {
+				break
+			}
+		}
+
+		if p.peek(0) != "," || ohMonth(p.peek(1)) == 0 {
+			return nil
+		}
+		p.i++
+	}
+}
+
+// parseWeeks parses a list of ISO week numbers, ranges and stepped ranges.
+func (p *ohParser) parseWeeks(r *ohRule) error {
+	r.weeks = make([]bool, 54)
+
+	for {
+		from, err := p.parseNumber(1, 53)
+		if err != nil {
+			return err
+		}
+		to, step := from, 1
+		if p.peek(0) == "-" {
+			p.i++
+			if to, err = p.parseNumber(from, 53); err != nil {
+				return err
+			}
+			if p.peek(0) == "/" {
+				p.i++
+				if step, err = p.parseNumber(1, 53); err != nil {
+					return err
+				}
+			}
+		}
+
+		for w := from; w <= to; w += step {
+			r.weeks[w] = true
+		}
+
+		if p.peek(0) != "," || !isNumber(p.peek(1)) {
+			return nil
+		}
+		p.i++
+	}
+}
+
+// parseWeekdays parses a list of weekdays, weekday ranges and holidays.
+// Returns true if the list only has holidays.
+func (p *ohParser) parseWeekdays(r *ohRule) (bool, error) {
+	var holiday bool
+
+	for {
+		if ohHoliday(p.peek(0)) {
+			p.i++
+			holiday = true
+		} else {
+			from, _ := ohWeekday(p.next())
+			w := ohRange{from: from, to: from}
+			if p.peek(0) == "-" {
+				p.i++
+				to, ok := ohWeekday(p.peek(0))
+				if !ok {
+					return false, p.errorf("expected weekday")
+				}
+				p.i++
+				w.to = to
+			} else if p.peek(0) == "[" {
+				p.i++
+				nth, err := p.parseNth()
+				if err != nil {
+					return false, err
+				}
+				w.nth = nth
+			}
+			r.weekdays = append(r.weekdays, w)
+		}
+
+		if p.peek(0) != "," {
+			break
+		}
+		if _, ok := ohWeekday(p.peek(1)); !ok && !ohHoliday(p.peek(1)) {
+			break
+		}
+		p.i++
+	}
+
+	return holiday && r.weekdays == nil, nil
+}
+
+// parseNth parses the occurrences of a weekday within the month, as in
+// "[1,3]", "[1-2]" or "[-1]", after the opening bracket.
+func (p *ohParser) parseNth() ([]int, error) {
+	var nth []int
+
+	for {
+		sign := 1
+		if p.peek(0) == "-" {
+			p.i++
+			sign = -1
+		}
+		from, err := p.parseNumber(1, 5)
+		if err != nil {
+			return nil, err
+		}
+		to := from
+		if sign > 0 && p.peek(0) == "-" {
+			p.i++
+			if to, err = p.parseNumber(from, 5); err != nil {
+				return nil, err
+			}
+		}
+		for n := from; n <= to; n++ {
+			nth = append(nth, sign*n)
+		}
+
+		switch p.next() {
+		case ",":
+		case "]":
+			return nth, nil
+		default:
+			p.i--
+			return nil, p.errorf("expected \"]\"")
+		}
+	}
+}
+
+// parseTimes parses a list of time spans.
+func (p *ohParser) parseTimes(r *ohRule) error {
+	for {
+		from := ohTime(p.next())
+		if p.next() != "-" {
+			p.i--
+			return p.errorf("expected \"-\"")
+		}
+		to := ohTime(p.peek(0))
+		if to < 0 {
+			return p.errorf("expected time")
+		}
+		p.i++
+
+		if to <= from {
+			to += 24 * 60
+		}
+		r.spans = append(r.spans, [2]int{from, to})
+
+		if p.peek(0) != "," || ohTime(p.peek(1)) < 0 {
+			return nil
+		}
+		p.i++
+	}
+}
+
+// parseNumber parses an integer within the bounds.
+func (p *ohParser) parseNumber(lo, hi int) (int, error) {
+	n, err := strconv.Atoi(p.peek(0))
+	if err != nil || n < lo || n > hi {
+		return 0, p.errorf("expected a number from %d to %d", lo, hi)
+	}
+	p.i++
+	return n, nil
+}
+
+// isNumber returns true if the token is an integer.
+func isNumber(tok string) bool {
+	_, err := strconv.Atoi(tok)
+	return err == nil
+}
+
+// ohMonth returns the month of the abbreviation, or zero.
+func ohMonth(tok string) time.Month {
+	for m, s := range ohMonths {
+		if s != "" && strings.EqualFold(tok, s) {
+			return time.Month(m)
+		}
+	}
+	return 0
+}
+
+// ohWeekday returns the weekday of the abbreviation.
+func ohWeekday(tok string) (time.Weekday, bool) {
+	for d, s := range ohWeekdays {
+		if strings.EqualFold(tok, s) {
+			return time.Weekday(d), true
+		}
+	}
+	return 0, false
+}
+
+// ohHoliday returns true for public and school holidays.
+func ohHoliday(tok string) bool {
+	return tok == "PH" || tok == "SH"
+}
+
+// ohTime returns the minutes from midnight of an "hh:mm" time, or -1.
+func ohTime(tok string) int {
+	i := strings.IndexByte(tok, ':')
+	if i < 0 {
+		return -1
+	}
+	h, err1 := strconv.Atoi(tok[:i])
+	m, err2 := strconv.Atoi(tok[i+1:])
+	if err1 != nil || err2 != nil || h > 48 || m > 59 {
+		return -1
+	}
+	return h*60 + m
+}
+
+// FormatOpeningHours renders the expression as an OpenStreetMap
+// opening_hours value.  Only unions of weekdays, months, the nth weekday of
+// the month and times of day can be represented; other expressions return an
+// error.
+func FormatOpeningHours(e Expr) (string, error) {
+	type group struct {
+		selector string
+		days     uint8 // weekdays, or zero if the selector has months or nth
+		times    []string
+	}
+
+	var (
+		groups []*group
+		arms   []Expr
+		split  func(e Expr)
+	)
+	split = func(e Expr) {
+		if b, ok := unnamed(e).(*BinaryExpr); ok && b.Op == AND {
+			split(b.X)
+			split(b.Y)
+			return
+		}
+		arms = append(arms, unnamed(e))
+	}
+	split(e)
+
+	for _, arm := range arms {
+		sel, days, times, err := ohFormatArm(arm)
+		if err != nil {
+			return "", err
+		}
+
+		var g *group
+		for _, v := range groups {
+			if v.selector == sel {
+				g = v
+			}
+		}
+		if g == nil {
+			g = &group{selector: sel, days: days}
+			groups = append(groups, g)
+		}
+		g.times = append(g.times, times...)
+	}
+
+	var (
+		buf  strings.Builder
+		seen uint8
+	)
+	for i, g := range groups {
+		// a rule can only override the previous rules if it selects other days
+		if i > 0 {
+			if g.days != 0 && seen&g.days == 0 {
+				buf.WriteString("; ")
+			} else {
+				buf.WriteString(", ")
+			}
+		}
+		if g.days == 0 {
+			seen = 0x7f
+		}
+		seen |= g.days
+
+		switch {
+		case g.selector == "" && len(g.times) == 0:
+			buf.WriteString("24/7")
+		case len(g.times) == 0:
+			buf.WriteString(g.selector)
+		case g.selector == "":
+			buf.WriteString(strings.Join(g.times, ","))
+		default:
+			buf.WriteString(g.selector + " " + strings.Join(g.times, ","))
+		}
+	}
+
+	return buf.String(), nil
+}
+
+// ohFormatArm returns the selector, selected weekdays and times of an arm of a
+// union.  The weekdays are zero if the selector has months or occurrences.
+func ohFormatArm(arm Expr) (selector string, days uint8, times []string, err error) {
+	var (
+		month    time.Month
+		weekdays string
+		operands []Expr
+	)
+
+	var flatten func(e Expr)
+	flatten = func(e Expr) {
+		e = unnamed(e)
+		if b, ok := e.(*BinaryExpr); ok && b.Op == IN {
+			flatten(b.X)
+			flatten(b.Y)
+			return
+		}
+		operands = append(operands, e)
+	}
+	flatten(arm)
+
+	unsupported := func(e Expr) error {
+		return fmt.Errorf("opening_hours cannot represent %s", e)
+	}
+	setMonth := func(e Expr, m time.Month) error {
+		if month != 0 && month != m {
+			return unsupported(e)
+		}
+		month = m
+		return nil
+	}
+
+	days = 0x7f
+	for _, op := range operands {
+		switch o := op.(type) {
+		case *MonthExpr:
+			if err := setMonth(o, o.Month); err != nil {
+				return "", 0, nil, err
+			}
+		case *DayExpr:
+			if len(o.Args) != 0 {
+				return "", 0, nil, unsupported(o)
+			}
+		case *WeekdayExpr:
+			if weekdays != "" {
+				return "", 0, nil, unsupported(o)
+			}
+			weekdays = ohWeekdays[o.From]
+			if o.To != o.From {
+				weekdays += "-" + ohWeekdays[o.To]
+			}
+			days, _ = weekdaySet(o)
+		case *OrdinalExpr:
+			w, ok := unnamed(o.X).(*WeekdayExpr)
+			u, isMonth := o.Unit.(*MonthExpr)
+			if !ok || !isMonth || w.Span() != 1 || weekdays != "" || o.N < -5 || o.N > 5 {
+				return "", 0, nil, unsupported(o)
+			}
+			if err := setMonth(o, u.Month); err != nil {
+				return "", 0, nil, err
+			}
+			weekdays = ohWeekdays[w.From] + "[" + strconv.Itoa(o.N) + "]"
+			days = 0
+		case *TimeExpr:
+			if times != nil {
+				return "", 0, nil, unsupported(o)
+			}
+			if times, err = ohFormatTimes(o); err != nil {
+				return "", 0, nil, err
+			}
+		case *BinaryExpr:
+			// a union of times
+			if times != nil {
+				return "", 0, nil, unsupported(o)
+			}
+			if times, err = ohFormatTimes(unionArms(o, nil)...); err != nil {
+				return "", 0, nil, err
+			}
+		default:
+			return "", 0, nil, unsupported(o)
+		}
+	}
+
+	if month != 0 {
+		selector = ohMonths[month]
+		days = 0
+	}
+	if weekdays != "" {
+		selector = strings.TrimSpace(selector + " " + weekdays)
+	}
+	return selector, days, times, nil
+}
+
+// ohFormatTimes formats time expressions as opening_hours time spans.
+func ohFormatTimes(exprs ...Expr) ([]string, error) {
+	var times []string
+
+	for _, e := range exprs {
+		t, ok := unnamed(e).(*TimeExpr)
+		if !ok || t.From == t.To {
+			return nil, fmt.Errorf("opening_hours cannot represent %s", e)
+		}
+
+		to := t.To[:2] + ":" + t.To[2:]
+		if t.To == "0000" {
+			to = "24:00"
+		}
+		times = append(times, t.From[:2]+":"+t.From[2:]+"-"+to)
+	}
+
+	return times, nil
+}
+
+// unnamed returns the expression referenced by a named expression.
+func unnamed(e Expr) Expr {
+	for {
+		n, ok := e.(*NamedExpr)
+		if !ok {
+			return e
+		}
+		e = n.X
+	}
+}
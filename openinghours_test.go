@@ -0,0 +1,114 @@
+package timewarp_test
+
+import (
+	"time"
+
+	"github.com/takeinitiative/timewarp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Opening hours", func() {
+	const datefmt = "01-02-06 15:04"
+
+	DescribeTable("ParseOpeningHours",
+		func(in, start, end string, expected ...string) {
+			f, err := timewarp.ParseOpeningHours(in)
+			Expect(err).NotTo(HaveOccurred())
+
+			r, _ := timewarp.Parse(datefmt, start, end)
+			var out []string
+			for _, v := range f(*r) {
+				out = append(out, v.Start.Format(datefmt)+" - "+v.End.Format(datefmt))
+			}
+			Expect(out).To(Equal(expected))
+		},
+		// 11-07-16 is a Monday
+		Entry("weekdays and saturday", `Mo-Fr 08:00-18:00; Sa 09:00-13:00; PH off`, "11-10-16 00:00", "11-14-16 00:00",
+			"11-10-16 08:00 - 11-10-16 18:00",
+			"11-11-16 08:00 - 11-11-16 18:00",
+			"11-12-16 09:00 - 11-12-16 13:00"),
+		Entry("override", `Mo-Fr 08:00-18:00; We 10:00-12:00`, "11-08-16 00:00", "11-10-16 00:00",
+			"11-08-16 08:00 - 11-08-16 18:00",
+			"11-09-16 10:00 - 11-09-16 12:00"),
+		Entry("additional rule", `Mo-Fr 08:00-12:00, We 14:00-16:00`, "11-09-16 00:00", "11-10-16 00:00",
+			"11-09-16 08:00 - 11-09-16 12:00",
+			"11-09-16 14:00 - 11-09-16 16:00"),
+		Entry("off", `Mo-Sa 10:00-20:00; Tu off`, "11-07-16 00:00", "11-09-16 00:00",
+			"11-07-16 10:00 - 11-07-16 20:00"),
+		Entry("time lists and weekday lists", `Mo,We 08:00-12:00,13:00-17:00`, "11-07-16 00:00", "11-09-16 00:00",
+			"11-07-16 08:00 - 11-07-16 12:00",
+			"11-07-16 13:00 - 11-07-16 17:00"),
+		Entry("past midnight", `Fr 22:00-02:00`, "11-11-16 00:00", "11-14-16 00:00",
+			"11-11-16 22:00 - 11-12-16 02:00"),
+		Entry("spills into the input", `Su 22:00-02:00`, "11-07-16 00:00", "11-08-16 00:00",
+			"11-07-16 00:00 - 11-07-16 02:00"),
+		Entry("first sunday", `Su[1] 10:00-12:00`, "11-01-16 00:00", "12-01-16 00:00",
+			"11-06-16 10:00 - 11-06-16 12:00"),
+		Entry("last sunday", `Su[-1] 10:00-12:00`, "11-01-16 00:00", "12-01-16 00:00",
+			"11-27-16 10:00 - 11-27-16 12:00"),
+		Entry("month ranges", `Nov-Dec Mo 10:00-11:00`, "10-30-16 00:00", "11-08-16 00:00",
+			"11-07-16 10:00 - 11-07-16 11:00"),
+		Entry("week numbers", `week 45 Mo-Fr 09:00-10:00`, "11-04-16 00:00", "11-08-16 00:00",
+			"11-07-16 09:00 - 11-07-16 10:00"),
+		Entry("whole days", `Sa-Su`, "11-11-16 00:00", "11-15-16 00:00",
+			"11-12-16 00:00 - 11-14-16 00:00"),
+		Entry("24/7", `24/7`, "11-11-16 00:00", "11-12-16 00:00",
+			"11-11-16 00:00 - 11-12-16 00:00"),
+		Entry("comments", `Mo 10:00-12:00 "by appointment"`, "11-07-16 00:00", "11-08-16 00:00",
+			"11-07-16 10:00 - 11-07-16 12:00"),
+	)
+
+	DescribeTable("Invalid",
+		func(in string) {
+			_, err := timewarp.ParseOpeningHours(in)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("fallback", `Mo-Fr 08:00-18:00 || "on call"`),
+		Entry("missing end", `Mo 08:00`),
+		Entry("bad weekday range", `Mo-Xx 08:00-12:00`),
+		Entry("bad week", `week 60 Mo`),
+		Entry("bad nth", `Su[6]`),
+		Entry("sunrise", `Mo sunrise-sunset`),
+	)
+
+	DescribeTable("FormatOpeningHours",
+		func(in, expected string) {
+			e, err := timewarp.ParseExprString(in)
+			Expect(err).NotTo(HaveOccurred())
+
+			s, err := timewarp.FormatOpeningHours(e)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(s).To(Equal(expected))
+
+			// the result must match the same time ranges
+			f, err := timewarp.ParseOpeningHours(s)
+			Expect(err).NotTo(HaveOccurred())
+			r := &timewarp.TimeRange{Start: time.Date(2016, 11, 6, 0, 0, 0, 0, time.UTC), End: time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)}
+			Expect(f(*r)).To(Equal(e.Filter()(*r)))
+		},
+		Entry("weekdays", `DAY MONDAY FRIDAY IN TIME 0800 1800 AND (DAY SATURDAY IN TIME 0900 1300)`, `Mo-Fr 08:00-18:00; Sa 09:00-13:00`),
+		Entry("grouped times", `DAY MONDAY IN TIME 0800 1200 AND (DAY MONDAY IN TIME 1300 1700)`, `Mo 08:00-12:00,13:00-17:00`),
+		Entry("time unions", `DAY MONDAY IN (TIME 0800 1200 AND TIME 1300 1700)`, `Mo 08:00-12:00,13:00-17:00`),
+		Entry("overlapping days", `DAY MONDAY FRIDAY IN TIME 0800 1200 AND (DAY FRIDAY IN TIME 1400 1600)`, `Mo-Fr 08:00-12:00, Fr 14:00-16:00`),
+		Entry("nth weekday", `DAY SUNDAY OF MONTH IN TIME 1000 1200`, `Su[1] 10:00-12:00`),
+		Entry("month", `MONTH DECEMBER IN DAY SATURDAY`, `Dec Sa`),
+		Entry("every day", `TIME 0900 0000`, `09:00-24:00`),
+	)
+
+	DescribeTable("Unrepresentable",
+		func(in string) {
+			e, err := timewarp.ParseExprString(in)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = timewarp.FormatOpeningHours(e)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("negation", `NOT DAY SUNDAY`),
+		Entry("year", `YEAR 2020`),
+		Entry("day of the month", `DAY 15 OF MONTH`),
+		Entry("ordinal time", `TIME 0900 1000 OF 2 DAY`),
+	)
+})
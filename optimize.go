@@ -0,0 +1,272 @@
+package timewarp
+
+import "fmt"
+
+// Optimize returns a simplified expression that produces the same time
+// ranges as the original.  It eliminates double negation, drops duplicate
+// union arms, folds intersections of weekdays, times, months and years, merges
+// unions of weekdays and times into ranges, and evaluates year and month
+// restrictions first so the remaining expression only runs within them.
+func Optimize(e Expr) Expr {
+	return optimize(e, false)
+}
+
+// optimize simplifies the expression.  If fragments is true, the individual
+// ranges of the result are counted by an ordinal or passed as the input of a
+// relative expression, so rewrites that would merge them are skipped.
+func optimize(e Expr, fragments bool) Expr {
+	switch e := e.(type) {
+	case *NotExpr:
+		x := optimize(e.X, false)
+		if n, ok := x.(*NotExpr); ok && !fragments {
+			return n.X
+		}
+		return &NotExpr{NotPos: e.NotPos, X: x}
+	case *BinaryExpr:
+		if e.Op == AND {
+			x, y := optimize(e.X, fragments), optimize(e.Y, fragments)
+			if fragments {
+				return &BinaryExpr{Op: AND, OpPos: e.OpPos, X: x, Y: y}
+			}
+			return optimizeUnion(unionArms(&BinaryExpr{Op: AND, OpPos: e.OpPos, X: x, Y: y}, nil))
+		}
+		x, y := optimize(e.X, fragments || !isAbsolute(e.Y)), optimize(e.Y, fragments)
+		return optimizeIntersect(&BinaryExpr{Op: IN, OpPos: e.OpPos, X: x, Y: y}, fragments)
+	case *OrdinalExpr:
+		return &OrdinalExpr{X: optimize(e.X, true), OfPos: e.OfPos, N: e.N, Unit: e.Unit}
+	case *NamedExpr:
+		return &NamedExpr{NamePos: e.NamePos, Name: e.Name, X: optimize(e.X, fragments)}
+	default:
+		return e
+	}
+}
+
+// optimizeUnion removes duplicate arms and merges weekday and time arms.
+func optimizeUnion(arms []Expr) Expr {
+	var (
+		result   []Expr
+		seen     = make(map[string]bool)
+		weekdays []*WeekdayExpr
+		times    []*TimeExpr
+	)
+
+	for _, arm := range arms {
+		if s := arm.String(); seen[s] {
+			continue
+		} else {
+			seen[s] = true
+		}
+
+		switch a := arm.(type) {
+		case *WeekdayExpr:
+			if len(weekdays) == 0 {
+				result = append(result, a)
+			}
+			weekdays = append(weekdays, a)
+		case *TimeExpr:
+			if _, _, ok := timeSpan(a); !ok {
+				result = append(result, a)
+				break
+			}
+			if len(times) == 0 {
+				result = append(result, a)
+			}
+			times = append(times, a)
+		default:
+			result = append(result, a)
+		}
+	}
+
+	// replace the first weekday and time arms with the merged arms
+	var merged []Expr
+	for _, arm := range result {
+		switch {
+		case len(weekdays) > 0 && arm == Expr(weekdays[0]):
+			for _, w := range mergeWeekdays(weekdays) {
+				merged = append(merged, w)
+			}
+		case len(times) > 0 && arm == Expr(times[0]):
+			for _, t := range mergeTimes(times) {
+				merged = append(merged, t)
+			}
+		default:
+			merged = append(merged, arm)
+		}
+	}
+
+	e := merged[0]
+	for _, arm := range merged[1:] {
+		e = &BinaryExpr{Op: AND, OpPos: arm.Pos(), X: e, Y: arm}
+	}
+	return e
+}
+
+// mergeWeekdays merges weekday expressions that overlap or are adjacent into
+// weekday ranges.  Ranges are ordered by the first expression they contain.
+func mergeWeekdays(weekdays []*WeekdayExpr) []*WeekdayExpr {
+	var set uint8
+	for _, w := range weekdays {
+		s, _ := weekdaySet(w)
+		set |= s
+	}
+
+	if set == 0x7f {
+		w := weekdays[0]
+		return []*WeekdayExpr{{DayPos: w.DayPos, From: w.From, To: (w.From + 6) % 7}}
+	}
+
+	var result []*WeekdayExpr
+	for _, w := range weekdays {
+		if set&(1<<uint(w.From)) == 0 {
+			continue
+		}
+
+		// rewind to the first day of the run and consume it from the set
+		from := w.From
+		for set&(1<<uint((from+6)%7)) != 0 {
+			from = (from + 6) % 7
+		}
+		to := from
+		for set&(1<<uint((to+1)%7)) != 0 {
+			to = (to + 1) % 7
+		}
+		for d := from; ; d = (d + 1) % 7 {
+			set &^= 1 << uint(d)
+			if d == to {
+				break
+			}
+		}
+
+		result = append(result, &WeekdayExpr{DayPos: w.DayPos, From: from, To: to})
+	}
+	return result
+}
+
+// mergeTimes merges time expressions that overlap or are adjacent.  Ranges
+// are ordered by time of day.
+func mergeTimes(times []*TimeExpr) []*TimeExpr {
+	var (
+		result []*TimeExpr
+		spans  [][2]int
+	)
+
+	times = append([]*TimeExpr(nil), times...)
+	for _, t := range times {
+		from, to, _ := timeSpan(t)
+		spans = append(spans, [2]int{from, to})
+	}
+
+	for len(spans) > 0 {
+		// find the earliest span and grow it with everything it touches
+		first := 0
+		for i, s := range spans {
+			if s[0] < spans[first][0] {
+				first = i
+			}
+		}
+		cur, pos := spans[first], times[first].TimePos
+		spans = append(spans[:first], spans[first+1:]...)
+		times = append(times[:first], times[first+1:]...)
+
+		for grown := true; grown; {
+			grown = false
+			for i := 0; i < len(spans); i++ {
+				if s := spans[i]; s[0] <= cur[1] && cur[0] <= s[1] {
+					if s[1] > cur[1] {
+						cur[1] = s[1]
+					}
+					spans = append(spans[:i], spans[i+1:]...)
+					times = append(times[:i], times[i+1:]...)
+					grown = true
+					i--
+				}
+			}
+		}
+
+		result = append(result, &TimeExpr{TimePos: pos, From: formatMinutes(cur[0]), To: formatMinutes(cur[1])})
+	}
+	return result
+}
+
+// optimizeIntersect folds intersections of comparable expressions and moves
+// year and month restrictions to the left so they are evaluated first.
+func optimizeIntersect(e *BinaryExpr, fragments bool) Expr {
+	switch x := e.X.(type) {
+	case *YearExpr:
+		if y, ok := e.Y.(*YearExpr); ok && x.Year == y.Year {
+			return x
+		}
+	case *MonthExpr:
+		if y, ok := e.Y.(*MonthExpr); ok && x.Month == y.Month {
+			return x
+		}
+	case *WeekdayExpr:
+		if y, ok := e.Y.(*WeekdayExpr); ok && x.From == y.From {
+			if x.Span() <= y.Span() {
+				return x
+			}
+			return &WeekdayExpr{DayPos: x.DayPos, From: y.From, To: y.To}
+		}
+	case *TimeExpr:
+		if y, ok := e.Y.(*TimeExpr); ok {
+			x1, x2, xok := timeSpan(x)
+			y1, y2, yok := timeSpan(y)
+			if xok && yok && x1 < y2 && y1 < x2 {
+				if y1 > x1 {
+					x1 = y1
+				}
+				if y2 < x2 {
+					x2 = y2
+				}
+				return &TimeExpr{TimePos: x.TimePos, From: formatMinutes(x1), To: formatMinutes(x2)}
+			}
+		}
+	}
+
+	if !fragments && isNarrowing(e.Y) && !isNarrowing(e.X) && isAbsolute(e.X) {
+		return &BinaryExpr{Op: IN, OpPos: e.OpPos, X: e.Y, Y: e.X}
+	}
+	return e
+}
+
+// isNarrowing returns true for expressions that select a single year or
+// month.
+func isNarrowing(e Expr) bool {
+	switch e := e.(type) {
+	case *YearExpr:
+		return true
+	case *MonthExpr:
+		return e.Month > 0
+	}
+	return false
+}
+
+// isAbsolute returns true if the expression matches the same time ranges
+// regardless of where the input starts, so that it can be evaluated within
+// any narrower input.
+func isAbsolute(e Expr) bool {
+	switch e := e.(type) {
+	case *YearExpr, *MonthExpr, *TimeExpr:
+		return true
+	case *WeekdayExpr:
+		return e.Span() == 1
+	case *DayExpr:
+		return len(e.Args) == 0
+	case *NotExpr:
+		return isAbsolute(e.X)
+	case *NamedExpr:
+		return isAbsolute(e.X)
+	case *BinaryExpr:
+		return isAbsolute(e.X) && isAbsolute(e.Y)
+	case *OrdinalExpr:
+		// whole months are found regardless of the input
+		_, ok := e.Unit.(*MonthExpr)
+		return ok
+	}
+	return false
+}
+
+// formatMinutes formats the minutes of the day in the "1504" format.
+func formatMinutes(m int) string {
+	return fmt.Sprintf("%02d%02d", m/60, m%60)
+}
@@ -0,0 +1,80 @@
+package timewarp_test
+
+import (
+	"time"
+
+	"github.com/takeinitiative/timewarp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Optimize", func() {
+	const datefmt = "01-02-06"
+
+	DescribeTable("Optimized expressions",
+		func(in, optimized, start string) {
+			e, err := timewarp.ParseExprString(in)
+			Expect(err).NotTo(HaveOccurred())
+
+			o := timewarp.Optimize(e)
+			Expect(o.String()).To(Equal(optimized))
+
+			// both expressions must produce the same time ranges, also when
+			// the input starts in the middle of a range
+			r, _ := timewarp.Parse(datefmt, start, "01-01-21")
+			for i := 0; i < 14; i++ {
+				in := timewarp.TimeRange{Start: r.Start.Add(time.Duration(i) * 12 * time.Hour), End: r.End}
+				expected, actual := e.Filter()(in), o.Filter()(in)
+				timewarp.Merge(&expected)
+				timewarp.Merge(&actual)
+				Expect(actual).To(Equal(expected), "from %s", in.Start)
+			}
+		},
+		Entry("double negation",
+			`NOT NOT DAY MONDAY`, `DAY MONDAY`, "01-01-18"),
+		Entry("nested double negation",
+			`DAY MONDAY FRIDAY IN NOT NOT NOT DAY TUESDAY`, `DAY MONDAY FRIDAY IN NOT DAY TUESDAY`, "01-01-18"),
+		Entry("duplicate arms",
+			`MONTH JUNE AND YEAR 2019 AND MONTH JUNE`, `MONTH JUNE AND YEAR 2019`, "01-01-18"),
+		Entry("adjacent weekdays",
+			`DAY MONDAY AND DAY TUESDAY`, `DAY MONDAY TUESDAY`, "01-02-18"),
+		Entry("overlapping weekdays",
+			`DAY MONDAY WEDNESDAY AND DAY TUESDAY THURSDAY AND DAY SATURDAY`, `DAY MONDAY THURSDAY AND DAY SATURDAY`, "01-03-18"),
+		Entry("weekdays across the weekend",
+			`DAY SUNDAY AND DAY FRIDAY SATURDAY AND DAY MONDAY`, `DAY FRIDAY MONDAY`, "01-07-18"),
+		Entry("every weekday",
+			`DAY WEDNESDAY SATURDAY AND DAY SUNDAY TUESDAY`, `DAY WEDNESDAY TUESDAY`, "01-04-18"),
+		Entry("weekdays from the same day",
+			`DAY MONDAY WEDNESDAY AND DAY SATURDAY AND DAY MONDAY TUESDAY AND YEAR 2019`, `DAY MONDAY WEDNESDAY AND DAY SATURDAY AND YEAR 2019`, "01-02-18"),
+		Entry("weekdays across the weekend from the same day",
+			`DAY SATURDAY AND DAY SATURDAY MONDAY`, `DAY SATURDAY MONDAY`, "01-07-18"),
+		Entry("adjacent times",
+			`TIME 0900 1200 AND TIME 1200 1300 AND TIME 1100 1700`, `TIME 0900 1700`, "01-01-18"),
+		Entry("separate times",
+			`TIME 1500 1700 AND TIME 0900 1200 AND TIME 1100 1300`, `TIME 0900 1300 AND TIME 1500 1700`, "01-01-18"),
+		Entry("times across midnight",
+			`TIME 2200 0200 AND TIME 0100 0300`, `TIME 2200 0200 AND TIME 0100 0300`, "01-01-18"),
+		Entry("time intersection",
+			`TIME 0900 1700 IN TIME 1200 1800`, `TIME 1200 1700`, "01-01-18"),
+		Entry("weekday intersection",
+			`DAY MONDAY FRIDAY IN DAY MONDAY WEDNESDAY`, `DAY MONDAY WEDNESDAY`, "01-01-18"),
+		Entry("year intersection",
+			`YEAR 2019 IN YEAR 2019`, `YEAR 2019`, "01-01-18"),
+		Entry("year restriction",
+			`DAY 5 OF MONTH JULY IN YEAR 2019`, `YEAR 2019 IN (DAY 5 OF MONTH JULY)`, "01-01-18"),
+		Entry("month restriction",
+			`DAY MONDAY IN TIME 0900 1700 IN MONTH JUNE`, `MONTH JUNE IN (DAY MONDAY IN TIME 0900 1700)`, "03-14-18"),
+		Entry("relative expressions are not reordered",
+			`DAY MONDAY FRIDAY IN YEAR 2019`, `DAY MONDAY FRIDAY IN YEAR 2019`, "01-01-18"),
+		Entry("ordinals count each arm",
+			`(DAY TUESDAY AND DAY WEDNESDAY AND DAY TUESDAY) OF 2 MONTH`, `DAY TUESDAY AND DAY WEDNESDAY AND DAY TUESDAY OF 2 MONTH`, "01-01-18"),
+		Entry("relative intersections count each arm",
+			`(DAY MONDAY AND DAY TUESDAY) IN DAY 2`, `DAY MONDAY AND DAY TUESDAY IN DAY 2`, "01-01-18"),
+		Entry("folds within ordinals",
+			`(TIME 0900 1700 IN TIME 1200 1800) OF 2 DAY`, `TIME 1200 1700 OF 2 DAY`, "01-01-18"),
+		Entry("README example",
+			`DAY MONDAY FRIDAY IN TIME 0500 1100 AND NOT NOT NOT DAY TUESDAY`, `DAY MONDAY FRIDAY IN TIME 0500 1100 AND NOT DAY TUESDAY`, "01-01-18"),
+	)
+})
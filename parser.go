@@ -14,10 +14,11 @@ const timefmt = "1504"
 // Parser represents a wrapper for scanner to add a buffer.
 // It provides a fixed-length circular buffer that can be unread.
 type Parser struct {
-	s   *Scanner
-	i   int // buffer index
-	n   int // buffer size
-	buf [3]struct {
+	s     *Scanner
+	names map[string]Expr
+	i     int // buffer index
+	n     int // buffer size
+	buf   [3]struct {
 		tok Token
 		pos Pos
 		lit string
@@ -29,14 +30,50 @@ func ParseString(s string) (Filter, error) {
 	return NewParser(bytes.NewBufferString(s)).Parse()
 }
 
+// ParseExprString returns the parsed expression for the provided string
+func ParseExprString(s string) (Expr, error) {
+	return NewParser(bytes.NewBufferString(s)).ParseExpr()
+}
+
 // NewParser instantiates a parser
 func NewParser(r io.Reader) *Parser {
 	return &Parser{s: NewScanner(r)}
 }
 
+// NewLocalizedParser instantiates a parser of the keywords of the locales, in
+// order of preference.
+func NewLocalizedParser(r io.Reader, locales ...*Locale) *Parser {
+	return &Parser{s: NewLocalizedScanner(r, locales...)}
+}
+
+// Define allows the expression to be referenced by name from the statements
+// parsed afterwards.  Names are case insensitive and cannot be keywords.  Names
+// of letters can be referenced bare, and any name can be referenced quoted, as
+// in "public holidays".
+func (p *Parser) Define(name string, e Expr) error {
+	if name == "" || p.s.lookup(name) != IDENT {
+		return fmt.Errorf("invalid name %q", name)
+	}
+
+	if p.names == nil {
+		p.names = make(map[string]Expr)
+	}
+	p.names[strings.ToLower(name)] = e
+	return nil
+}
+
 // Parse returns a filter for the provided statement
 func (p *Parser) Parse() (f Filter, err error) {
-	f, err = p.parseExpr()
+	e, err := p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return e.Filter(), nil
+}
+
+// ParseExpr returns the parsed expression for the provided statement
+func (p *Parser) ParseExpr() (e Expr, err error) {
+	e, err = p.parseExpr()
 	if err != nil {
 		return nil, err
 	}
@@ -50,11 +87,20 @@ func (p *Parser) Parse() (f Filter, err error) {
 
 // ParseFilter returns a filter for each individual statement.
 func (p *Parser) ParseFilter() (f Filter, err error) {
+	e, err := p.parseFilterExpr()
+	if err != nil {
+		return nil, err
+	}
+	return e.Filter(), nil
+}
+
+// parseFilterExpr returns the expression for each individual statement.
+func (p *Parser) parseFilterExpr() (e Expr, err error) {
 	// inspect the first token
 	tok, pos, lit := p.scanIgnoreWhitespace()
 	switch tok {
 	case LPAREN:
-		f, err = p.parseExpr()
+		e, err = p.parseExpr()
 		if err != nil {
 			return nil, err
 		}
@@ -64,31 +110,41 @@ func (p *Parser) ParseFilter() (f Filter, err error) {
 		}
 		return
 	case NOT:
-		f, err = p.ParseFilter()
+		x, err := p.parseFilterExpr()
 		if err != nil {
 			return nil, err
 		}
-		f = f.Negate()
-		return
+		return &NotExpr{NotPos: pos, X: x}, nil
 	case YEAR:
-		return p.parseYearFilter()
+		return p.parseYearExpr(pos)
 	case MONTH:
-		return p.parseMonthFilter(0)
+		return p.parseMonthExpr(pos)
 	case WEEK:
-		return p.parseWeekFilter(0)
+		return p.parseWeekExpr(pos)
 	case DAY:
-		return p.parseDayFilter(0)
+		return p.parseDayExpr(pos, 0)
 	case TIME:
-		return p.parseTimeFilter()
+		return p.parseTimeExpr(pos)
+	case IDENT, STRING:
+		if e, ok := p.names[strings.ToLower(lit)]; ok {
+			return &NamedExpr{NamePos: pos, Name: lit, X: e}, nil
+		} else if tok == STRING {
+			return nil, &ParseError{
+				Message: fmt.Sprintf("undefined name %q", lit),
+				Pos:     pos,
+			}
+		}
+		fallthrough
 	default:
 		return nil, newParseError(tokstr(tok, lit), []string{"(", "NOT", "YEAR", "MONTH", "WEEK", "DAY", "TIME"}, pos)
 	}
 }
 
-// parseExpr returns the resulting filter from joining multiple filters.
-func (p *Parser) parseExpr() (f Filter, err error) {
+// parseExpr returns the resulting expression from joining multiple
+// statements.
+func (p *Parser) parseExpr() (e Expr, err error) {
 	// read the first statement
-	f, err = p.ParseFilter()
+	e, err = p.parseFilterExpr()
 	if err != nil {
 		return nil, err
 	}
@@ -97,23 +153,18 @@ func (p *Parser) parseExpr() (f Filter, err error) {
 		tok, pos, lit := p.scanIgnoreWhitespace()
 		switch tok {
 		case EOF:
-			return f, nil
+			return e, nil
 		case RPAREN:
 			p.unscan()
-			return f, nil
-		case AND:
-			filter, err := p.ParseFilter()
+			return e, nil
+		case AND, IN:
+			y, err := p.parseFilterExpr()
 			if err != nil {
 				return nil, err
 			}
-			f = f.Union(filter)
-		case IN:
-			filter, err := p.ParseFilter()
-			if err != nil {
-				return nil, err
-			}
-			f = f.Intersect(filter)
+			e = &BinaryExpr{Op: tok, OpPos: pos, X: e, Y: y}
 		case OF:
+			ofPos := pos
 			tok, pos, lit = p.scanIgnoreWhitespace()
 
 			var v = 1
@@ -134,42 +185,37 @@ func (p *Parser) parseExpr() (f Filter, err error) {
 			} else {
 				p.unscan()
 			}
-			filter, err := p.parseOrdinal(v)
+			unit, err := p.parseOrdinal(v)
 			if err != nil {
 				return nil, err
 			}
-			f = f.Ordinal(v, filter)
+			e = &OrdinalExpr{X: e, OfPos: ofPos, N: v, Unit: unit}
 		default:
 			return nil, newParseError(tokstr(tok, lit), []string{"AND", "IN", "OF"}, pos)
 		}
 	}
 }
 
-// parseOrdinal handles sub-filter values under token "OF"
-func (p *Parser) parseOrdinal(v int) (f Filter, err error) {
+// parseOrdinal handles sub-expression values under token "OF"
+func (p *Parser) parseOrdinal(v int) (e Expr, err error) {
 	// inspect the first token
 	tok, pos, lit := p.scanIgnoreWhitespace()
 	switch tok {
 	case MONTH:
-		return p.parseMonthFilter(v)
+		return p.parseMonthExpr(pos)
 	case WEEK:
-		return p.parseWeekFilter(v)
+		return p.parseWeekExpr(pos)
 	case DAY:
-		return p.parseDayFilter(v)
+		return p.parseDayExpr(pos, v)
 	case RANGE:
-		return p.parseRangeFilter()
+		return &RangeExpr{RangePos: pos}, nil
 	default:
 		return nil, newParseError(tokstr(tok, lit), []string{"MONTH", "WEEK", "DAY", "RANGE"}, pos)
 	}
 }
 
-// parseRangeFilter is a no-op for ordinal calculations
-func (p *Parser) parseRangeFilter() (f Filter, err error) {
-	return Range().Filter(), nil
-}
-
-// parseYearFilter returns a filter for a given year
-func (p *Parser) parseYearFilter() (f Filter, err error) {
+// parseYearExpr returns an expression for a given year
+func (p *Parser) parseYearExpr(yearPos Pos) (e Expr, err error) {
 	tok, pos, lit := p.scanIgnoreWhitespace()
 	switch tok {
 	case IDENT:
@@ -186,7 +232,7 @@ func (p *Parser) parseYearFilter() (f Filter, err error) {
 			}
 		}
 
-		return Year(v).Filter(), nil
+		return &YearExpr{YearPos: yearPos, Year: v}, nil
 	default:
 		return nil, &ParseError{
 			Message: "missing year",
@@ -195,8 +241,8 @@ func (p *Parser) parseYearFilter() (f Filter, err error) {
 	}
 }
 
-// parseMonthFilter returns a filter for a given month
-func (p *Parser) parseMonthFilter(v int) (f Filter, err error) {
+// parseMonthExpr returns an expression for a given month
+func (p *Parser) parseMonthExpr(monthPos Pos) (e Expr, err error) {
 	tok, _, _ := p.scanIgnoreWhitespace()
 
 	var m time.Month
@@ -206,14 +252,11 @@ func (p *Parser) parseMonthFilter(v int) (f Filter, err error) {
 		p.unscan()
 	}
 
-	if v != 0 {
-		return TheMonth(m).Filter(), nil
-	}
-	return Month(m).Filter(), nil
+	return &MonthExpr{MonthPos: monthPos, Month: m}, nil
 }
 
-// parseWeekFilter returns a filter for the given week
-func (p *Parser) parseWeekFilter(v int) (f Filter, err error) {
+// parseWeekExpr returns an expression for the given week
+func (p *Parser) parseWeekExpr(weekPos Pos) (e Expr, err error) {
 	tok, _, _ := p.scanIgnoreWhitespace()
 
 	var w time.Weekday
@@ -224,14 +267,11 @@ func (p *Parser) parseWeekFilter(v int) (f Filter, err error) {
 		w = -1
 	}
 
-	if v > 0 {
-		return TheWeek(w, 7, -v+1, 2*v-1).Filter(), nil
-	}
-	return Week(w, 7).Filter(), nil
+	return &WeekExpr{WeekPos: weekPos, Weekday: w}, nil
 }
 
-// parseDayFilter returns a filter for the given day
-func (p *Parser) parseDayFilter(v int) (f Filter, err error) {
+// parseDayExpr returns an expression for the given day
+func (p *Parser) parseDayExpr(dayPos Pos, v int) (e Expr, err error) {
 	tok, pos, lit := p.scanIgnoreWhitespace()
 	if tok == IDENT {
 		d, err := strconv.Atoi(lit)
@@ -252,19 +292,11 @@ func (p *Parser) parseDayFilter(v int) (f Filter, err error) {
 				}
 			}
 
-			if v != 0 {
-				return TheDays(d, n).Filter(), nil
-			}
-
-			return Days(d-1, n-d+1).Filter(), nil
+			return &DayExpr{DayPos: dayPos, Args: []int{d, n}}, nil
 		}
 
 		p.unscan()
-		if v != 0 {
-			return TheDays(d, 1).Filter(), nil
-		}
-
-		return Days(d-1, 1).Filter(), nil
+		return &DayExpr{DayPos: dayPos, Args: []int{d}}, nil
 
 	} else if tok.isDayOfWeek() {
 		if v != 0 {
@@ -275,64 +307,76 @@ func (p *Parser) parseDayFilter(v int) (f Filter, err error) {
 		}
 
 		var (
-			day   = getDayOfWeek(tok)
-			delta = 1
+			from = getDayOfWeek(tok)
+			to   = from
 		)
 
-		tok, pos, lit = p.scanIgnoreWhitespace()
+		tok, _, _ = p.scanIgnoreWhitespace()
 		if tok.isDayOfWeek() {
-			delta = getWeekdayDelta(day, getDayOfWeek(tok)) + 1
+			to = getDayOfWeek(tok)
 		} else {
 			p.unscan()
 		}
-		return Week(day, delta).Filter(), nil
+		return &WeekdayExpr{DayPos: dayPos, From: from, To: to}, nil
 	} else {
 		p.unscan()
-		if v != 0 {
-			return TheDays(-v+1, 2*v-1).Filter(), nil
-		}
-		return Days(0, 1).Filter(), nil
+		return &DayExpr{DayPos: dayPos}, nil
 	}
 }
 
-// parseTimeFilter returns a filter for the given time
-func (p *Parser) parseTimeFilter() (f Filter, err error) {
-	tok, pos, lit := p.scanIgnoreWhitespace()
-	if tok != IDENT {
-		return nil, newParseError(tokstr(tok, lit), []string{"IDENT"}, pos)
+// parseTimeExpr returns an expression for the given time
+func (p *Parser) parseTimeExpr(timePos Pos) (e Expr, err error) {
+	t1, err := p.parseClock()
+	if err != nil {
+		return nil, err
 	}
 
-	if _, err := time.Parse(timefmt, lit); err != nil {
-		return nil, &ParseError{
-			Message: "invalid time format",
-			Pos:     pos,
-		}
+	t2, err := p.parseClock()
+	if err != nil {
+		return nil, err
 	}
-	t1 := lit
 
-	tok, pos, lit = p.scanIgnoreWhitespace()
+	return &TimeExpr{TimePos: timePos, From: t1, To: t2}, nil
+}
+
+// parseClock returns a time of day written as 1504 or 15:04, in the canonical
+// 1504 form.
+func (p *Parser) parseClock() (string, error) {
+	tok, pos, lit := p.scanIgnoreWhitespace()
 	if tok != IDENT {
-		return nil, newParseError(tokstr(tok, lit), []string{"IDENT"}, pos)
+		return "", newParseError(tokstr(tok, lit), []string{"IDENT"}, pos)
+	}
+
+	if tok, _, _ := p.scan(); tok == COLON {
+		tok, mpos, min := p.scan()
+		if tok != IDENT {
+			return "", newParseError(tokstr(tok, min), []string{"IDENT"}, mpos)
+		}
+		if len(lit) == 1 {
+			lit = "0" + lit
+		}
+		lit += min
+	} else {
+		p.unscan()
 	}
 
 	if _, err := time.Parse(timefmt, lit); err != nil {
-		return nil, &ParseError{
+		return "", &ParseError{
 			Message: "invalid time format",
 			Pos:     pos,
 		}
 	}
-	t2 := lit
-
-	return Times(timefmt, t1, t2).Filter(), nil
+	return lit, nil
 }
 
-// scanIgnoreWhitespace scans the next non-whitespace token.
+// scanIgnoreWhitespace scans the next token that is neither whitespace nor a
+// comment.
 func (p *Parser) scanIgnoreWhitespace() (tok Token, pos Pos, lit string) {
-	tok, pos, lit = p.scan()
-	if tok == WS {
-		tok, pos, lit = p.scan()
+	for {
+		if tok, pos, lit = p.scan(); tok != WS && tok != COMMENT {
+			return
+		}
 	}
-	return
 }
 
 // scan returns the next token from the underlying scanner.
@@ -4,7 +4,7 @@ import (
 	"bytes"
 	"time"
 
-	. "github.com/takeinitiative/timewarp"
+	"github.com/takeinitiative/timewarp"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -17,20 +17,20 @@ var _ = Describe("Parser", func() {
 	)
 
 	var (
-		r      *TimeRange
+		r      *timewarp.TimeRange
 		in     string
-		out    Filter
-		result Filter
+		out    timewarp.Filter
+		result timewarp.Filter
 		err    error
 	)
 
 	BeforeEach(func() {
-		r, err = Parse(datefmt, "01-01-18", "01-01-19")
+		r, err = timewarp.Parse(datefmt, "01-01-18", "01-01-19")
 		Expect(err).ShouldNot(HaveOccurred())
 	})
 
 	JustBeforeEach(func() {
-		result, err = NewParser(bytes.NewBufferString(in)).Parse()
+		result, err = timewarp.NewParser(bytes.NewBufferString(in)).Parse()
 	})
 
 	AssertFilter := func() {
@@ -57,8 +57,8 @@ var _ = Describe("Parser", func() {
 	Context("First Monday, Wednesday and Friday of July", func() {
 		BeforeEach(func() {
 			in = `(day monday friday of month july) in not (day tuesday and day thursday)`
-			d1 := Week(time.Tuesday, 1).And(Week(time.Thursday, 1)).Negate()
-			out = Week(time.Monday, 5).Of(1, TheMonth(time.July)).Intersect(d1)
+			d1 := timewarp.Week(time.Tuesday, 1).And(timewarp.Week(time.Thursday, 1)).Negate()
+			out = timewarp.Week(time.Monday, 5).Of(1, timewarp.TheMonth(time.July)).Intersect(d1)
 		})
 		AssertFilter()
 	})
@@ -67,9 +67,9 @@ var _ = Describe("Parser", func() {
 		BeforeEach(func() {
 			in = `(DAY 2 OF MONTH JULY) and (DAY 4 OF MONTH JULY) and (DAY 6 oF MONTH JULY)`
 
-			d1 := Days(1, 1).Of(1, TheMonth(time.July))
-			d2 := Days(3, 1).Of(1, TheMonth(time.July))
-			d3 := Days(5, 1).Of(1, TheMonth(time.July))
+			d1 := timewarp.Days(1, 1).Of(1, timewarp.TheMonth(time.July))
+			d2 := timewarp.Days(3, 1).Of(1, timewarp.TheMonth(time.July))
+			d3 := timewarp.Days(5, 1).Of(1, timewarp.TheMonth(time.July))
 
 			out = d1.Union(d2, d3)
 		})
@@ -79,7 +79,7 @@ var _ = Describe("Parser", func() {
 	Context("July 7 830a-1130p", func() {
 		BeforeEach(func() {
 			in = `day 7 of month july in time 0830 1130`
-			out = Days(6, 1).Of(1, TheMonth(time.July)).In(Times(timefmt, "0830", "1130"))
+			out = timewarp.Days(6, 1).Of(1, timewarp.TheMonth(time.July)).In(timewarp.Times(timefmt, "0830", "1130"))
 		})
 		AssertFilter()
 	})
@@ -88,10 +88,10 @@ var _ = Describe("Parser", func() {
 		BeforeEach(func() {
 			in = `(day monday and day saturday) and ((day tuesday and day thursday) in time 1300 0000) and ((day wednesday and day friday) in time 0000 1400) in not (week monday of month july)`
 
-			d1 := Week(time.Monday, 1).And(Week(time.Saturday, 1))
-			d2 := Week(time.Tuesday, 1).And(Week(time.Thursday, 1)).In(Times(timefmt, "1300", "0000"))
-			d3 := Week(time.Wednesday, 1).And(Week(time.Friday, 1)).In(Times(timefmt, "0000", "1400"))
-			d4 := Week(time.Monday, 7).Of(1, TheMonth(time.July)).Negate()
+			d1 := timewarp.Week(time.Monday, 1).And(timewarp.Week(time.Saturday, 1))
+			d2 := timewarp.Week(time.Tuesday, 1).And(timewarp.Week(time.Thursday, 1)).In(timewarp.Times(timefmt, "1300", "0000"))
+			d3 := timewarp.Week(time.Wednesday, 1).And(timewarp.Week(time.Friday, 1)).In(timewarp.Times(timefmt, "0000", "1400"))
+			d4 := timewarp.Week(time.Monday, 7).Of(1, timewarp.TheMonth(time.July)).Negate()
 			out = d1.Union(d2, d3).Intersect(d4)
 		})
 		AssertFilter()
@@ -100,9 +100,9 @@ var _ = Describe("Parser", func() {
 	Context("Sundays through Tuesdays, Wednesday through Saturdays except Thursdays before 4p, excluding July 10-17", func() {
 		BeforeEach(func() {
 			in = `day sunday tuesday and ((day wednesday saturday in not day thursday) in time 0000 1600) in not (day 10 17 of month july)`
-			d1 := Week(time.Sunday, 3).Filter()
-			d2 := Week(time.Wednesday, 4).Filter().Intersect(Week(time.Thursday, 1).Not()).In(Times(timefmt, "0000", "1600"))
-			d3 := Days(9, 8).Of(1, TheMonth(time.July)).Negate()
+			d1 := timewarp.Week(time.Sunday, 3).Filter()
+			d2 := timewarp.Week(time.Wednesday, 4).Filter().Intersect(timewarp.Week(time.Thursday, 1).Not()).In(timewarp.Times(timefmt, "0000", "1600"))
+			d3 := timewarp.Days(9, 8).Of(1, timewarp.TheMonth(time.July)).Negate()
 			out = d1.Union(d2).Intersect(d3)
 		})
 		AssertFilter()
@@ -111,7 +111,7 @@ var _ = Describe("Parser", func() {
 	Context("June 5th 2006", func() {
 		BeforeEach(func() {
 			in = `DAY 5 OF MONTH JUNE IN YEAR 2006`
-			out = Days(4, 1).Of(1, TheMonth(time.June)).In(Year(2006))
+			out = timewarp.Days(4, 1).Of(1, timewarp.TheMonth(time.June)).In(timewarp.Year(2006))
 		})
 		AssertFilter()
 	})
@@ -119,7 +119,7 @@ var _ = Describe("Parser", func() {
 	Context("Leap days", func() {
 		BeforeEach(func() {
 			in = `DAY 29 OF MONTH FEBRUARY`
-			out = Days(29, 1).Of(1, TheMonth(time.February))
+			out = timewarp.Days(29, 1).Of(1, timewarp.TheMonth(time.February))
 		})
 		AssertFilter()
 	})
@@ -127,7 +127,7 @@ var _ = Describe("Parser", func() {
 	Context("The second Tuesday of the month", func() {
 		BeforeEach(func() {
 			in = `DAY TUESDAY OF 2 MONTH`
-			out = Week(time.Tuesday, 1).Of(2, TheMonth(0))
+			out = timewarp.Week(time.Tuesday, 1).Of(2, timewarp.TheMonth(0))
 		})
 		AssertFilter()
 	})
@@ -135,7 +135,7 @@ var _ = Describe("Parser", func() {
 	Context("Mondays-Wednesdays, and Fridays from 4-6p", func() {
 		BeforeEach(func() {
 			in = `DAY MONDAY WEDNESDAY AND DAY FRIDAY IN TIME 1600 1800`
-			out = Week(time.Monday, 3).And(Week(time.Friday, 1)).In(Times(timefmt, "1600", "1800"))
+			out = timewarp.Week(time.Monday, 3).And(timewarp.Week(time.Friday, 1)).In(timewarp.Times(timefmt, "1600", "1800"))
 		})
 		AssertFilter()
 	})
@@ -143,8 +143,8 @@ var _ = Describe("Parser", func() {
 	Context("Sundays from 8-10a, Tuesdays from 4-9p", func() {
 		BeforeEach(func() {
 			in = `(DAY SUNDAY IN TIME 0800 1000) AND (DAY TUESDAY IN TIME 1600 2100)`
-			f1 := Week(time.Sunday, 1).In(Times(timefmt, "0800", "1000"))
-			f2 := Week(time.Tuesday, 1).In(Times(timefmt, "1600", "2100"))
+			f1 := timewarp.Week(time.Sunday, 1).In(timewarp.Times(timefmt, "0800", "1000"))
+			f2 := timewarp.Week(time.Tuesday, 1).In(timewarp.Times(timefmt, "1600", "2100"))
 			out = f1.Union(f2)
 		})
 		AssertFilter()
@@ -195,7 +195,7 @@ var _ = Describe("Parser", func() {
 	Context("Every three days", func() {
 		BeforeEach(func() {
 			in = `DAY OF 3 DAY`
-			out = Days(0, 1).Of(3, TheDays(-2, 5))
+			out = timewarp.Days(0, 1).Of(3, timewarp.TheDays(-2, 5))
 		})
 		AssertFilter()
 	})
@@ -210,7 +210,7 @@ var _ = Describe("Parser", func() {
 	Context("Three days on, 2 days off", func() {
 		BeforeEach(func() {
 			in = `DAY 1 3 OF DAY 0 5`
-			out = Days(0, 3).Of(1, TheDays(0, 5))
+			out = timewarp.Days(0, 3).Of(1, timewarp.TheDays(0, 5))
 		})
 		AssertFilter()
 	})
@@ -218,7 +218,7 @@ var _ = Describe("Parser", func() {
 	Context("First Monday from three days from now", func() {
 		BeforeEach(func() {
 			in = `DAY MONDAY OF DAY 3 7`
-			out = Week(time.Monday, 1).Of(1, TheDays(3, 7))
+			out = timewarp.Week(time.Monday, 1).Of(1, timewarp.TheDays(3, 7))
 		})
 		AssertFilter()
 	})
@@ -226,7 +226,7 @@ var _ = Describe("Parser", func() {
 	Context("The week, starting from Monday", func() {
 		BeforeEach(func() {
 			in = `WEEK MONDAY`
-			out = Week(time.Monday, 7).Filter()
+			out = timewarp.Week(time.Monday, 7).Filter()
 		})
 		AssertFilter()
 	})
@@ -234,7 +234,7 @@ var _ = Describe("Parser", func() {
 	Context("Tuesday, Wednesday, every 3 weeks", func() {
 		BeforeEach(func() {
 			in = `DAY TUESDAY WEDNESDAY OF 3 WEEK MONDAY`
-			out = Week(time.Tuesday, 2).Of(3, TheWeek(time.Monday, 7, -2, 5))
+			out = timewarp.Week(time.Tuesday, 2).Of(3, timewarp.TheWeek(time.Monday, 7, -2, 5))
 		})
 		AssertFilter()
 	})
@@ -242,7 +242,7 @@ var _ = Describe("Parser", func() {
 	Context("Tuesday, Wednesday every 3 weeks", func() {
 		BeforeEach(func() {
 			in = `DAY TUESDAY WEDNESDAY OF 3 WEEK`
-			out = Week(time.Tuesday, 2).Of(3, TheWeek(-1, 7, -2, 5))
+			out = timewarp.Week(time.Tuesday, 2).Of(3, timewarp.TheWeek(-1, 7, -2, 5))
 		})
 		AssertFilter()
 	})
@@ -250,7 +250,7 @@ var _ = Describe("Parser", func() {
 	Context("Fourth Thursday of the month", func() {
 		BeforeEach(func() {
 			in = `DAY THURSDAY OF 4 MONTH`
-			out = Week(time.Thursday, 1).Of(4, Month(0))
+			out = timewarp.Week(time.Thursday, 1).Of(4, timewarp.Month(0))
 		})
 		AssertFilter()
 	})
@@ -258,7 +258,7 @@ var _ = Describe("Parser", func() {
 	Context("June", func() {
 		BeforeEach(func() {
 			in = `MONTH JUNE`
-			out = Month(time.June).Filter()
+			out = timewarp.Month(time.June).Filter()
 		})
 		AssertFilter()
 	})
@@ -319,10 +319,55 @@ var _ = Describe("Parser", func() {
 		AssertError()
 	})
 
+	Context("Comments", func() {
+		BeforeEach(func() {
+			in = "# business hours\nDAY MONDAY FRIDAY -- weekdays\nIN TIME 0900 1700 # only"
+			out = timewarp.Week(time.Monday, 5).In(timewarp.Times(timefmt, "0900", "1700"))
+		})
+		AssertFilter()
+	})
+
+	Context("Times with colons", func() {
+		BeforeEach(func() {
+			in = `DAY MONDAY IN TIME 9:00 17:30`
+			out = timewarp.Week(time.Monday, 1).In(timewarp.Times(timefmt, "0900", "1730"))
+		})
+		AssertFilter()
+	})
+
+	Context("Invalid minutes", func() {
+		BeforeEach(func() {
+			in = `TIME 9:0 1700`
+		})
+		AssertError()
+	})
+
+	Context("Missing minutes", func() {
+		BeforeEach(func() {
+			in = `TIME 09: 1700`
+		})
+		AssertError()
+	})
+
+	Context("The last Friday of the month", func() {
+		BeforeEach(func() {
+			in = `DAY FRIDAY OF -1 MONTH`
+			out = timewarp.Week(time.Friday, 1).Of(-1, timewarp.TheMonth(0))
+		})
+		AssertFilter()
+	})
+
+	Context("Undefined quoted name", func() {
+		BeforeEach(func() {
+			in = `DAY MONDAY IN NOT "holidays"`
+		})
+		AssertError()
+	})
+
 	Context("Except the first Tuesday of March", func() {
 		BeforeEach(func() {
 			in = `NOT (DAY TUESDAY OF MONTH MARCH)`
-			out = (Week(time.Tuesday, 1).Of(1, TheMonth(time.March))).Negate()
+			out = (timewarp.Week(time.Tuesday, 1).Of(1, timewarp.TheMonth(time.March))).Negate()
 		})
 		AssertFilter()
 	})
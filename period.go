@@ -0,0 +1,281 @@
+package timewarp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Period is an ISO 8601 duration such as "P1Y2M10DT2H30M".  Calendar
+// components are kept separate from the time of day since months and years
+// vary in length.
+type Period struct {
+	Years  int
+	Months int
+	Weeks  int
+	Days   int
+	Time   time.Duration
+}
+
+// ParsePeriod parses an ISO 8601 duration.  Only the hours, minutes and
+// seconds may have a fraction.
+func ParsePeriod(s string) (Period, error) {
+	var p Period
+
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return p, fmt.Errorf("invalid duration %q", s)
+	}
+
+	var (
+		inTime bool
+		units  = "YMWD"
+		rest   = s[1:]
+	)
+	for len(rest) > 0 {
+		if rest[0] == 'T' {
+			if inTime || len(rest) == 1 {
+				return p, fmt.Errorf("invalid duration %q", s)
+			}
+			inTime, units, rest = true, "HMS", rest[1:]
+			continue
+		}
+
+		// read the number and its unit designator
+		i := strings.IndexFunc(rest, func(r rune) bool {
+			return (r < '0' || r > '9') && r != '.' && r != ','
+		})
+		if i <= 0 {
+			return p, fmt.Errorf("invalid duration %q", s)
+		}
+		num, unit := strings.Replace(rest[:i], ",", ".", 1), rest[i]
+		rest = rest[i+1:]
+
+		// designators must be in order and appear once
+		j := strings.IndexByte(units, unit)
+		if j < 0 {
+			return p, fmt.Errorf("invalid duration %q: unexpected %q", s, unit)
+		}
+		units = units[j+1:]
+
+		if inTime {
+			v, err := strconv.ParseFloat(num, 64)
+			if err != nil {
+				return p, fmt.Errorf("invalid duration %q", s)
+			}
+			switch unit {
+			case 'H':
+				p.Time += time.Duration(v * float64(time.Hour))
+			case 'M':
+				p.Time += time.Duration(v * float64(time.Minute))
+			case 'S':
+				p.Time += time.Duration(v * float64(time.Second))
+			}
+			continue
+		}
+
+		v, err := strconv.Atoi(num)
+		if err != nil {
+			return p, fmt.Errorf("invalid duration %q: only the time may have a fraction", s)
+		}
+		switch unit {
+		case 'Y':
+			p.Years = v
+		case 'M':
+			p.Months = v
+		case 'W':
+			p.Weeks = v
+		case 'D':
+			p.Days = v
+		}
+	}
+
+	return p, nil
+}
+
+// IsZero returns true if the period has no length.
+func (p Period) IsZero() bool {
+	return p == Period{}
+}
+
+// AddTo returns the time after the period.
+func (p Period) AddTo(t time.Time) time.Time {
+	return p.addTimes(t, 1)
+}
+
+// SubFrom returns the time before the period.
+func (p Period) SubFrom(t time.Time) time.Time {
+	return p.addTimes(t, -1)
+}
+
+// addTimes returns the time after n periods.  The calendar components are
+// added at once so that months are not clamped by each step.
+func (p Period) addTimes(t time.Time, n int) time.Time {
+	return t.AddDate(p.Years*n, p.Months*n, (p.Weeks*7+p.Days)*n).Add(p.Time * time.Duration(n))
+}
+
+// String returns the ISO 8601 representation of the period.
+func (p Period) String() string {
+	if p.IsZero() {
+		return "PT0S"
+	}
+
+	var buf strings.Builder
+	buf.WriteString("P")
+	for _, c := range []struct {
+		v    int
+		unit string
+	}{{p.Years, "Y"}, {p.Months, "M"}, {p.Weeks, "W"}, {p.Days, "D"}} {
+		if c.v != 0 {
+			buf.WriteString(strconv.Itoa(c.v) + c.unit)
+		}
+	}
+
+	if p.Time == 0 {
+		return buf.String()
+	}
+
+	buf.WriteString("T")
+	var (
+		h = p.Time / time.Hour
+		m = p.Time % time.Hour / time.Minute
+		s = p.Time % time.Minute
+	)
+	if h != 0 {
+		buf.WriteString(strconv.FormatInt(int64(h), 10) + "H")
+	}
+	if m != 0 {
+		buf.WriteString(strconv.FormatInt(int64(m), 10) + "M")
+	}
+	if s != 0 {
+		buf.WriteString(strconv.FormatFloat(s.Seconds(), 'f', -1, 64) + "S")
+	}
+	return buf.String()
+}
+
+// RepeatingInterval is an ISO 8601 repeating interval such as
+// "R5/2024-01-01T09:00Z/P1W": consecutive occurrences of the period from the
+// start.
+type RepeatingInterval struct {
+	// Repetitions is the number of occurrences, or negative if unbounded.
+	Repetitions int
+
+	// Start is the start of the first occurrence.
+	Start time.Time
+
+	// Period is the length of each occurrence.
+	Period Period
+}
+
+// ParseRepeatingInterval parses an ISO 8601 repeating interval.  The interval
+// may be given in any of the forms accepted by ParseInterval, but an
+// unbounded interval must have a start.
+func ParseRepeatingInterval(s string) (*RepeatingInterval, error) {
+	i := strings.IndexByte(s, '/')
+	if i < 0 || !strings.HasPrefix(s, "R") {
+		return nil, fmt.Errorf("invalid repeating interval %q", s)
+	}
+
+	var ri = RepeatingInterval{Repetitions: -1}
+	if i > 1 {
+		n, err := strconv.Atoi(s[1:i])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid repeating interval %q: bad repetitions", s)
+		}
+		ri.Repetitions = n
+	}
+
+	parts := strings.Split(s[i+1:], "/")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid repeating interval %q", s)
+	}
+
+	switch {
+	case strings.HasPrefix(parts[0], "P"):
+		// counts back from the end
+		if ri.Repetitions < 0 {
+			return nil, fmt.Errorf("invalid repeating interval %q: unbounded intervals must have a start", s)
+		}
+		p, err := ParsePeriod(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		end, err := ParseTime(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		ri.Start, ri.Period = p.addTimes(end, -ri.Repetitions), p
+	case strings.HasPrefix(parts[1], "P"):
+		p, err := ParsePeriod(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		start, err := ParseTime(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		ri.Start, ri.Period = start, p
+	default:
+		tr, err := ParseInterval(s[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		ri.Start, ri.Period = tr.Start, Period{Time: tr.Duration()}
+	}
+
+	if !ri.Period.AddTo(ri.Start).After(ri.Start) {
+		return nil, fmt.Errorf("invalid repeating interval %q: the period must be positive", s)
+	}
+	return &ri, nil
+}
+
+// String returns the ISO 8601 representation of the repeating interval.
+func (ri *RepeatingInterval) String() string {
+	r := "R"
+	if ri.Repetitions >= 0 {
+		r += strconv.Itoa(ri.Repetitions)
+	}
+	return r + "/" + ri.Start.Format(time.RFC3339Nano) + "/" + ri.Period.String()
+}
+
+// occurrence returns the nth occurrence of the interval.
+func (ri *RepeatingInterval) occurrence(n int) TimeRange {
+	return TimeRange{ri.Period.addTimes(ri.Start, n), ri.Period.addTimes(ri.Start, n+1)}
+}
+
+// Query returns a query that finds the first occurrence within the input.
+func (ri *RepeatingInterval) Query() Query {
+	return func(input TimeRange) *TimeRange {
+		if input.End.Before(ri.Start) || !ri.Period.AddTo(ri.Start).After(ri.Start) {
+			return nil
+		}
+
+		// estimate the occurrence from the length of the first one, then
+		// correct for the varying length of months
+		var n int
+		if first := ri.occurrence(0); input.Start.After(first.Start) {
+			n = int(input.Start.Sub(first.Start) / first.Duration())
+		}
+		for n > 0 && ri.occurrence(n).Start.After(input.Start) {
+			n--
+		}
+		for !ri.occurrence(n).End.After(input.Start) {
+			n++
+		}
+
+		if ri.Repetitions >= 0 && n >= ri.Repetitions {
+			return nil
+		}
+
+		output := ri.occurrence(n)
+		if !clip(&output, input) {
+			return nil
+		}
+		return &output
+	}
+}
+
+// Filter returns a filter of every occurrence within the input.
+func (ri *RepeatingInterval) Filter() Filter {
+	return ri.Query().Filter()
+}
@@ -0,0 +1,112 @@
+package timewarp_test
+
+import (
+	"time"
+
+	"github.com/takeinitiative/timewarp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Period", func() {
+	DescribeTable("Parsing",
+		func(in string, expected timewarp.Period, canonical string) {
+			p, err := timewarp.ParsePeriod(in)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(p).To(Equal(expected))
+			Expect(p.String()).To(Equal(canonical))
+		},
+		Entry("hours", "PT8H", timewarp.Period{Time: 8 * time.Hour}, "PT8H"),
+		Entry("weeks", "P1W", timewarp.Period{Weeks: 1}, "P1W"),
+		Entry("every component", "P1Y2M10DT2H30M", timewarp.Period{Years: 1, Months: 2, Days: 10, Time: 150 * time.Minute}, "P1Y2M10DT2H30M"),
+		Entry("fractional hours", "PT1.5H", timewarp.Period{Time: 90 * time.Minute}, "PT1H30M"),
+		Entry("decimal comma", "PT0,5S", timewarp.Period{Time: 500 * time.Millisecond}, "PT0.5S"),
+		Entry("zero", "PT0S", timewarp.Period{}, "PT0S"),
+	)
+
+	DescribeTable("Invalid",
+		func(in string) {
+			_, err := timewarp.ParsePeriod(in)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("empty", ""),
+		Entry("no components", "P"),
+		Entry("empty time", "P1DT"),
+		Entry("missing designator", "P1"),
+		Entry("out of order", "P1D1M"),
+		Entry("fractional days", "P1.5D"),
+		Entry("unknown designator", "P1X"),
+	)
+
+	It("should add calendar components at once", func() {
+		p := timewarp.Period{Months: 1, Time: time.Hour}
+		t := time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC)
+		Expect(p.AddTo(t)).To(Equal(time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC)))
+		Expect(p.SubFrom(t)).To(Equal(time.Date(2023, 12, 31, 8, 0, 0, 0, time.UTC)))
+	})
+})
+
+var _ = Describe("RepeatingInterval", func() {
+	var day = func(d, h int) time.Time {
+		return time.Date(2024, 1, d, h, 0, 0, 0, time.UTC)
+	}
+
+	DescribeTable("Parsing",
+		func(in string, expected timewarp.RepeatingInterval, canonical string) {
+			ri, err := timewarp.ParseRepeatingInterval(in)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*ri).To(Equal(expected))
+			Expect(ri.String()).To(Equal(canonical))
+		},
+		Entry("start and period", "R5/2024-01-01T09:00Z/P1W",
+			timewarp.RepeatingInterval{Repetitions: 5, Start: day(1, 9), Period: timewarp.Period{Weeks: 1}}, "R5/2024-01-01T09:00:00Z/P1W"),
+		Entry("unbounded", "R/2024-01-01T09:00Z/PT8H",
+			timewarp.RepeatingInterval{Repetitions: -1, Start: day(1, 9), Period: timewarp.Period{Time: 8 * time.Hour}}, "R/2024-01-01T09:00:00Z/PT8H"),
+		Entry("start and end", "R2/2024-01-01T09:00Z/2024-01-01T17:00Z",
+			timewarp.RepeatingInterval{Repetitions: 2, Start: day(1, 9), Period: timewarp.Period{Time: 8 * time.Hour}}, "R2/2024-01-01T09:00:00Z/PT8H"),
+		Entry("period and end", "R3/P1D/2024-01-04",
+			timewarp.RepeatingInterval{Repetitions: 3, Start: day(1, 0), Period: timewarp.Period{Days: 1}}, "R3/2024-01-01T00:00:00Z/P1D"),
+	)
+
+	DescribeTable("Invalid",
+		func(in string) {
+			_, err := timewarp.ParseRepeatingInterval(in)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("no repetition", "2024-01-01T09:00Z/P1W"),
+		Entry("bad repetitions", "Rx/2024-01-01T09:00Z/P1W"),
+		Entry("unbounded from the end", "R/P1D/2024-01-04"),
+		Entry("zero period", "R/2024-01-01T09:00Z/PT0S"),
+		Entry("missing period", "R5/2024-01-01T09:00Z"),
+	)
+
+	Describe("Filter", func() {
+		It("should find the occurrences within the input", func() {
+			ri, err := timewarp.ParseRepeatingInterval("R3/2024-01-01T09:00Z/P1D")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(ri.Filter()(timewarp.TimeRange{day(1, 12), day(10, 0)})).To(Equal([]*timewarp.TimeRange{
+				{Start: day(1, 12), End: day(2, 9)},
+				{Start: day(2, 9), End: day(3, 9)},
+				{Start: day(3, 9), End: day(4, 9)},
+			}))
+		})
+
+		It("should find occurrences of months", func() {
+			ri, err := timewarp.ParseRepeatingInterval("R/2024-01-31/P1M")
+			Expect(err).NotTo(HaveOccurred())
+
+			r := ri.Filter()(timewarp.TimeRange{time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)})
+			Expect(r).To(HaveLen(2))
+			Expect(r[0].End).To(Equal(time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC)))
+		})
+
+		It("should find nothing before the start", func() {
+			ri, err := timewarp.ParseRepeatingInterval("R/2024-01-01T09:00Z/P1D")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ri.Filter()(timewarp.TimeRange{day(1, 0), day(1, 9)})).To(BeEmpty())
+		})
+	})
+})
@@ -87,28 +87,30 @@ func TheMonth(month time.Month) Query {
 }
 
 // Week finds the time ranges for n consecutive days that start on the given
-// day.
+// day.  A range already in progress at the start of the input is found from
+// the start of the input.
 func Week(weekday time.Weekday, days int) Query {
 	return func(input TimeRange) *TimeRange {
 		var (
-			start time.Time
-			end   time.Time
-			delta int
+			start   time.Time
+			end     time.Time
+			elapsed int
 		)
 
 		if weekday >= 0 {
-			delta = getWeekdayDelta(input.Start.Weekday(), weekday)
+			elapsed = getWeekdayDelta(weekday, input.Start.Weekday())
 		}
-		if delta > 0 {
-			start = input.Start.AddDate(0, 0, delta).Truncate(24 * time.Hour)
-		} else {
+		if elapsed < days {
 			start = input.Start
+		} else {
+			start = input.Start.AddDate(0, 0, 7-elapsed).Truncate(24 * time.Hour)
+			elapsed = 0
 		}
 		if !start.Before(input.End) {
 			return nil
 		}
 
-		if end = start.AddDate(0, 0, days).Truncate(24 * time.Hour); end.After(input.End) {
+		if end = start.AddDate(0, 0, days-elapsed).Truncate(24 * time.Hour); end.After(input.End) {
 			end = input.End
 		}
 		return &TimeRange{Start: start, End: end}
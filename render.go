@@ -0,0 +1,281 @@
+package timewarp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// RenderOptions controls how calendars and timelines are drawn.
+type RenderOptions struct {
+	// Width is the number of columns available, or zero for 80.  Calendars
+	// place as many months side by side as fit and timelines divide days
+	// into as many slots as fit.
+	Width int
+
+	// Color highlights matched days and slots with ANSI escape sequences.
+	Color bool
+
+	// ASCII draws with ASCII characters only instead of Unicode symbols.
+	ASCII bool
+}
+
+const (
+	// defaultRenderWidth is the width of a terminal.
+	defaultRenderWidth = 80
+
+	// calendarWidth is the width of a month, seven cells of three columns.
+	calendarWidth = 21
+
+	// timelineLabel is the layout of the day labels of timelines.
+	timelineLabel = "Mon 2006-01-02 "
+
+	ansiReset   = "\x1b[0m"
+	ansiDim     = "\x1b[2m"
+	ansiPartial = "\x1b[32m"
+	ansiFull    = "\x1b[1;32m"
+)
+
+// timelineSlots are the numbers of slots a day may be divided into, finest
+// first.
+var timelineSlots = []int{96, 48, 24, 12, 8, 6, 4}
+
+// width returns the number of columns available.
+func (o RenderOptions) width() int {
+	if o.Width <= 0 {
+		return defaultRenderWidth
+	}
+	return o.Width
+}
+
+// marks returns the marks of partly and fully matched days.
+func (o RenderOptions) marks() (partial, full rune) {
+	if o.ASCII {
+		return '+', '#'
+	}
+	return '◐', '●'
+}
+
+// shades returns the characters of increasing coverage of a timeline slot.
+func (o RenderOptions) shades() []rune {
+	if o.ASCII {
+		return []rune(" .-=#")
+	}
+	return []rune(" ░▒▓█")
+}
+
+// paint wraps the text in the ANSI escape sequence if colours are enabled.
+func (o RenderOptions) paint(s, esc string) string {
+	if !o.Color || esc == "" {
+		return s
+	}
+	return esc + s + ansiReset
+}
+
+// RenderCalendar draws a calendar of each month of the window in the location
+// of its start, weeks starting on Monday.  Days partly matched by the filter
+// are marked ◐ and days fully matched ●, or + and # with ASCII.
+func RenderCalendar(w io.Writer, f Filter, window TimeRange, opts RenderOptions) error {
+	loc := window.Start.Location()
+	ranges := f.Within(window)
+
+	// months are laid out side by side, separated by a column
+	perRow := (opts.width() + 1) / (calendarWidth + 1)
+	if perRow < 1 {
+		perRow = 1
+	}
+
+	var months []time.Time
+	for m := time.Date(window.Start.Year(), window.Start.Month(), 1, 0, 0, 0, 0, loc); m.Before(window.End); m = m.AddDate(0, 1, 0) {
+		months = append(months, m)
+	}
+
+	bw := bufio.NewWriter(w)
+	for i := 0; i < len(months); i += perRow {
+		if i > 0 {
+			fmt.Fprintln(bw)
+		}
+
+		row := months[i:]
+		if len(row) > perRow {
+			row = row[:perRow]
+		}
+
+		blocks := make([][]string, len(row))
+		for j, m := range row {
+			blocks[j] = calendarMonth(m, ranges, window, opts)
+		}
+		for line := 0; line < 8; line++ {
+			var buf strings.Builder
+			for j, b := range blocks {
+				if j > 0 {
+					buf.WriteByte(' ')
+				}
+				if line < len(b) {
+					buf.WriteString(b[line])
+				} else {
+					buf.WriteString(strings.Repeat(" ", calendarWidth))
+				}
+			}
+			if s := strings.TrimRight(buf.String(), " "); s != "" {
+				fmt.Fprintln(bw, s)
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// calendarMonth returns the lines of the month, each padded to the width of a
+// month.
+func calendarMonth(month time.Time, ranges []*TimeRange, window TimeRange, opts RenderOptions) []string {
+	partial, full := opts.marks()
+	lines := []string{
+		pad(month.Format("January 2006"), calendarWidth),
+		"Mo Tu We Th Fr Sa Su ",
+	}
+
+	// Monday is the first column
+	var buf strings.Builder
+	buf.WriteString(strings.Repeat("   ", (int(month.Weekday())+6)%7))
+
+	next := month.AddDate(0, 1, 0)
+	for day := month; day.Before(next); day = day.AddDate(0, 0, 1) {
+		end := day.AddDate(0, 0, 1)
+		num, mark, esc := fmt.Sprintf("%2d", day.Day()), ' ', ""
+
+		switch covered := coverage(ranges, day, end); {
+		case !day.Before(window.End) || !end.After(window.Start):
+			esc = ansiDim
+		case covered == end.Sub(day):
+			mark, esc = full, ansiFull
+		case covered > 0:
+			mark, esc = partial, ansiPartial
+		}
+		buf.WriteString(opts.paint(num, esc))
+		buf.WriteRune(mark)
+
+		if day.Weekday() == time.Sunday || end.Equal(next) {
+			line := buf.String()
+			lines = append(lines, line+strings.Repeat(" ", calendarWidth-visibleWidth(line)))
+			buf.Reset()
+		}
+	}
+	return lines
+}
+
+// RenderTimeline draws a row for each day of the window in the location of
+// its start, with the hours of the day divided into as many slots as fit the
+// width.  Each slot is shaded by how much of it the filter matches, from ░ to
+// █, or . to # with ASCII.
+func RenderTimeline(w io.Writer, f Filter, window TimeRange, opts RenderOptions) error {
+	loc := window.Start.Location()
+	ranges := f.Within(window)
+	shades := opts.shades()
+
+	n := timelineSlots[len(timelineSlots)-1]
+	for _, slots := range timelineSlots {
+		if len(timelineLabel)+slots <= opts.width() {
+			n = slots
+			break
+		}
+	}
+	minutes := 24 * 60 / n
+
+	bw := bufio.NewWriter(w)
+
+	// label every six hours where the labels fit
+	header := []byte(strings.Repeat(" ", n))
+	for h, free := 0, 0; h < 24; h += 6 {
+		if col := h * n / 24; col >= free && col+2 <= n {
+			copy(header[col:], fmt.Sprintf("%02d", h))
+			free = col + 3
+		}
+	}
+	fmt.Fprintf(bw, "%s%s\n", strings.Repeat(" ", len(timelineLabel)), strings.TrimRight(string(header), " "))
+
+	for day := time.Date(window.Start.Year(), window.Start.Month(), window.Start.Day(), 0, 0, 0, 0, loc); day.Before(window.End); day = day.AddDate(0, 0, 1) {
+		var buf strings.Builder
+		buf.WriteString(day.Format(timelineLabel))
+
+		for i := 0; i < n; i++ {
+			// slots are in wall clock time, so days changing to or from
+			// daylight saving time have shorter or longer slots
+			start := time.Date(day.Year(), day.Month(), day.Day(), 0, i*minutes, 0, 0, loc)
+			end := time.Date(day.Year(), day.Month(), day.Day(), 0, (i+1)*minutes, 0, 0, loc)
+
+			shade := 0
+			if d := end.Sub(start); d > 0 {
+				covered := coverage(ranges, start, end)
+				switch {
+				case covered == d:
+					shade = len(shades) - 1
+				case covered > 0:
+					shade = 1 + int(int64(len(shades)-2)*int64(covered)/int64(d))
+				}
+			}
+
+			esc := ""
+			if shade > 0 {
+				esc = ansiPartial
+			}
+			buf.WriteString(opts.paint(string(shades[shade]), esc))
+		}
+		fmt.Fprintln(bw, strings.TrimRight(buf.String(), " "))
+	}
+
+	fmt.Fprintf(bw, "each column is %s\n", slotDuration(minutes))
+	return bw.Flush()
+}
+
+// coverage returns how much of [start, end) the sorted ranges cover.
+func coverage(ranges []*TimeRange, start, end time.Time) time.Duration {
+	var d time.Duration
+	for _, r := range ranges {
+		if !r.Start.Before(end) {
+			break
+		}
+		tr := *r
+		if clip(&tr, TimeRange{start, end}) {
+			d += tr.Duration()
+		}
+	}
+	return d
+}
+
+// slotDuration formats the minutes of a timeline slot.
+func slotDuration(minutes int) string {
+	if minutes%60 == 0 {
+		return fmt.Sprintf("%dh", minutes/60)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
+
+// pad pads the text with spaces to the width.
+func pad(s string, width int) string {
+	if n := visibleWidth(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+// visibleWidth returns the number of runes of the text, not counting ANSI
+// escape sequences.
+func visibleWidth(s string) int {
+	var (
+		n      int
+		escape bool
+	)
+	for _, r := range s {
+		switch {
+		case r == '\x1b':
+			escape = true
+		case escape:
+			escape = r != 'm'
+		default:
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,156 @@
+package timewarp_test
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/takeinitiative/timewarp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Render", func() {
+	var (
+		f      timewarp.Filter
+		window timewarp.TimeRange
+		opts   timewarp.RenderOptions
+		out    bytes.Buffer
+	)
+
+	at := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2018, month, day, hour, min, 0, 0, time.UTC)
+	}
+
+	lines := func(s ...string) string {
+		return strings.Join(s, "\n") + "\n"
+	}
+
+	BeforeEach(func() {
+		opts = timewarp.RenderOptions{}
+		out.Reset()
+	})
+
+	Context("RenderCalendar", func() {
+		BeforeEach(func() {
+			f = timewarp.Ranges(
+				&timewarp.TimeRange{at(time.March, 10, 0, 0), at(time.March, 11, 0, 0)},
+				&timewarp.TimeRange{at(time.March, 14, 9, 0), at(time.March, 14, 10, 0)},
+				&timewarp.TimeRange{at(time.April, 30, 20, 0), at(time.May, 2, 6, 0)},
+			)
+			window = timewarp.TimeRange{at(time.March, 1, 0, 0), at(time.May, 10, 0, 0)}
+		})
+
+		JustBeforeEach(func() {
+			Expect(timewarp.RenderCalendar(&out, f, window, opts)).To(Succeed())
+		})
+
+		It("should place the months side by side", func() {
+			Expect(out.String()).To(Equal(lines(
+				"March 2018            April 2018            May 2018",
+				"Mo Tu We Th Fr Sa Su  Mo Tu We Th Fr Sa Su  Mo Tu We Th Fr Sa Su",
+				"          1  2  3  4                     1      1● 2◐ 3  4  5  6",
+				" 5  6  7  8  9 10●11   2  3  4  5  6  7  8   7  8  9 10 11 12 13",
+				"12 13 14◐15 16 17 18   9 10 11 12 13 14 15  14 15 16 17 18 19 20",
+				"19 20 21 22 23 24 25  16 17 18 19 20 21 22  21 22 23 24 25 26 27",
+				"26 27 28 29 30 31     23 24 25 26 27 28 29  28 29 30 31",
+				"                      30◐",
+			)))
+		})
+
+		Context("narrow with ASCII", func() {
+			BeforeEach(func() {
+				window.End = at(time.April, 10, 0, 0)
+				opts = timewarp.RenderOptions{Width: 40, ASCII: true}
+			})
+
+			It("should stack the months", func() {
+				Expect(out.String()).To(Equal(lines(
+					"March 2018",
+					"Mo Tu We Th Fr Sa Su",
+					"          1  2  3  4",
+					" 5  6  7  8  9 10#11",
+					"12 13 14+15 16 17 18",
+					"19 20 21 22 23 24 25",
+					"26 27 28 29 30 31",
+					"",
+					"April 2018",
+					"Mo Tu We Th Fr Sa Su",
+					"                   1",
+					" 2  3  4  5  6  7  8",
+					" 9 10 11 12 13 14 15",
+					"16 17 18 19 20 21 22",
+					"23 24 25 26 27 28 29",
+					"30",
+				)))
+			})
+		})
+
+		Context("with colour", func() {
+			BeforeEach(func() {
+				opts = timewarp.RenderOptions{Color: true}
+			})
+
+			It("should highlight the matched days and dim the days outside the window", func() {
+				Expect(out.String()).To(ContainSubstring("\x1b[1;32m10\x1b[0m●"))
+				Expect(out.String()).To(ContainSubstring("\x1b[32m14\x1b[0m◐"))
+				Expect(out.String()).To(ContainSubstring("\x1b[2m10\x1b[0m"))
+			})
+		})
+	})
+
+	Context("RenderTimeline", func() {
+		BeforeEach(func() {
+			f = timewarp.Ranges(
+				&timewarp.TimeRange{at(time.March, 4, 22, 0), at(time.March, 5, 2, 0)},
+				&timewarp.TimeRange{at(time.March, 5, 9, 0), at(time.March, 5, 10, 15)},
+				&timewarp.TimeRange{at(time.March, 5, 23, 0), at(time.March, 6, 1, 0)},
+			)
+			window = timewarp.TimeRange{at(time.March, 5, 0, 0), at(time.March, 7, 0, 0)}
+			opts = timewarp.RenderOptions{Width: 39, ASCII: true}
+		})
+
+		JustBeforeEach(func() {
+			Expect(timewarp.RenderTimeline(&out, f, window, opts)).To(Succeed())
+		})
+
+		It("should shade the slots by coverage", func() {
+			Expect(out.String()).To(Equal(lines(
+				"               00    06    12    18",
+				"Mon 2018-03-05 ##       #.            #",
+				"Tue 2018-03-06 #",
+				"each column is 1h",
+			)))
+		})
+
+		Context("with the default width", func() {
+			BeforeEach(func() {
+				opts = timewarp.RenderOptions{}
+			})
+
+			It("should divide days into half hours", func() {
+				Expect(out.String()).To(HavePrefix(lines(
+					"               00          06          12          18",
+					"Mon 2018-03-05 ████              ██▒                         ██",
+				)))
+				Expect(out.String()).To(HaveSuffix("each column is 30m\n"))
+			})
+		})
+
+		Context("narrow", func() {
+			BeforeEach(func() {
+				opts.Width = 10
+			})
+
+			It("should fall back to the coarsest slots", func() {
+				Expect(out.String()).To(Equal(lines(
+					"               00",
+					"Mon 2018-03-05 -. .",
+					"Tue 2018-03-06 .",
+					"each column is 6h",
+				)))
+			})
+		})
+	})
+})
@@ -0,0 +1,245 @@
+package timewarp
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Booking is a reservation of one unit of a resource's capacity.
+type Booking struct {
+	ID    int
+	Range TimeRange
+}
+
+// CapacityRange is a time range annotated with the remaining capacity of a
+// resource.
+type CapacityRange struct {
+	Range     TimeRange
+	Remaining int
+}
+
+// MarshalJSON encodes the booking as an object of its ID and its start and
+// end times.
+func (b Booking) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		ID int `json:"id"`
+		jsonRange
+	}{b.ID, newJSONRange(b.Range)})
+}
+
+// UnmarshalJSON decodes an object of an ID and start and end times.
+func (b *Booking) UnmarshalJSON(data []byte) error {
+	var v struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := b.Range.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	b.ID = v.ID
+	return nil
+}
+
+// MarshalJSON encodes the range as an object of its start and end times and
+// the remaining capacity.
+func (r CapacityRange) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonRange
+		Remaining int `json:"remaining"`
+	}{newJSONRange(r.Range), r.Remaining})
+}
+
+// UnmarshalJSON decodes an object of start and end times and the remaining
+// capacity.
+func (r *CapacityRange) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Remaining int `json:"remaining"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := r.Range.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	r.Remaining = v.Remaining
+	return nil
+}
+
+// BookingError is returned when a time range cannot be booked.
+type BookingError struct {
+	Range TimeRange
+
+	// Conflicts are the bookings overlapping the range when the resource is
+	// fully booked, or nil when the resource is closed during the range.
+	Conflicts []Booking
+}
+
+// Error returns the string representation of the error.
+func (e *BookingError) Error() string {
+	if e.Conflicts == nil {
+		return fmt.Sprintf("resource is not open during %s", &e.Range)
+	}
+	return fmt.Sprintf("resource is fully booked during %s by %d booking(s)", &e.Range, len(e.Conflicts))
+}
+
+// Resource is a resource with a capacity that can be booked during its open
+// hours.  Its methods may be called concurrently.
+type Resource struct {
+	open     Filter
+	capacity int
+
+	mu       sync.Mutex
+	bookings []Booking
+	lastID   int
+}
+
+// NewResource returns a resource that is open during the results of the
+// filter, with capacity for as many concurrent bookings.
+func NewResource(open Filter, capacity int) *Resource {
+	return &Resource{open: open, capacity: capacity}
+}
+
+// Capacity returns the capacity of the resource.
+func (r *Resource) Capacity() int {
+	return r.capacity
+}
+
+// Book books the time range, which must be within a single open range.  A
+// *BookingError is returned if the resource is closed or fully booked during
+// any part of the range.
+func (r *Resource) Book(tr TimeRange) (Booking, error) {
+	if !tr.Start.Before(tr.End) {
+		return Booking{}, fmt.Errorf("invalid booking %s", &tr)
+	}
+	if !r.isOpen(tr) {
+		return Booking{}, &BookingError{Range: tr}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range r.usage(tr) {
+		if c.Remaining <= 0 {
+			return Booking{}, &BookingError{Range: tr, Conflicts: r.conflicts(tr)}
+		}
+	}
+
+	r.lastID++
+	b := Booking{ID: r.lastID, Range: tr}
+
+	i := sort.Search(len(r.bookings), func(i int) bool {
+		return tr.Start.Before(r.bookings[i].Range.Start)
+	})
+	r.bookings = append(r.bookings, Booking{})
+	copy(r.bookings[i+1:], r.bookings[i:])
+	r.bookings[i] = b
+	return b, nil
+}
+
+// Cancel cancels the booking with the ID.  Returns false if there is no such
+// booking.
+func (r *Resource) Cancel(id int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, b := range r.bookings {
+		if b.ID == id {
+			r.bookings = append(r.bookings[:i], r.bookings[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Bookings returns the bookings in order of their start.
+func (r *Resource) Bookings() []Booking {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Booking(nil), r.bookings...)
+}
+
+// Conflicts returns the bookings that overlap the time range, in order of
+// their start.
+func (r *Resource) Conflicts(tr TimeRange) []Booking {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.conflicts(tr)
+}
+
+// Available returns the open ranges of the resource within the window that
+// have remaining capacity.  The ranges are split where the remaining
+// capacity changes.
+func (r *Resource) Available(window TimeRange) []CapacityRange {
+	open := r.open.Within(window)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var result []CapacityRange
+	for _, o := range open {
+		for _, c := range r.usage(*o) {
+			if c.Remaining > 0 {
+				result = append(result, c)
+			}
+		}
+	}
+	return result
+}
+
+// isOpen returns true if the time range is within a single open range.
+func (r *Resource) isOpen(tr TimeRange) bool {
+	for _, o := range matchedRanges(r.open, TimeRange{tr.Start.AddDate(0, 0, -7), tr.End}) {
+		if !o.Start.After(tr.Start) && !o.End.Before(tr.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// conflicts returns the bookings overlapping the time range while holding
+// the lock.
+func (r *Resource) conflicts(tr TimeRange) []Booking {
+	result := []Booking{}
+	for _, b := range r.bookings {
+		if b.Range.Start.Before(tr.End) && tr.Start.Before(b.Range.End) {
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
+// usage splits the time range where the number of overlapping bookings
+// changes and returns the remaining capacity of each part, while holding the
+// lock.
+func (r *Resource) usage(tr TimeRange) []CapacityRange {
+	edges := []time.Time{tr.Start, tr.End}
+	for _, b := range r.bookings {
+		for _, t := range []time.Time{b.Range.Start, b.Range.End} {
+			if t.After(tr.Start) && t.Before(tr.End) {
+				edges = append(edges, t)
+			}
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].Before(edges[j]) })
+
+	var result []CapacityRange
+	for i := 0; i < len(edges)-1; i++ {
+		part := TimeRange{edges[i], edges[i+1]}
+		if !part.Start.Before(part.End) {
+			continue
+		}
+
+		remaining := r.capacity - len(r.conflicts(part))
+		if n := len(result); n > 0 && result[n-1].Remaining == remaining {
+			result[n-1].Range.End = part.End
+			continue
+		}
+		result = append(result, CapacityRange{part, remaining})
+	}
+	return result
+}
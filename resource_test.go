@@ -0,0 +1,144 @@
+package timewarp_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/takeinitiative/timewarp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Resource", func() {
+	var (
+		r  *timewarp.Resource
+		at = func(d, h int) time.Time {
+			// 11-07-16 is a Monday
+			return time.Date(2016, 11, d, h, 0, 0, 0, time.UTC)
+		}
+		book = func(d, from, to int) timewarp.Booking {
+			b, err := r.Book(timewarp.TimeRange{at(d, from), at(d, to)})
+			Expect(err).NotTo(HaveOccurred())
+			return b
+		}
+	)
+
+	BeforeEach(func() {
+		open, err := timewarp.ParseString(`DAY MONDAY FRIDAY IN TIME 0800 1800`)
+		Expect(err).NotTo(HaveOccurred())
+		r = timewarp.NewResource(open, 2)
+	})
+
+	It("should book up to the capacity", func() {
+		a := book(7, 9, 11)
+		b := book(7, 10, 12)
+		Expect(r.Bookings()).To(Equal([]timewarp.Booking{a, b}))
+
+		_, err := r.Book(timewarp.TimeRange{at(7, 10), at(7, 11)})
+		Expect(err).To(BeAssignableToTypeOf(&timewarp.BookingError{}))
+		Expect(err.(*timewarp.BookingError).Conflicts).To(Equal([]timewarp.Booking{a, b}))
+		Expect(err).To(MatchError("resource is fully booked during 2016-11-07T10:00:00Z/2016-11-07T11:00:00Z by 2 booking(s)"))
+
+		book(7, 11, 12)
+	})
+
+	It("should not book outside the open hours", func() {
+		_, err := r.Book(timewarp.TimeRange{at(7, 17), at(7, 19)})
+		Expect(err).To(MatchError("resource is not open during 2016-11-07T17:00:00Z/2016-11-07T19:00:00Z"))
+
+		_, err = r.Book(timewarp.TimeRange{at(12, 9), at(12, 10)})
+		Expect(err).To(BeAssignableToTypeOf(&timewarp.BookingError{}))
+	})
+
+	It("should reject empty ranges", func() {
+		_, err := r.Book(timewarp.TimeRange{at(7, 9), at(7, 9)})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should free the capacity of cancelled bookings", func() {
+		a := book(7, 9, 11)
+		book(7, 9, 11)
+
+		Expect(r.Cancel(a.ID)).To(BeTrue())
+		Expect(r.Cancel(a.ID)).To(BeFalse())
+		book(7, 9, 11)
+	})
+
+	It("should find the conflicts", func() {
+		a := book(7, 9, 11)
+		book(7, 11, 12)
+		Expect(r.Conflicts(timewarp.TimeRange{at(7, 8), at(7, 10)})).To(Equal([]timewarp.Booking{a}))
+		Expect(r.Conflicts(timewarp.TimeRange{at(7, 12), at(7, 13)})).To(BeEmpty())
+	})
+
+	It("should annotate the available ranges with the remaining capacity", func() {
+		book(7, 9, 11)
+		book(7, 10, 12)
+
+		Expect(r.Available(timewarp.TimeRange{at(7, 0), at(8, 10)})).To(Equal([]timewarp.CapacityRange{
+			{timewarp.TimeRange{at(7, 8), at(7, 9)}, 2},
+			{timewarp.TimeRange{at(7, 9), at(7, 10)}, 1},
+			{timewarp.TimeRange{at(7, 11), at(7, 12)}, 1},
+			{timewarp.TimeRange{at(7, 12), at(7, 18)}, 2},
+			{timewarp.TimeRange{at(8, 8), at(8, 10)}, 2},
+		}))
+	})
+
+	It("should marshal the ID of bookings and the remaining capacity", func() {
+		a := book(7, 9, 11)
+		b, err := json.Marshal(a)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(fmt.Sprintf(`{"id":%d,"start":"2016-11-07T09:00:00Z","end":"2016-11-07T11:00:00Z"}`, a.ID)))
+
+		var booking timewarp.Booking
+		Expect(json.Unmarshal(b, &booking)).To(Succeed())
+		Expect(booking).To(Equal(a))
+
+		remaining := timewarp.CapacityRange{timewarp.TimeRange{at(7, 9), at(7, 11)}, 1}
+		b, err = json.Marshal(remaining)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`{"start":"2016-11-07T09:00:00Z","end":"2016-11-07T11:00:00Z","remaining":1}`))
+
+		var capacity timewarp.CapacityRange
+		Expect(json.Unmarshal(b, &capacity)).To(Succeed())
+		Expect(capacity).To(Equal(remaining))
+	})
+
+	It("should not encode annotated ranges as bare time ranges", func() {
+		for _, v := range []interface{}{&timewarp.Booking{}, &timewarp.CapacityRange{}, &timewarp.LabeledRange{}, &timewarp.BucketCount{}} {
+			_, valuer := v.(driver.Valuer)
+			_, scanner := v.(sql.Scanner)
+			_, text := v.(encoding.TextMarshaler)
+			_, stringer := v.(fmt.Stringer)
+			Expect([]bool{valuer, scanner, text, stringer}).To(Equal([]bool{false, false, false, false}), "%T", v)
+		}
+	})
+
+	It("should not overbook concurrently", func() {
+		var (
+			wg     sync.WaitGroup
+			mu     sync.Mutex
+			booked int
+		)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				if _, err := r.Book(timewarp.TimeRange{at(7, 9), at(7, 10)}); err == nil {
+					mu.Lock()
+					booked++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		Expect(booked).To(Equal(2))
+	})
+})
@@ -4,20 +4,43 @@ import (
 	"bufio"
 	"bytes"
 	"io"
+	"strconv"
+	"unicode"
 )
 
 // Scanner represents a lexical scanner for timerangeQL
 type Scanner struct {
-	r *reader
+	r       *reader
+	locales []*Locale
+	last    Token // the last scanned token
 }
 
 // NewScanner initializes a new lexical scanner
 func NewScanner(r io.Reader) *Scanner {
-	return &Scanner{r: &reader{r: bufio.NewReader(r)}}
+	return NewLocalizedScanner(r)
 }
 
-// Scan returns the next token and the literal value
+// NewLocalizedScanner initializes a lexical scanner that recognizes the
+// keywords of the locales, in order of preference.  Without locales, the
+// canonical keywords are recognized.
+func NewLocalizedScanner(r io.Reader, locales ...*Locale) *Scanner {
+	if len(locales) == 0 {
+		locales = []*Locale{canonical}
+	}
+	return &Scanner{r: &reader{r: bufio.NewReader(r)}, locales: locales}
+}
+
+// Scan returns the next token and the literal value.  The literal of a
+// string is its unquoted value, and the literal of a comment includes the
+// comment marker.
 func (s *Scanner) Scan() (tok Token, pos Pos, lit string) {
+	tok, pos, lit = s.scan()
+	s.last = tok
+	return
+}
+
+// scan returns the next token and the literal value.
+func (s *Scanner) scan() (tok Token, pos Pos, lit string) {
 	// read the next rune
 	ch, pos := s.r.read()
 
@@ -39,6 +62,31 @@ func (s *Scanner) Scan() (tok Token, pos Pos, lit string) {
 		return LPAREN, pos, ""
 	case ')':
 		return RPAREN, pos, ""
+	case ':':
+		return COLON, pos, ""
+	case ',':
+		return COMMA, pos, ""
+	case ';':
+		return SEMICOLON, pos, ""
+	case '#':
+		return s.scanComment("#", pos)
+	case '"':
+		return s.scanString(pos)
+	case '-', '+':
+		// A sign before a digit begins a signed number, unless it directly
+		// follows an ident as in "2018-01".
+		next, _ := s.r.read()
+		switch {
+		case ch == '-' && next == '-':
+			return s.scanComment("--", pos)
+		case isDigit(next) && s.last != IDENT:
+			s.r.unread()
+			return s.scanNumber(ch, pos)
+		}
+		s.r.unread()
+		if ch == '-' {
+			return DASH, pos, ""
+		}
 	}
 
 	return ILLEGAL, pos, string(ch)
@@ -92,13 +140,85 @@ func (s *Scanner) scanIdent() (tok Token, pos Pos, lit string) {
 	lit = buf.String()
 
 	// if the literal matches a keyword then return the keyword
-	if tok = Lookup(lit); tok != IDENT {
+	if tok = s.lookup(lit); tok != IDENT {
 		return tok, pos, ""
 	}
 
 	return
 }
 
+// lookup returns the token of the word in the first locale that has it.
+func (s *Scanner) lookup(word string) Token {
+	for _, l := range s.locales {
+		if tok := l.Lookup(word); tok != IDENT {
+			return tok
+		}
+	}
+	return IDENT
+}
+
+// scanComment consumes a comment to the end of the line, not including the
+// newline.
+func (s *Scanner) scanComment(marker string, pos Pos) (tok Token, _ Pos, lit string) {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString(marker)
+
+	for {
+		ch, _ := s.r.read()
+		if ch == eof || ch == '\n' {
+			s.r.unread()
+			break
+		}
+		_, _ = buf.WriteRune(ch)
+	}
+	return COMMENT, pos, buf.String()
+}
+
+// scanString consumes a double quoted string with Go escapes, after its
+// opening quote.  Unterminated strings and invalid escapes are illegal.
+func (s *Scanner) scanString(pos Pos) (tok Token, _ Pos, lit string) {
+	var buf bytes.Buffer
+	_, _ = buf.WriteRune('"')
+
+	for escaped := false; ; {
+		ch, _ := s.r.read()
+		if ch == eof || ch == '\n' {
+			s.r.unread()
+			return ILLEGAL, pos, buf.String()
+		}
+		_, _ = buf.WriteRune(ch)
+
+		switch {
+		case escaped:
+			escaped = false
+		case ch == '\\':
+			escaped = true
+		case ch == '"':
+			v, err := strconv.Unquote(buf.String())
+			if err != nil {
+				return ILLEGAL, pos, buf.String()
+			}
+			return STRING, pos, v
+		}
+	}
+}
+
+// scanNumber consumes a signed number after its sign.
+func (s *Scanner) scanNumber(sign rune, pos Pos) (tok Token, _ Pos, lit string) {
+	var buf bytes.Buffer
+	_, _ = buf.WriteRune(sign)
+
+	for {
+		ch, _ := s.r.read()
+		if !isIdentChar(ch) {
+			s.r.unread()
+			break
+		}
+		_, _ = buf.WriteRune(ch)
+	}
+	return IDENT, pos, buf.String()
+}
+
 // reader represents a buffered rune reader used by the scanner.  It provides a
 // fixed length circular buffer that can be unread.
 type reader struct {
@@ -167,10 +287,10 @@ const eof = rune(0)
 // isWhitespace returns true if the rune is a space, tab, or newline.
 func isWhitespace(ch rune) bool { return ch == ' ' || ch == '\t' || ch == '\n' }
 
-// isLetter returns true if the rune is a letter.
-func isLetter(ch rune) bool { return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') }
+// isLetter returns true if the rune is a letter, including accented letters.
+func isLetter(ch rune) bool { return unicode.IsLetter(ch) }
 
-// isDigit returns true if the rune is a digit.
+// isDigit returns true if the rune is an ASCII digit.
 func isDigit(ch rune) bool { return (ch >= '0' && ch <= '9') }
 
 // isIdentChar returns true if the rune is an ident character.
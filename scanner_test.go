@@ -3,7 +3,7 @@ package timewarp_test
 import (
 	"bytes"
 
-	. "github.com/takeinitiative/timewarp"
+	"github.com/takeinitiative/timewarp"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
@@ -12,64 +12,81 @@ import (
 
 var _ = Describe("Scanner", func() {
 	var (
-		s   *Scanner
+		s   *timewarp.Scanner
 		buf bytes.Buffer
 	)
 
 	JustBeforeEach(func() {
-		s = NewScanner(&buf)
+		s = timewarp.NewScanner(&buf)
 	})
 
 	DescribeTable("Identify tokens",
-		func(str string, tok Token, lit string) {
+		func(str string, tok timewarp.Token, lit string) {
 			_, _ = buf.WriteString(str)
 			t, _, l := s.Scan()
 			Expect(t).To(Equal(tok))
 			Expect(l).To(Equal(lit))
 		},
-		Entry("EOF", ``, EOF, ``),
-		Entry("ILLEGAL", `#`, ILLEGAL, `#`),
-		Entry("WS", "\n \r\n", WS, "\n \n"),
-		Entry("WS", "\n \r", WS, "\n \n"),
-		Entry("WS", "\n \r ", WS, "\n \n "),
-
-		Entry("IDENT <1st>", `1st`, IDENT, `1st`),
-		Entry("IDENT <ms>", `ms`, IDENT, `ms`),
-
-		Entry("AND", "and", AND, ""),
-		Entry("IN", "in", IN, ""),
-		Entry("OF", "of", OF, ""),
-		Entry("NOT", "not", NOT, ""),
-
-		Entry("YEAR", "year", YEAR, ""),
-		Entry("MONTH", "month", MONTH, ""),
-		Entry("WEEK", "week", WEEK, ""),
-		Entry("DAY", "day", DAY, ""),
-		Entry("TIME", "time", TIME, ""),
-
-		Entry("JANUARY", "january", JANUARY, ""),
-		Entry("FEBRUARY", "february", FEBRUARY, ""),
-		Entry("MARCH", "march", MARCH, ""),
-		Entry("APRIL", "april", APRIL, ""),
-		Entry("MAY", "may", MAY, ""),
-		Entry("JUNE", "june", JUNE, ""),
-		Entry("JULY", "july", JULY, ""),
-		Entry("AUGUST", "august", AUGUST, ""),
-		Entry("SEPTEMBER", "september", SEPTEMBER, ""),
-		Entry("OCTOBER", "october", OCTOBER, ""),
-		Entry("NOVEMBER", "november", NOVEMBER, ""),
-		Entry("DECEMEBER", "december", DECEMBER, ""),
-
-		Entry("MONDAY", "monday", MONDAY, ""),
-		Entry("TUESDAY", "tuesday", TUESDAY, ""),
-		Entry("WEDNESDAY", "wednesday", WEDNESDAY, ""),
-		Entry("THURSDAY", "thursday", THURSDAY, ""),
-		Entry("FRIDAY", "friday", FRIDAY, ""),
-		Entry("SATURDAY", "saturday", SATURDAY, ""),
-		Entry("SUNDAY", "sunday", SUNDAY, ""),
-
-		Entry("LPAREN", "(", LPAREN, ""),
-		Entry("RPAREN", ")", RPAREN, ""),
+		Entry("EOF", ``, timewarp.EOF, ``),
+		Entry("ILLEGAL", `@`, timewarp.ILLEGAL, `@`),
+		Entry("ILLEGAL <+>", `+`, timewarp.ILLEGAL, `+`),
+		Entry("ILLEGAL <unterminated string>", "\"day\nof", timewarp.ILLEGAL, `"day`),
+		Entry("ILLEGAL <invalid escape>", `"\q"`, timewarp.ILLEGAL, `"\q"`),
+		Entry("WS", "\n \r\n", timewarp.WS, "\n \n"),
+		Entry("WS", "\n \r", timewarp.WS, "\n \n"),
+		Entry("WS", "\n \r ", timewarp.WS, "\n \n "),
+
+		Entry("IDENT <1st>", `1st`, timewarp.IDENT, `1st`),
+		Entry("IDENT <ms>", `ms`, timewarp.IDENT, `ms`),
+		Entry("IDENT <août>", `août`, timewarp.IDENT, `août`),
+		Entry("IDENT <-1>", `-1`, timewarp.IDENT, `-1`),
+		Entry("IDENT <+2nd>", `+2nd`, timewarp.IDENT, `+2nd`),
+
+		Entry("COMMENT <#>", "# every day\nday", timewarp.COMMENT, `# every day`),
+		Entry("COMMENT <-->", "-- every day\r\nday", timewarp.COMMENT, `-- every day`),
+		Entry("STRING", `"public holidays"`, timewarp.STRING, `public holidays`),
+		Entry("STRING <escapes>", `"a \"b\"\t\u00e9"`, timewarp.STRING, "a \"b\"\té"),
+		Entry("STRING <empty>", `""`, timewarp.STRING, ``),
+		Entry("COLON", ":", timewarp.COLON, ""),
+		Entry("DASH", "-", timewarp.DASH, ""),
+		Entry("DASH <before ident>", "-day", timewarp.DASH, ""),
+		Entry("COMMA", ",", timewarp.COMMA, ""),
+		Entry("SEMICOLON", ";", timewarp.SEMICOLON, ""),
+
+		Entry("AND", "and", timewarp.AND, ""),
+		Entry("IN", "in", timewarp.IN, ""),
+		Entry("OF", "of", timewarp.OF, ""),
+		Entry("NOT", "not", timewarp.NOT, ""),
+
+		Entry("YEAR", "year", timewarp.YEAR, ""),
+		Entry("MONTH", "month", timewarp.MONTH, ""),
+		Entry("WEEK", "week", timewarp.WEEK, ""),
+		Entry("DAY", "day", timewarp.DAY, ""),
+		Entry("TIME", "time", timewarp.TIME, ""),
+
+		Entry("JANUARY", "january", timewarp.JANUARY, ""),
+		Entry("FEBRUARY", "february", timewarp.FEBRUARY, ""),
+		Entry("MARCH", "march", timewarp.MARCH, ""),
+		Entry("APRIL", "april", timewarp.APRIL, ""),
+		Entry("MAY", "may", timewarp.MAY, ""),
+		Entry("JUNE", "june", timewarp.JUNE, ""),
+		Entry("JULY", "july", timewarp.JULY, ""),
+		Entry("AUGUST", "august", timewarp.AUGUST, ""),
+		Entry("SEPTEMBER", "september", timewarp.SEPTEMBER, ""),
+		Entry("OCTOBER", "october", timewarp.OCTOBER, ""),
+		Entry("NOVEMBER", "november", timewarp.NOVEMBER, ""),
+		Entry("DECEMEBER", "december", timewarp.DECEMBER, ""),
+
+		Entry("MONDAY", "monday", timewarp.MONDAY, ""),
+		Entry("TUESDAY", "tuesday", timewarp.TUESDAY, ""),
+		Entry("WEDNESDAY", "wednesday", timewarp.WEDNESDAY, ""),
+		Entry("THURSDAY", "thursday", timewarp.THURSDAY, ""),
+		Entry("FRIDAY", "friday", timewarp.FRIDAY, ""),
+		Entry("SATURDAY", "saturday", timewarp.SATURDAY, ""),
+		Entry("SUNDAY", "sunday", timewarp.SUNDAY, ""),
+
+		Entry("LPAREN", "(", timewarp.LPAREN, ""),
+		Entry("RPAREN", ")", timewarp.RPAREN, ""),
 	)
 
 	Describe("Sentence", func() {
@@ -77,7 +94,7 @@ var _ = Describe("Scanner", func() {
 			_, _ = buf.WriteString("DAY TUESDAY AND DAY\n WEDNESDAY AND DAY FRIDAY SUNDAY")
 		})
 
-		ExpectScanned := func(tok Token, pos Pos, lit string) {
+		ExpectScanned := func(tok timewarp.Token, pos timewarp.Pos, lit string) {
 			t, p, l := s.Scan()
 			Expect(tok).To(Equal(t))
 			Expect(pos).To(Equal(p))
@@ -85,27 +102,63 @@ var _ = Describe("Scanner", func() {
 		}
 
 		Specify("tokens scanned", func() {
-			ExpectScanned(DAY, Pos{0, 0}, "")
-			ExpectScanned(WS, Pos{0, 3}, " ")
-			ExpectScanned(TUESDAY, Pos{0, 4}, "")
-			ExpectScanned(WS, Pos{0, 11}, " ")
-			ExpectScanned(AND, Pos{0, 12}, "")
-			ExpectScanned(WS, Pos{0, 15}, " ")
-			ExpectScanned(DAY, Pos{0, 16}, "")
-			ExpectScanned(WS, Pos{0, 19}, "\n ")
-			ExpectScanned(WEDNESDAY, Pos{1, 1}, "")
-			ExpectScanned(WS, Pos{1, 10}, " ")
-			ExpectScanned(AND, Pos{1, 11}, "")
-			ExpectScanned(WS, Pos{1, 14}, " ")
-			ExpectScanned(DAY, Pos{1, 15}, "")
-			ExpectScanned(WS, Pos{1, 18}, " ")
-			ExpectScanned(FRIDAY, Pos{1, 19}, "")
-			ExpectScanned(WS, Pos{1, 25}, " ")
-			ExpectScanned(SUNDAY, Pos{1, 26}, "")
-			ExpectScanned(EOF, Pos{1, 32}, "")
-			ExpectScanned(EOF, Pos{1, 32}, "")
-			ExpectScanned(EOF, Pos{1, 32}, "")
+			ExpectScanned(timewarp.DAY, timewarp.Pos{0, 0}, "")
+			ExpectScanned(timewarp.WS, timewarp.Pos{0, 3}, " ")
+			ExpectScanned(timewarp.TUESDAY, timewarp.Pos{0, 4}, "")
+			ExpectScanned(timewarp.WS, timewarp.Pos{0, 11}, " ")
+			ExpectScanned(timewarp.AND, timewarp.Pos{0, 12}, "")
+			ExpectScanned(timewarp.WS, timewarp.Pos{0, 15}, " ")
+			ExpectScanned(timewarp.DAY, timewarp.Pos{0, 16}, "")
+			ExpectScanned(timewarp.WS, timewarp.Pos{0, 19}, "\n ")
+			ExpectScanned(timewarp.WEDNESDAY, timewarp.Pos{1, 1}, "")
+			ExpectScanned(timewarp.WS, timewarp.Pos{1, 10}, " ")
+			ExpectScanned(timewarp.AND, timewarp.Pos{1, 11}, "")
+			ExpectScanned(timewarp.WS, timewarp.Pos{1, 14}, " ")
+			ExpectScanned(timewarp.DAY, timewarp.Pos{1, 15}, "")
+			ExpectScanned(timewarp.WS, timewarp.Pos{1, 18}, " ")
+			ExpectScanned(timewarp.FRIDAY, timewarp.Pos{1, 19}, "")
+			ExpectScanned(timewarp.WS, timewarp.Pos{1, 25}, " ")
+			ExpectScanned(timewarp.SUNDAY, timewarp.Pos{1, 26}, "")
+			ExpectScanned(timewarp.EOF, timewarp.Pos{1, 32}, "")
+			ExpectScanned(timewarp.EOF, timewarp.Pos{1, 32}, "")
+			ExpectScanned(timewarp.EOF, timewarp.Pos{1, 32}, "")
 		})
 
 	})
+
+	Describe("Punctuation", func() {
+		BeforeEach(func() {
+			_, _ = buf.WriteString("2018-01 \"août\", 9:00 OF -1 # été\n\"é\";")
+		})
+
+		ExpectScanned := func(tok timewarp.Token, pos timewarp.Pos, lit string) {
+			t, p, l := s.Scan()
+			Expect(tok).To(Equal(t))
+			Expect(pos).To(Equal(p))
+			Expect(lit).To(Equal(l))
+		}
+
+		Specify("tokens scanned with columns counted in runes", func() {
+			ExpectScanned(timewarp.IDENT, timewarp.Pos{0, 0}, "2018")
+			ExpectScanned(timewarp.DASH, timewarp.Pos{0, 4}, "")
+			ExpectScanned(timewarp.IDENT, timewarp.Pos{0, 5}, "01")
+			ExpectScanned(timewarp.WS, timewarp.Pos{0, 7}, " ")
+			ExpectScanned(timewarp.STRING, timewarp.Pos{0, 8}, "août")
+			ExpectScanned(timewarp.COMMA, timewarp.Pos{0, 14}, "")
+			ExpectScanned(timewarp.WS, timewarp.Pos{0, 15}, " ")
+			ExpectScanned(timewarp.IDENT, timewarp.Pos{0, 16}, "9")
+			ExpectScanned(timewarp.COLON, timewarp.Pos{0, 17}, "")
+			ExpectScanned(timewarp.IDENT, timewarp.Pos{0, 18}, "00")
+			ExpectScanned(timewarp.WS, timewarp.Pos{0, 20}, " ")
+			ExpectScanned(timewarp.OF, timewarp.Pos{0, 21}, "")
+			ExpectScanned(timewarp.WS, timewarp.Pos{0, 23}, " ")
+			ExpectScanned(timewarp.IDENT, timewarp.Pos{0, 24}, "-1")
+			ExpectScanned(timewarp.WS, timewarp.Pos{0, 26}, " ")
+			ExpectScanned(timewarp.COMMENT, timewarp.Pos{0, 27}, "# été")
+			ExpectScanned(timewarp.WS, timewarp.Pos{0, 32}, "\n")
+			ExpectScanned(timewarp.STRING, timewarp.Pos{1, 0}, "é")
+			ExpectScanned(timewarp.SEMICOLON, timewarp.Pos{1, 3}, "")
+			ExpectScanned(timewarp.EOF, timewarp.Pos{1, 4}, "")
+		})
+	})
 })
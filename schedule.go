@@ -0,0 +1,117 @@
+package timewarp
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Schedule is a parsed expression along with its compiled filter.  Unlike a
+// Filter, a schedule can be stored and transmitted: it marshals as the
+// canonical expression and is parsed again when unmarshalled.  The zero value
+// is an empty schedule that matches nothing.
+type Schedule struct {
+	expr   Expr
+	filter Filter
+}
+
+// NewSchedule parses the expression into a schedule.
+func NewSchedule(s string) (*Schedule, error) {
+	var sch Schedule
+	if err := sch.parse(s); err != nil {
+		return nil, err
+	}
+	return &sch, nil
+}
+
+// parse replaces the schedule with the parsed expression.  The schedule is
+// unchanged if the expression is invalid.
+func (s *Schedule) parse(src string) error {
+	e, err := ParseExprString(src)
+	if err != nil {
+		return err
+	}
+	s.expr, s.filter = e, e.Filter()
+	return nil
+}
+
+// Expr returns the parsed expression, or nil for an empty schedule.
+func (s *Schedule) Expr() Expr {
+	return s.expr
+}
+
+// Filter returns the compiled filter.
+func (s *Schedule) Filter() Filter {
+	if s.filter == nil {
+		return func(TimeRange) []*TimeRange { return nil }
+	}
+	return s.filter
+}
+
+// IsZero returns true for an empty schedule.
+func (s *Schedule) IsZero() bool {
+	return s.expr == nil
+}
+
+// String returns the canonical expression, or an empty string for an empty
+// schedule.
+func (s Schedule) String() string {
+	if s.expr == nil {
+		return ""
+	}
+	return s.expr.String()
+}
+
+// MarshalText encodes the schedule as its canonical expression.
+func (s Schedule) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText parses the expression.  An empty expression is an empty
+// schedule.
+func (s *Schedule) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*s = Schedule{}
+		return nil
+	}
+	return s.parse(string(text))
+}
+
+// MarshalJSON encodes the schedule as a string of its canonical expression.
+func (s Schedule) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON parses the expression from a JSON string.
+func (s *Schedule) UnmarshalJSON(data []byte) error {
+	var src string
+	if err := json.Unmarshal(data, &src); err != nil {
+		return err
+	}
+	return s.UnmarshalText([]byte(src))
+}
+
+// Scan implements the sql.Scanner interface.  A NULL value scans as an empty
+// schedule.
+func (s *Schedule) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*s = Schedule{}
+		return nil
+	case string:
+		return s.UnmarshalText([]byte(v))
+	case []byte:
+		return s.UnmarshalText(v)
+	default:
+		return fmt.Errorf("cannot scan %T into a schedule", src)
+	}
+}
+
+// Value implements the driver.Valuer interface.  An empty schedule is stored
+// as NULL.
+func (s Schedule) Value() (driver.Value, error) {
+	if s.expr == nil {
+		return nil, nil
+	}
+	return s.String(), nil
+}
@@ -0,0 +1,59 @@
+package timewarp_test
+
+import (
+	"encoding/json"
+
+	"github.com/takeinitiative/timewarp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Schedule", func() {
+	const datefmt = "01-02-06"
+
+	type record struct {
+		Name     string             `json:"name"`
+		Schedule *timewarp.Schedule `json:"schedule"`
+	}
+
+	It("should marshal as the canonical expression", func() {
+		s, err := timewarp.NewSchedule(`day tuesday of 2 month march in time 1200 1400`)
+		Expect(err).NotTo(HaveOccurred())
+
+		b, err := json.Marshal(record{"lunch", s})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`{"name":"lunch","schedule":"DAY TUESDAY OF 2 MONTH MARCH IN TIME 1200 1400"}`))
+
+		var out record
+		Expect(json.Unmarshal(b, &out)).To(Succeed())
+		Expect(out.Schedule.String()).To(Equal(s.String()))
+
+		r, _ := timewarp.Parse(datefmt, "01-01-18", "01-01-19")
+		Expect(out.Schedule.Filter()(*r)).To(Equal(s.Filter()(*r)))
+	})
+
+	It("should validate on unmarshal", func() {
+		var out record
+		Expect(json.Unmarshal([]byte(`{"schedule":"DAY TUESDAY AND"}`), &out)).NotTo(Succeed())
+		Expect(json.Unmarshal([]byte(`{"schedule":42}`), &out)).NotTo(Succeed())
+	})
+
+	It("should scan and value SQL strings", func() {
+		var s timewarp.Schedule
+		Expect(s.Scan("DAY MONDAY FRIDAY")).To(Succeed())
+		Expect(s.String()).To(Equal("DAY MONDAY FRIDAY"))
+
+		v, err := s.Value()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v).To(Equal("DAY MONDAY FRIDAY"))
+
+		Expect(s.Scan(nil)).To(Succeed())
+		Expect(s.IsZero()).To(BeTrue())
+		Expect(s.Filter()(timewarp.TimeRange{})).To(BeEmpty())
+
+		v, err = s.Value()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v).To(BeNil())
+	})
+})
@@ -0,0 +1,159 @@
+package timewarp
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultLookahead is how far ahead a scheduler evaluates its filter.
+const defaultLookahead = 7 * 24 * time.Hour
+
+// Scheduler calls OnStart when a result of its filter starts and OnEnd when
+// it ends.  The filter is evaluated around the current time each time the
+// scheduler wakes, so it can run indefinitely.
+//
+// The scheduler is level triggered: when it wakes, it compares the range
+// that contains the current time with the range it last started, so a range
+// that ended while the scheduler was not running is ended once, and a range
+// that continues across a call to Swap is not started again.
+//
+// The zero value matches nothing until a filter is set with Swap.
+type Scheduler struct {
+	// OnStart is called with the range when it starts.  A range already in
+	// progress when the scheduler runs is started immediately.
+	OnStart func(TimeRange)
+
+	// OnEnd is called with the range when it ends.  It is not called when
+	// the scheduler is cancelled.
+	OnEnd func(TimeRange)
+
+	// Clock is the source of time.  Defaults to the system clock.
+	Clock Clock
+
+	// Lookahead is how far before and after the current time the filter is
+	// evaluated.  Ranges that started earlier are reported from the start of
+	// the lookahead.  Defaults to a week.
+	Lookahead time.Duration
+
+	mu     sync.Mutex
+	filter Filter
+	swap   chan struct{}
+}
+
+// NewScheduler returns a scheduler for the results of the filter.
+func NewScheduler(f Filter) *Scheduler {
+	return &Scheduler{filter: f}
+}
+
+// Swap replaces the filter of a running scheduler.  The current range is
+// ended if the new filter does not match the current time, and a range of
+// the new filter is started if it does.
+func (s *Scheduler) Swap(f Filter) {
+	s.mu.Lock()
+	s.filter = f
+	swap := s.swapped()
+	s.mu.Unlock()
+
+	select {
+	case swap <- struct{}{}:
+	default:
+	}
+}
+
+// swapped returns the channel that wakes Run after a swap, creating it on
+// first use, while holding the lock.
+func (s *Scheduler) swapped() chan struct{} {
+	if s.swap == nil {
+		s.swap = make(chan struct{}, 1)
+	}
+	return s.swap
+}
+
+// Run calls the callbacks as the ranges start and end until the context is
+// done, then returns the error of the context.
+func (s *Scheduler) Run(ctx context.Context) error {
+	var (
+		clock     = s.Clock
+		lookahead = s.Lookahead
+		active    *TimeRange
+	)
+	if clock == nil {
+		clock = RealClock{}
+	}
+	if lookahead <= 0 {
+		lookahead = defaultLookahead
+	}
+
+	for {
+		now := clock.Now()
+
+		s.mu.Lock()
+		f := s.filter
+		swap := s.swapped()
+		s.mu.Unlock()
+
+		cur, next := locate(f, now, lookahead)
+
+		// reconcile the started range with the current range
+		if active != nil && (cur == nil || !cur.Start.Before(active.End) || !active.Start.Before(cur.End)) {
+			if s.OnEnd != nil {
+				s.OnEnd(*active)
+			}
+			active = nil
+		}
+		if cur != nil {
+			if active == nil {
+				active = cur
+				if s.OnStart != nil {
+					s.OnStart(*cur)
+				}
+			} else {
+				// the range continues, possibly with a new end
+				active.End = cur.End
+			}
+		}
+
+		timer := clock.NewTimer(next.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-swap:
+			timer.Stop()
+		case <-timer.C():
+		}
+	}
+}
+
+// Current returns the result of the filter that contains the current time of
+// the clock, or nil if there is none.  Results that started more than a week
+// earlier are returned from a week earlier.
+func (f Filter) Current(clock Clock) *TimeRange {
+	cur, _ := locate(f, clock.Now(), defaultLookahead)
+	return cur
+}
+
+// locate returns the range containing the current time, if any, and the time
+// of the next edge or the end of the lookahead.
+func locate(f Filter, now time.Time, lookahead time.Duration) (cur *TimeRange, next time.Time) {
+	next = now.Add(lookahead)
+	if f == nil {
+		return nil, next
+	}
+
+	for _, r := range matchedRanges(f, TimeRange{now.Add(-lookahead), next}) {
+		switch {
+		case !r.End.After(now):
+		case r.Start.After(now):
+			return cur, r.Start
+		default:
+			cur = r
+			if r.End.Before(next) {
+				next = r.End
+			}
+			return cur, next
+		}
+	}
+	return cur, next
+}
@@ -0,0 +1,174 @@
+package timewarp_test
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/takeinitiative/timewarp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Scheduler", func() {
+	var (
+		clock  *timewarp.FakeClock
+		s      *timewarp.Scheduler
+		mu     sync.Mutex
+		events []string
+		cancel context.CancelFunc
+		done   chan error
+		start  time.Time
+		zero   bool
+		at     = func(h int) time.Time {
+			// 11-07-16 is a Monday
+			return time.Date(2016, 11, 7, h, 0, 0, 0, time.UTC)
+		}
+		parse = func(s string) timewarp.Filter {
+			f, err := timewarp.ParseString(s)
+			Expect(err).NotTo(HaveOccurred())
+			return f
+		}
+		record = func(kind string) func(timewarp.TimeRange) {
+			return func(tr timewarp.TimeRange) {
+				mu.Lock()
+				defer mu.Unlock()
+				events = append(events, kind+" "+tr.String())
+			}
+		}
+		recorded = func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]string(nil), events...)
+		}
+		// wait blocks until the scheduler sleeps again after a timer fired
+		wait = func() {
+			clock.BlockUntil(1)
+		}
+		// sleeping returns the time the scheduler sleeps until
+		sleeping = func() time.Time {
+			t, _ := clock.Next()
+			return t
+		}
+	)
+
+	BeforeEach(func() {
+		events = nil
+		start = at(8)
+		zero = false
+	})
+
+	JustBeforeEach(func() {
+		clock = timewarp.NewFakeClock(start)
+		if zero {
+			s = &timewarp.Scheduler{}
+		} else {
+			s = timewarp.NewScheduler(parse(`DAY MONDAY FRIDAY IN TIME 0900 1700`))
+		}
+		s.Clock = clock
+		s.OnStart = record("start")
+		s.OnEnd = record("end")
+
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		done = make(chan error, 1)
+		go func() { done <- s.Run(ctx) }()
+		wait()
+	})
+
+	AfterEach(func() {
+		cancel()
+		Eventually(done).Should(Receive(Equal(context.Canceled)))
+	})
+
+	It("should call the callbacks at the edges", func() {
+		Expect(recorded()).To(BeEmpty())
+
+		clock.Advance(time.Hour)
+		wait()
+		Expect(recorded()).To(Equal([]string{"start 2016-11-07T09:00:00Z/2016-11-07T17:00:00Z"}))
+
+		clock.Advance(8 * time.Hour)
+		wait()
+		Expect(recorded()).To(Equal([]string{
+			"start 2016-11-07T09:00:00Z/2016-11-07T17:00:00Z",
+			"end 2016-11-07T09:00:00Z/2016-11-07T17:00:00Z",
+		}))
+	})
+
+	Context("in progress", func() {
+		BeforeEach(func() {
+			start = at(10)
+		})
+
+		It("should start the range immediately", func() {
+			Expect(recorded()).To(Equal([]string{"start 2016-11-07T09:00:00Z/2016-11-07T17:00:00Z"}))
+		})
+
+		It("should continue a range across a swap", func() {
+			s.Swap(parse(`DAY MONDAY FRIDAY IN TIME 0900 1800`))
+			Eventually(sleeping).Should(Equal(at(18)))
+			Expect(recorded()).To(HaveLen(1))
+
+			clock.Advance(7 * time.Hour)
+			Expect(recorded()).To(HaveLen(1))
+
+			clock.Advance(time.Hour)
+			wait()
+			Expect(recorded()).To(Equal([]string{
+				"start 2016-11-07T09:00:00Z/2016-11-07T17:00:00Z",
+				"end 2016-11-07T09:00:00Z/2016-11-07T18:00:00Z",
+			}))
+		})
+
+		It("should end a range removed by a swap", func() {
+			s.Swap(parse(`DAY SATURDAY`))
+			Eventually(recorded).Should(Equal([]string{
+				"start 2016-11-07T09:00:00Z/2016-11-07T17:00:00Z",
+				"end 2016-11-07T09:00:00Z/2016-11-07T17:00:00Z",
+			}))
+		})
+	})
+
+	Context("zero value", func() {
+		BeforeEach(func() {
+			start = at(10)
+			zero = true
+		})
+
+		It("should match nothing until swapped", func() {
+			Expect(recorded()).To(BeEmpty())
+			Expect(sleeping()).To(Equal(start.Add(7 * 24 * time.Hour)))
+
+			s.Swap(parse(`DAY MONDAY FRIDAY IN TIME 0900 1700`))
+			Eventually(recorded).Should(Equal([]string{"start 2016-11-07T09:00:00Z/2016-11-07T17:00:00Z"}))
+		})
+	})
+
+	It("should stop its timer when cancelled", func() {
+		cancel()
+		Eventually(done).Should(Receive(Equal(context.Canceled)))
+		Expect(clock.Next()).To(BeZero())
+
+		// for the check after each spec
+		done <- context.Canceled
+	})
+
+	It("should re-evaluate past the lookahead", func() {
+		s.Swap(parse(`DAY 1 OF MONTH DECEMBER`))
+		Eventually(sleeping).Should(Equal(start.Add(7 * 24 * time.Hour)))
+
+		// wake at the end of each week until december is within a week
+		for i := 0; i < 3; i++ {
+			clock.Advance(7 * 24 * time.Hour)
+			wait()
+		}
+		Expect(recorded()).To(BeEmpty())
+
+		clock.Advance(2*24*time.Hour + 16*time.Hour)
+		wait()
+		Expect(recorded()).To(HaveLen(1))
+		Expect(recorded()[0]).To(HavePrefix("start 2016-12-01T00:00:00Z"))
+	})
+})
@@ -0,0 +1,386 @@
+package timewarp
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// schemaDay is the schema.org URL prefix of the days of the week.
+const schemaDay = "https://schema.org/"
+
+// OpeningHoursSpecification is a schema.org OpeningHoursSpecification
+// JSON-LD object.  Entries with validFrom or validThrough are special hours
+// that replace the regular hours of the dates they cover, and an entry that
+// opens and closes at "00:00" is closed for the day.
+type OpeningHoursSpecification struct {
+	DayOfWeek    []string `json:"dayOfWeek,omitempty"`
+	Opens        string   `json:"opens,omitempty"`
+	Closes       string   `json:"closes,omitempty"`
+	ValidFrom    string   `json:"validFrom,omitempty"`
+	ValidThrough string   `json:"validThrough,omitempty"`
+}
+
+// MarshalJSON encodes the specification with its JSON-LD type.
+func (s OpeningHoursSpecification) MarshalJSON() ([]byte, error) {
+	type spec OpeningHoursSpecification
+	return json.Marshal(struct {
+		Type string `json:"@type"`
+		spec
+	}{"OpeningHoursSpecification", spec(s)})
+}
+
+// UnmarshalJSON decodes the specification, accepting a single day of the week
+// as well as a list.
+func (s *OpeningHoursSpecification) UnmarshalJSON(data []byte) error {
+	type spec OpeningHoursSpecification
+	var v struct {
+		spec
+		DayOfWeek json.RawMessage `json:"dayOfWeek"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*s = OpeningHoursSpecification(v.spec)
+
+	if len(v.DayOfWeek) == 0 {
+		return nil
+	}
+	var day string
+	if err := json.Unmarshal(v.DayOfWeek, &day); err == nil {
+		s.DayOfWeek = []string{day}
+		return nil
+	}
+	return json.Unmarshal(v.DayOfWeek, &s.DayOfWeek)
+}
+
+// SpecifyOpeningHours converts the expression into schema.org opening hours.
+// The expression must be a union of weekdays in times of day, optionally
+// followed by exceptions as in "X IN NOT Y".  The exceptions are evaluated
+// within the window and every date where they change the hours is emitted as
+// special hours.
+func SpecifyOpeningHours(e Expr, window TimeRange) ([]*OpeningHoursSpecification, error) {
+	var (
+		regular    = unnamed(e)
+		exceptions []Expr
+	)
+	for {
+		b, ok := regular.(*BinaryExpr)
+		if !ok || b.Op != IN {
+			break
+		}
+		n, ok := unnamed(b.Y).(*NotExpr)
+		if !ok {
+			break
+		}
+		exceptions = append(exceptions, n.X)
+		regular = unnamed(b.X)
+	}
+
+	var (
+		result []*OpeningHoursSpecification
+		arms   []Expr
+		split  func(e Expr)
+	)
+	split = func(e Expr) {
+		if b, ok := unnamed(e).(*BinaryExpr); ok && b.Op == AND {
+			split(b.X)
+			split(b.Y)
+			return
+		}
+		arms = append(arms, unnamed(e))
+	}
+	split(regular)
+
+	for _, arm := range arms {
+		specs, err := specifyArm(arm)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, specs...)
+	}
+
+	if len(exceptions) == 0 {
+		return result, nil
+	}
+	return append(result, specifyExceptions(e, regular, exceptions, window)...), nil
+}
+
+// specifyArm converts an arm of the regular hours into specifications.
+func specifyArm(arm Expr) ([]*OpeningHoursSpecification, error) {
+	var (
+		days     []string
+		times    []*TimeExpr
+		operands []Expr
+	)
+
+	var flatten func(e Expr)
+	flatten = func(e Expr) {
+		e = unnamed(e)
+		if b, ok := e.(*BinaryExpr); ok && b.Op == IN {
+			flatten(b.X)
+			flatten(b.Y)
+			return
+		}
+		operands = append(operands, e)
+	}
+	flatten(arm)
+
+	unsupported := func(e Expr) error {
+		return fmt.Errorf("schema.org opening hours cannot represent %s", e)
+	}
+
+	for _, op := range operands {
+		switch o := op.(type) {
+		case *DayExpr:
+			if len(o.Args) != 0 {
+				return nil, unsupported(o)
+			}
+		case *WeekdayExpr:
+			if days != nil {
+				return nil, unsupported(o)
+			}
+			for d := o.From; ; d = (d + 1) % 7 {
+				days = append(days, schemaDay+d.String())
+				if d == o.To {
+					break
+				}
+			}
+		case *TimeExpr, *BinaryExpr:
+			if times != nil {
+				return nil, unsupported(o)
+			}
+			for _, t := range unionArms(o, nil) {
+				te, ok := unnamed(t).(*TimeExpr)
+				if !ok || te.From == te.To {
+					return nil, unsupported(o)
+				}
+				times = append(times, te)
+			}
+		default:
+			return nil, unsupported(o)
+		}
+	}
+
+	if days == nil {
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			days = append(days, schemaDay+d.String())
+		}
+	}
+	if times == nil {
+		return []*OpeningHoursSpecification{{DayOfWeek: days, Opens: "00:00", Closes: "23:59"}}, nil
+	}
+
+	var result []*OpeningHoursSpecification
+	for _, t := range times {
+		result = append(result, &OpeningHoursSpecification{
+			DayOfWeek: days,
+			Opens:     t.From[:2] + ":" + t.From[2:],
+			Closes:    schemaCloses(t.To[:2] + ":" + t.To[2:]),
+		})
+	}
+	return result, nil
+}
+
+// specifyExceptions returns the special hours of every date within the
+// window where the exceptions change the regular hours.
+func specifyExceptions(e, regular Expr, exceptions []Expr, window TimeRange) []*OpeningHoursSpecification {
+	// ordinals and relative expressions are only found from their unit, so
+	// evaluate from a week earlier
+	ext := TimeRange{window.Start.AddDate(0, 0, -7), window.End.AddDate(0, 0, 1)}
+	actual, expected := e.Filter()(ext), regular.Filter()(ext)
+
+	// find the dates touched by the exceptions
+	var (
+		dates []time.Time
+		seen  = make(map[time.Time]bool)
+		loc   = window.Start.Location()
+	)
+	for _, x := range exceptions {
+		for _, r := range x.Filter()(window) {
+			y, m, d := r.Start.In(loc).Date()
+			for day := time.Date(y, m, d, 0, 0, 0, 0, loc); day.Before(r.End); day = day.AddDate(0, 0, 1) {
+				if !seen[day] {
+					seen[day] = true
+					dates = append(dates, day)
+				}
+			}
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	var result []*OpeningHoursSpecification
+	for _, day := range dates {
+		var (
+			bounds = TimeRange{day, day.AddDate(0, 0, 1)}
+			hours  = clipAll(actual, bounds)
+			date   = day.Format("2006-01-02")
+		)
+		if rangesEqual(hours, clipAll(expected, bounds)) {
+			continue
+		}
+
+		if len(hours) == 0 {
+			result = append(result, &OpeningHoursSpecification{Opens: "00:00", Closes: "00:00", ValidFrom: date, ValidThrough: date})
+			continue
+		}
+		for _, h := range hours {
+			closes := "23:59"
+			if h.End.Before(bounds.End) {
+				closes = h.End.Format("15:04")
+			}
+			result = append(result, &OpeningHoursSpecification{Opens: h.Start.Format("15:04"), Closes: closes, ValidFrom: date, ValidThrough: date})
+		}
+	}
+	return result
+}
+
+// schemaCloses returns the closing time, using "23:59" for midnight.
+func schemaCloses(s string) string {
+	if s == "00:00" {
+		return "23:59"
+	}
+	return s
+}
+
+// clipAll returns copies of the ranges clipped to the bounds.
+func clipAll(ranges []*TimeRange, bounds TimeRange) []*TimeRange {
+	var result []*TimeRange
+	for _, r := range ranges {
+		c := *r
+		if clip(&c, bounds) {
+			result = append(result, &c)
+		}
+	}
+	return result
+}
+
+// rangesEqual returns true if both slices hold the same time ranges.
+func rangesEqual(a, b []*TimeRange) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Start.Equal(b[i].Start) || !a[i].End.Equal(b[i].End) {
+			return false
+		}
+	}
+	return true
+}
+
+// OpeningHoursFilter converts schema.org opening hours into a filter.  On the
+// dates covered by special hours, only the special hours apply.  A closing
+// time of "23:59" is taken as midnight, and a closing time at or before the
+// opening time passes midnight.  Dates and times use the location of the
+// input.
+func OpeningHoursFilter(specs []*OpeningHoursSpecification) (Filter, error) {
+	type rule struct {
+		days     uint8 // or zero for every day
+		from, to string
+		special  bool
+		span     [2]int
+		closed   bool
+	}
+
+	var rules []*rule
+	for _, s := range specs {
+		r := &rule{from: s.ValidFrom, to: s.ValidThrough, special: s.ValidFrom != "" || s.ValidThrough != ""}
+
+		for _, name := range s.DayOfWeek {
+			d, ok := schemaWeekday(name)
+			if !ok {
+				return nil, fmt.Errorf("invalid dayOfWeek %q", name)
+			}
+			r.days |= 1 << uint(d)
+		}
+
+		for _, bound := range []*string{&r.from, &r.to} {
+			if *bound == "" {
+				continue
+			}
+			t, err := ParseTime(*bound)
+			if err != nil {
+				return nil, err
+			}
+			*bound = t.Format("2006-01-02")
+		}
+
+		opens, err := schemaTime(s.Opens)
+		if err != nil {
+			return nil, err
+		}
+		closes, err := schemaTime(s.Closes)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case opens == closes && opens == 0:
+			r.closed = true
+		case closes == 23*60+59:
+			closes = 24 * 60
+		case closes <= opens:
+			closes += 24 * 60
+		}
+		r.span = [2]int{opens, closes}
+
+		rules = append(rules, r)
+	}
+
+	return dailyFilter(func(day time.Time) [][2]int {
+		var (
+			regular, special [][2]int
+			isSpecial        bool
+			date             = day.Format("2006-01-02")
+		)
+
+		for _, r := range rules {
+			if r.days != 0 && r.days&(1<<uint(day.Weekday())) == 0 {
+				continue
+			}
+			if !r.special {
+				if !r.closed {
+					regular = append(regular, r.span)
+				}
+				continue
+			}
+			if (r.from == "" || r.from <= date) && (r.to == "" || date <= r.to) {
+				isSpecial = true
+				if !r.closed {
+					special = append(special, r.span)
+				}
+			}
+		}
+
+		if isSpecial {
+			return special
+		}
+		return regular
+	}), nil
+}
+
+// schemaWeekday returns the weekday of a schema.org day of the week, with or
+// without its URL prefix.
+func schemaWeekday(name string) (time.Weekday, bool) {
+	if i := strings.LastIndexAny(name, "/:"); i >= 0 {
+		name = name[i+1:]
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(name, d.String()) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// schemaTime returns the minutes from midnight of an "hh:mm" or "hh:mm:ss"
+// time.
+func schemaTime(s string) (int, error) {
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Hour()*60 + t.Minute(), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid time %q", s)
+}
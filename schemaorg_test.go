@@ -0,0 +1,122 @@
+package timewarp_test
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/takeinitiative/timewarp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OpeningHoursSpecification", func() {
+	var window = timewarp.TimeRange{
+		Start: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
+		End:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	Describe("SpecifyOpeningHours", func() {
+		var (
+			in    string
+			specs []*timewarp.OpeningHoursSpecification
+			err   error
+		)
+
+		JustBeforeEach(func() {
+			e, perr := timewarp.ParseExprString(in)
+			Expect(perr).NotTo(HaveOccurred())
+			specs, err = timewarp.SpecifyOpeningHours(e, window)
+		})
+
+		Context("regular hours", func() {
+			BeforeEach(func() {
+				in = `DAY MONDAY FRIDAY IN TIME 0900 1700 AND (DAY SATURDAY IN TIME 1000 0000)`
+			})
+
+			It("should specify each arm", func() {
+				Expect(err).NotTo(HaveOccurred())
+
+				b, err := json.Marshal(specs)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(b)).To(MatchJSON(`[
+					{"@type": "OpeningHoursSpecification", "dayOfWeek": ["https://schema.org/Monday", "https://schema.org/Tuesday", "https://schema.org/Wednesday", "https://schema.org/Thursday", "https://schema.org/Friday"], "opens": "09:00", "closes": "17:00"},
+					{"@type": "OpeningHoursSpecification", "dayOfWeek": ["https://schema.org/Saturday"], "opens": "10:00", "closes": "23:59"}
+				]`))
+			})
+		})
+
+		Context("exceptions", func() {
+			BeforeEach(func() {
+				in = `DAY MONDAY FRIDAY IN TIME 0900 1700 IN NOT (DAY 25 OF MONTH DECEMBER) IN NOT (DAY 24 OF MONTH DECEMBER IN TIME 1200 1700)`
+			})
+
+			It("should emit special hours", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(specs).To(HaveLen(3))
+				Expect(*specs[1]).To(Equal(timewarp.OpeningHoursSpecification{Opens: "09:00", Closes: "12:00", ValidFrom: "2024-12-24", ValidThrough: "2024-12-24"}))
+				Expect(*specs[2]).To(Equal(timewarp.OpeningHoursSpecification{Opens: "00:00", Closes: "00:00", ValidFrom: "2024-12-25", ValidThrough: "2024-12-25"}))
+			})
+		})
+
+		Context("exceptions on closed days", func() {
+			BeforeEach(func() {
+				in = `DAY MONDAY FRIDAY IN TIME 0900 1700 IN NOT (DAY 28 OF MONTH DECEMBER)`
+			})
+
+			It("should not emit special hours", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(specs).To(HaveLen(1))
+			})
+		})
+
+		Context("unrepresentable", func() {
+			BeforeEach(func() {
+				in = `DAY SUNDAY OF MONTH IN TIME 1000 1200`
+			})
+
+			It("should fail", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("OpeningHoursFilter", func() {
+		It("should match the specified expression", func() {
+			e, err := timewarp.ParseExprString(`DAY MONDAY FRIDAY IN TIME 0900 1700 AND (DAY SATURDAY IN TIME 1000 0000) IN NOT (DAY 25 OF MONTH DECEMBER) IN NOT (DAY 24 OF MONTH DECEMBER IN TIME 1200 1700)`)
+			Expect(err).NotTo(HaveOccurred())
+
+			specs, err := timewarp.SpecifyOpeningHours(e, window)
+			Expect(err).NotTo(HaveOccurred())
+
+			f, err := timewarp.OpeningHoursFilter(specs)
+			Expect(err).NotTo(HaveOccurred())
+
+			// the first monday so the weekday range is in progress
+			r := timewarp.TimeRange{Start: time.Date(2024, 12, 2, 0, 0, 0, 0, time.UTC), End: window.End}
+			Expect(f(r)).To(Equal(e.Filter()(r)))
+		})
+
+		It("should decode JSON-LD", func() {
+			var specs []*timewarp.OpeningHoursSpecification
+			Expect(json.Unmarshal([]byte(`[
+				{"@type": "OpeningHoursSpecification", "dayOfWeek": "http://schema.org/Sunday", "opens": "22:00:00", "closes": "02:00:00"},
+				{"@type": "OpeningHoursSpecification", "opens": "00:00", "closes": "00:00", "validFrom": "2024-12-22", "validThrough": "2024-12-22"}
+			]`), &specs)).To(Succeed())
+			Expect(specs[0].DayOfWeek).To(Equal([]string{"http://schema.org/Sunday"}))
+
+			f, err := timewarp.OpeningHoursFilter(specs)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(f(timewarp.TimeRange{Start: time.Date(2024, 12, 15, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 12, 24, 0, 0, 0, 0, time.UTC)})).To(Equal([]*timewarp.TimeRange{
+				{Start: time.Date(2024, 12, 15, 22, 0, 0, 0, time.UTC), End: time.Date(2024, 12, 16, 2, 0, 0, 0, time.UTC)},
+			}))
+		})
+
+		It("should reject invalid days and times", func() {
+			_, err := timewarp.OpeningHoursFilter([]*timewarp.OpeningHoursSpecification{{DayOfWeek: []string{"Funday"}, Opens: "09:00", Closes: "17:00"}})
+			Expect(err).To(HaveOccurred())
+			_, err = timewarp.OpeningHoursFilter([]*timewarp.OpeningHoursSpecification{{Opens: "9am", Closes: "17:00"}})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
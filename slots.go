@@ -0,0 +1,115 @@
+package timewarp
+
+import (
+	"sort"
+	"time"
+)
+
+// SlotOptions are the options of FindSlots.
+type SlotOptions struct {
+	// Align is the interval from midnight that slots start on, such as 15
+	// minutes for slots on the hour and quarter hours.  Without alignment,
+	// slots follow each other from the start of each free range.
+	Align time.Duration
+
+	// Before and After are the buffer times that must be free of busy
+	// ranges before and after each slot.
+	Before, After time.Duration
+
+	// MaxResults is the maximum number of slots returned, or zero for all.
+	MaxResults int
+
+	// Prefer reports whether slot a is preferred to slot b.  Slots are
+	// returned in order of preference, earliest first by default.
+	Prefer func(a, b *TimeRange) bool
+}
+
+// PreferLatest prefers later slots.
+func PreferLatest(a, b *TimeRange) bool {
+	return a.Start.After(b.Start)
+}
+
+// PreferNear returns a preference for slots starting close to the time, and
+// the earlier of two slots equally close.
+func PreferNear(t time.Time) func(a, b *TimeRange) bool {
+	distance := func(r *TimeRange) time.Duration {
+		if d := r.Start.Sub(t); d >= 0 {
+			return d
+		}
+		return t.Sub(r.Start)
+	}
+	return func(a, b *TimeRange) bool {
+		da, db := distance(a), distance(b)
+		if da != db {
+			return da < db
+		}
+		return a.Start.Before(b.Start)
+	}
+}
+
+// FindSlots returns the slots of the duration within the window where every
+// constraint matches and no busy range, extended by the buffer times of the
+// options, overlaps.  Each constraint is evaluated over the window, starting
+// a week earlier so that ranges in progress are found.
+//
+// The slots are candidates and may overlap each other when the alignment is
+// shorter than the duration.
+func FindSlots(d time.Duration, window TimeRange, constraints []Filter, busy []*TimeRange, opts SlotOptions) []*TimeRange {
+	if d <= 0 || !window.Start.Before(window.End) {
+		return nil
+	}
+
+	// a busy range also blocks the buffers of the slots next to it
+	var blocked []*TimeRange
+	for _, b := range busy {
+		blocked = append(blocked, &TimeRange{b.Start.Add(-opts.After), b.End.Add(opts.Before)})
+	}
+
+	lead := TimeRange{window.Start.AddDate(0, 0, -7), window.End}
+	free := Ranges(&window)
+	for _, c := range constraints {
+		free = free.Intersect(Ranges(matchedRanges(c, lead)...))
+	}
+	free = free.Intersect(Ranges(blocked...).Negate())
+
+	var result []*TimeRange
+	for _, r := range free(window) {
+		for start := alignSlot(r.Start, opts.Align); !start.Add(d).After(r.End); {
+			result = append(result, &TimeRange{start, start.Add(d)})
+
+			if opts.Align > 0 {
+				start = alignSlot(start.Add(opts.Align), opts.Align)
+			} else {
+				start = start.Add(d)
+			}
+		}
+	}
+
+	if opts.Prefer != nil {
+		sort.SliceStable(result, func(i, j int) bool {
+			return opts.Prefer(result[i], result[j])
+		})
+	}
+	if opts.MaxResults > 0 && len(result) > opts.MaxResults {
+		result = result[:opts.MaxResults]
+	}
+	return result
+}
+
+// alignSlot returns the first time at or after t that is a multiple of the
+// alignment from midnight in the location of t.
+func alignSlot(t time.Time, align time.Duration) time.Time {
+	if align <= 0 {
+		return t
+	}
+
+	y, m, d := t.Date()
+	var (
+		midnight = time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+		offset   = t.Sub(midnight)
+	)
+	if rem := offset % align; rem != 0 {
+		offset += align - rem
+	}
+	return midnight.Add(offset)
+}
@@ -0,0 +1,135 @@
+package timewarp_test
+
+import (
+	"time"
+
+	"github.com/takeinitiative/timewarp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FindSlots", func() {
+	var (
+		constraints []timewarp.Filter
+		busy        []*timewarp.TimeRange
+		opts        timewarp.SlotOptions
+		window      timewarp.TimeRange
+		slots       []*timewarp.TimeRange
+		at          = func(d, h, m int) time.Time {
+			// 11-07-16 is a Monday
+			return time.Date(2016, 11, d, h, m, 0, 0, time.UTC)
+		}
+		parse = func(s string) timewarp.Filter {
+			f, err := timewarp.ParseString(s)
+			Expect(err).NotTo(HaveOccurred())
+			return f
+		}
+		starts = func() []time.Time {
+			var result []time.Time
+			for _, s := range slots {
+				Expect(s.End.Sub(s.Start)).To(Equal(45 * time.Minute))
+				result = append(result, s.Start)
+			}
+			return result
+		}
+	)
+
+	BeforeEach(func() {
+		constraints = []timewarp.Filter{
+			parse(`DAY MONDAY FRIDAY IN TIME 0900 1700`),
+			parse(`TIME 1000 1600`),
+		}
+		busy = []*timewarp.TimeRange{
+			{at(7, 10, 0), at(7, 11, 30)},
+			{at(7, 12, 0), at(7, 15, 0)},
+		}
+		opts = timewarp.SlotOptions{Align: 15 * time.Minute}
+		window = timewarp.TimeRange{at(7, 0, 0), at(8, 0, 0)}
+	})
+
+	JustBeforeEach(func() {
+		slots = timewarp.FindSlots(45*time.Minute, window, constraints, busy, opts)
+	})
+
+	It("should find the aligned slots where everyone is free", func() {
+		Expect(starts()).To(Equal([]time.Time{at(7, 15, 0), at(7, 15, 15)}))
+	})
+
+	Context("without alignment", func() {
+		BeforeEach(func() {
+			opts.Align = 0
+			busy[1].End = at(7, 15, 5)
+		})
+
+		It("should find slots back to back", func() {
+			Expect(starts()).To(Equal([]time.Time{at(7, 15, 5)}))
+		})
+	})
+
+	Context("with buffers", func() {
+		BeforeEach(func() {
+			opts.Before = 15 * time.Minute
+		})
+
+		It("should keep the buffer after busy ranges free", func() {
+			Expect(starts()).To(Equal([]time.Time{at(7, 15, 15)}))
+		})
+	})
+
+	Context("with a buffer larger than the gap", func() {
+		BeforeEach(func() {
+			busy = busy[1:]
+			opts.After = time.Hour
+			window.End = at(7, 12, 0)
+		})
+
+		It("should keep the buffer before busy ranges free", func() {
+			Expect(starts()).To(Equal([]time.Time{at(7, 10, 0), at(7, 10, 15)}))
+		})
+	})
+
+	Context("in preference order", func() {
+		BeforeEach(func() {
+			window = timewarp.TimeRange{at(8, 0, 0), at(9, 0, 0)}
+			opts.MaxResults = 2
+		})
+
+		It("should return the earliest slots by default", func() {
+			Expect(starts()).To(Equal([]time.Time{at(8, 10, 0), at(8, 10, 15)}))
+		})
+
+		Context("latest", func() {
+			BeforeEach(func() {
+				opts.Prefer = timewarp.PreferLatest
+			})
+
+			It("should return the latest slots", func() {
+				Expect(starts()).To(Equal([]time.Time{at(8, 15, 15), at(8, 15, 0)}))
+			})
+		})
+
+		Context("near a time", func() {
+			BeforeEach(func() {
+				opts.Prefer = timewarp.PreferNear(at(8, 13, 5))
+			})
+
+			It("should return the nearest slots", func() {
+				Expect(starts()).To(Equal([]time.Time{at(8, 13, 0), at(8, 13, 15)}))
+			})
+		})
+	})
+
+	Context("with a constraint in progress at the window start", func() {
+		BeforeEach(func() {
+			constraints = []timewarp.Filter{parse(`DAY MONDAY FRIDAY`)}
+			busy = nil
+			opts = timewarp.SlotOptions{MaxResults: 1}
+			window = timewarp.TimeRange{at(8, 12, 0), at(9, 0, 0)}
+		})
+
+		It("should find slots from the window start", func() {
+			Expect(starts()).To(Equal([]time.Time{at(8, 12, 0)}))
+		})
+	})
+})
@@ -0,0 +1,268 @@
+package timewarp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SQLDialect is the SQL dialect of a compiled predicate.
+type SQLDialect int
+
+// SQL dialects
+const (
+	PostgreSQL SQLDialect = iota
+	MySQL
+	SQLite
+)
+
+// String returns the name of the dialect.
+func (d SQLDialect) String() string {
+	switch d {
+	case PostgreSQL:
+		return "PostgreSQL"
+	case MySQL:
+		return "MySQL"
+	case SQLite:
+		return "SQLite"
+	}
+	return "SQLDialect(" + strconv.Itoa(int(d)) + ")"
+}
+
+// CompileSQL compiles the expression into a SQL WHERE predicate over a
+// timestamp column, returning the predicate and its arguments.  The column is
+// used as written, so it must already be quoted if necessary.  Values are
+// passed as arguments using the placeholders of the dialect.
+//
+// The predicate compares the wall clock of the column: weekdays, days of the
+// month, months and times of day are extracted from the column, and years are
+// compared with date bounds.  Weekday ranges match every day in the range,
+// including the days of a range already in progress at the start of a filter
+// input.  Expressions that depend on the input of the filter, such as
+// numbered days or ordinals other than the nth day or weekday of the month
+// counted from its start or end, cannot be compiled and return an error.
+func CompileSQL(e Expr, column string, dialect SQLDialect) (string, []interface{}, error) {
+	c := sqlCompiler{dialect: dialect, column: column}
+	s, err := c.compile(e)
+	if err != nil {
+		return "", nil, err
+	}
+	return s, c.args, nil
+}
+
+// sqlCompiler compiles expressions into SQL predicates.
+type sqlCompiler struct {
+	dialect SQLDialect
+	column  string
+	args    []interface{}
+}
+
+// sqlTrue and sqlFalse are predicates that are always true or false.
+const (
+	sqlTrue  = "1 = 1"
+	sqlFalse = "1 = 0"
+)
+
+// compile returns the predicate of the expression.
+func (c *sqlCompiler) compile(e Expr) (string, error) {
+	switch e := e.(type) {
+	case *NamedExpr:
+		return c.compile(e.X)
+	case *BinaryExpr:
+		x, err := c.compile(e.X)
+		if err != nil {
+			return "", err
+		}
+		y, err := c.compile(e.Y)
+		if err != nil {
+			return "", err
+		}
+		if e.Op == IN {
+			return "(" + x + " AND " + y + ")", nil
+		}
+		return "(" + x + " OR " + y + ")", nil
+	case *NotExpr:
+		x, err := c.compile(e.X)
+		if err != nil {
+			return "", err
+		}
+		return "NOT " + x, nil
+	case *YearExpr:
+		var (
+			from = time.Date(e.Year, 1, 1, 0, 0, 0, 0, time.UTC)
+			to   = from.AddDate(1, 0, 0)
+		)
+		return "(" + c.column + " >= " + c.date(from) + " AND " + c.column + " < " + c.date(to) + ")", nil
+	case *MonthExpr:
+		if e.Month == 0 {
+			return sqlTrue, nil
+		}
+		return c.part("month") + " = " + c.arg(int(e.Month)), nil
+	case *DayExpr:
+		if len(e.Args) == 0 {
+			return sqlTrue, nil
+		}
+	case *WeekdayExpr:
+		if e.Span() == 7 {
+			return sqlTrue, nil
+		}
+		if e.Span() == 1 {
+			return c.part("dow") + " = " + c.arg(int(e.From)), nil
+		}
+		var days []string
+		for d := e.From; ; d = (d + 1) % 7 {
+			days = append(days, c.arg(int(d)))
+			if d == e.To {
+				break
+			}
+		}
+		return c.part("dow") + " IN (" + strings.Join(days, ", ") + ")", nil
+	case *TimeExpr:
+		return c.compileTime(e)
+	case *OrdinalExpr:
+		return c.compileOrdinal(e)
+	}
+
+	return "", fmt.Errorf("%s cannot be compiled to SQL", e)
+}
+
+// compileTime returns the predicate of a time of day, which may pass
+// midnight.
+func (c *sqlCompiler) compileTime(e *TimeExpr) (string, error) {
+	from, err1 := time.Parse(timefmt, e.From)
+	to, err2 := time.Parse(timefmt, e.To)
+	if err1 != nil || err2 != nil {
+		return "", fmt.Errorf("%s cannot be compiled to SQL", e)
+	}
+
+	var (
+		t     = c.part("time")
+		start = t + " >= " + c.arg(from.Format("15:04:05"))
+	)
+	switch {
+	case from.Equal(to):
+		return sqlFalse, nil
+	case to.Hour() == 0 && to.Minute() == 0:
+		return start, nil
+	case to.Before(from):
+		return "(" + start + " OR " + t + " < " + c.arg(to.Format("15:04:05")) + ")", nil
+	default:
+		return "(" + start + " AND " + t + " < " + c.arg(to.Format("15:04:05")) + ")", nil
+	}
+}
+
+// compileOrdinal returns the predicate of the nth day or weekday of the
+// month.  Negative ordinals count from the last day of the month.
+func (c *sqlCompiler) compileOrdinal(e *OrdinalExpr) (string, error) {
+	unit, ok := e.Unit.(*MonthExpr)
+	if !ok {
+		return "", fmt.Errorf("%s cannot be compiled to SQL", e)
+	}
+
+	var pred string
+	switch x := unnamed(e.X).(type) {
+	case *DayExpr:
+		switch {
+		case len(x.Args) == 0 && e.N > 0:
+			pred = c.part("day") + " = " + c.arg(e.N)
+		case len(x.Args) == 0 && e.N < 0:
+			pred = c.fromEnd() + " = " + c.arg(-e.N-1)
+		case e.N == 1 && x.Args[0] == x.Args[len(x.Args)-1]:
+			pred = c.part("day") + " = " + c.arg(x.Args[0])
+		case e.N == 1:
+			pred = c.part("day") + " BETWEEN " + c.arg(x.Args[0]) + " AND " + c.arg(x.Args[1])
+		default:
+			return "", fmt.Errorf("%s cannot be compiled to SQL", e)
+		}
+	case *WeekdayExpr:
+		if e.N < -5 || e.N > 5 || x.Span() != 1 {
+			return "", fmt.Errorf("%s cannot be compiled to SQL", e)
+		}
+		if e.N > 0 {
+			pred = "(" + c.part("dow") + " = " + c.arg(int(x.From)) + " AND " +
+				c.part("day") + " BETWEEN " + c.arg(7*e.N-6) + " AND " + c.arg(7*e.N) + ")"
+		} else {
+			pred = "(" + c.part("dow") + " = " + c.arg(int(x.From)) + " AND " +
+				c.fromEnd() + " BETWEEN " + c.arg(-7*e.N-7) + " AND " + c.arg(-7*e.N-1) + ")"
+		}
+	default:
+		return "", fmt.Errorf("%s cannot be compiled to SQL", e)
+	}
+
+	if unit.Month == 0 {
+		return pred, nil
+	}
+	return "(" + pred + " AND " + c.part("month") + " = " + c.arg(int(unit.Month)) + ")", nil
+}
+
+// arg adds an argument and returns its placeholder.
+func (c *sqlCompiler) arg(v interface{}) string {
+	c.args = append(c.args, v)
+	if c.dialect == PostgreSQL {
+		return "$" + strconv.Itoa(len(c.args))
+	}
+	return "?"
+}
+
+// date adds a timestamp argument.  SQLite stores timestamps as text, so it is
+// passed in the same format.
+func (c *sqlCompiler) date(t time.Time) string {
+	if c.dialect == SQLite {
+		return c.arg(t.Format("2006-01-02 15:04:05"))
+	}
+	return c.arg(t)
+}
+
+// fromEnd returns the SQL of the number of days from the column until the
+// last day of its month.
+func (c *sqlCompiler) fromEnd() string {
+	return "(" + c.part("days") + " - " + c.part("day") + ")"
+}
+
+// part returns the SQL extracting a part of the column: "dow" for the day of
+// the week from Sunday as zero, "day", "days" for the number of days in the
+// month, "month" or "time".
+func (c *sqlCompiler) part(name string) string {
+	switch c.dialect {
+	case MySQL:
+		switch name {
+		case "dow":
+			return "(DAYOFWEEK(" + c.column + ") - 1)"
+		case "day":
+			return "DAYOFMONTH(" + c.column + ")"
+		case "days":
+			return "DAYOFMONTH(LAST_DAY(" + c.column + "))"
+		case "month":
+			return "MONTH(" + c.column + ")"
+		default:
+			return "TIME(" + c.column + ")"
+		}
+	case SQLite:
+		switch name {
+		case "dow":
+			return "CAST(strftime('%w', " + c.column + ") AS INTEGER)"
+		case "day":
+			return "CAST(strftime('%d', " + c.column + ") AS INTEGER)"
+		case "days":
+			return "CAST(strftime('%d', " + c.column + ", 'start of month', '+1 month', '-1 day') AS INTEGER)"
+		case "month":
+			return "CAST(strftime('%m', " + c.column + ") AS INTEGER)"
+		default:
+			return "time(" + c.column + ")"
+		}
+	default:
+		switch name {
+		case "dow":
+			return "EXTRACT(DOW FROM " + c.column + ")"
+		case "day":
+			return "EXTRACT(DAY FROM " + c.column + ")"
+		case "days":
+			return "EXTRACT(DAY FROM DATE_TRUNC('month', " + c.column + ") + INTERVAL '1 month - 1 day')"
+		case "month":
+			return "EXTRACT(MONTH FROM " + c.column + ")"
+		default:
+			return "CAST(" + c.column + " AS TIME)"
+		}
+	}
+}
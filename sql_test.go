@@ -0,0 +1,141 @@
+package timewarp_test
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/takeinitiative/timewarp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CompileSQL", func() {
+	DescribeTable("Predicates",
+		func(in string, dialect timewarp.SQLDialect, sql string, args ...interface{}) {
+			e, err := timewarp.ParseExprString(in)
+			Expect(err).NotTo(HaveOccurred())
+
+			s, a, err := timewarp.CompileSQL(e, "created_at", dialect)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(s).To(Equal(sql))
+			if len(args) == 0 {
+				Expect(a).To(BeEmpty())
+			} else {
+				Expect(a).To(Equal(args))
+			}
+		},
+		Entry("business hours", `DAY MONDAY FRIDAY IN TIME 0900 1700`, timewarp.PostgreSQL,
+			`(EXTRACT(DOW FROM created_at) IN ($1, $2, $3, $4, $5) AND (CAST(created_at AS TIME) >= $6 AND CAST(created_at AS TIME) < $7))`,
+			1, 2, 3, 4, 5, "09:00:00", "17:00:00"),
+		Entry("mysql weekday", `DAY SUNDAY`, timewarp.MySQL,
+			`(DAYOFWEEK(created_at) - 1) = ?`, 0),
+		Entry("sqlite weekday", `DAY SUNDAY`, timewarp.SQLite,
+			`CAST(strftime('%w', created_at) AS INTEGER) = ?`, 0),
+		Entry("overnight", `TIME 2200 0600`, timewarp.SQLite,
+			`(time(created_at) >= ? OR time(created_at) < ?)`, "22:00:00", "06:00:00"),
+		Entry("until midnight", `TIME 1800 0000`, timewarp.MySQL,
+			`TIME(created_at) >= ?`, "18:00:00"),
+		Entry("year bounds", `YEAR 2020`, timewarp.PostgreSQL,
+			`(created_at >= $1 AND created_at < $2)`,
+			time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)),
+		Entry("sqlite year bounds", `YEAR 2020`, timewarp.SQLite,
+			`(created_at >= ? AND created_at < ?)`, "2020-01-01 00:00:00", "2021-01-01 00:00:00"),
+		Entry("day of the month", `DAY 15 OF MONTH JULY IN YEAR 2008`, timewarp.MySQL,
+			`((DAYOFMONTH(created_at) = ? AND MONTH(created_at) = ?) AND (created_at >= ? AND created_at < ?))`,
+			15, 7, time.Date(2008, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2009, 1, 1, 0, 0, 0, 0, time.UTC)),
+		Entry("second tuesday", `DAY TUESDAY OF 2 MONTH`, timewarp.PostgreSQL,
+			`(EXTRACT(DOW FROM created_at) = $1 AND EXTRACT(DAY FROM created_at) BETWEEN $2 AND $3)`, 2, 8, 14),
+		Entry("negation and union", `DAY SATURDAY AND NOT MONTH DECEMBER`, timewarp.PostgreSQL,
+			`(EXTRACT(DOW FROM created_at) = $1 OR NOT EXTRACT(MONTH FROM created_at) = $2)`, 6, 12),
+		Entry("every day", `DAY`, timewarp.SQLite, `1 = 1`),
+		Entry("last friday", `DAY FRIDAY OF -1 MONTH`, timewarp.MySQL,
+			`((DAYOFWEEK(created_at) - 1) = ? AND (DAYOFMONTH(LAST_DAY(created_at)) - DAYOFMONTH(created_at)) BETWEEN ? AND ?)`, 5, 0, 6),
+		Entry("second to last day", `DAY OF -2 MONTH NOVEMBER`, timewarp.PostgreSQL,
+			`((EXTRACT(DAY FROM DATE_TRUNC('month', created_at) + INTERVAL '1 month - 1 day') - EXTRACT(DAY FROM created_at)) = $1 AND EXTRACT(MONTH FROM created_at) = $2)`, 1, 11),
+	)
+
+	Describe("SQLite", func() {
+		const layout = "2006-01-02 15:04:05"
+
+		// query runs the predicate over half hourly timestamps from December
+		// 2019 through February 2020 with the sqlite3 shell, and returns the
+		// matching timestamps
+		query := func(pred string, args []interface{}) []string {
+			script := []string{
+				"CREATE TABLE events (created_at TEXT);",
+				"WITH RECURSIVE t(x) AS (SELECT '2019-12-01 00:00:00' UNION ALL SELECT datetime(x, '+30 minutes') FROM t WHERE x < '2020-02-29 23:30:00') INSERT INTO events SELECT x FROM t;",
+				".parameter init",
+			}
+			for i, a := range args {
+				if s, ok := a.(string); ok {
+					a = "'" + s + "'"
+				}
+				script = append(script, fmt.Sprintf(".parameter set ?%d %v", i+1, a))
+			}
+			script = append(script, "SELECT created_at FROM events WHERE "+pred+" ORDER BY created_at;")
+
+			cmd := exec.Command("sqlite3", "-bail")
+			cmd.Stdin = strings.NewReader(strings.Join(script, "\n"))
+			out, err := cmd.CombinedOutput()
+			Expect(err).NotTo(HaveOccurred(), string(out))
+			return strings.Fields(strings.Replace(string(out), " ", "T", -1))
+		}
+
+		BeforeEach(func() {
+			if _, err := exec.LookPath("sqlite3"); err != nil {
+				Skip("sqlite3 is not installed")
+			}
+		})
+
+		DescribeTable("Matches",
+			func(in string) {
+				e, err := timewarp.ParseExprString(in)
+				Expect(err).NotTo(HaveOccurred())
+
+				s, a, err := timewarp.CompileSQL(e, "created_at", timewarp.SQLite)
+				Expect(err).NotTo(HaveOccurred())
+
+				var ts []time.Time
+				for t := time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC); t.Before(time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)); t = t.Add(30 * time.Minute) {
+					ts = append(ts, t)
+				}
+				expected := []string{}
+				for _, t := range timewarp.FilterTimes(e.Filter(), ts) {
+					expected = append(expected, strings.Replace(t.Format(layout), " ", "T", -1))
+				}
+				Expect(query(s, a)).To(Equal(expected))
+			},
+			Entry("business hours", `DAY MONDAY FRIDAY IN TIME 0900 1700`),
+			Entry("weekend", `DAY SATURDAY SUNDAY`),
+			Entry("overnight", `TIME 2200 0600`),
+			Entry("until midnight", `TIME 1800 0000`),
+			Entry("year bounds", `YEAR 2020 IN TIME 1200 1300`),
+			Entry("day of the month", `DAY 15 OF MONTH JANUARY`),
+			Entry("days of the month", `DAY 10 12 OF MONTH IN TIME 0000 0100`),
+			Entry("second tuesday", `DAY TUESDAY OF 2 MONTH`),
+			Entry("last friday", `DAY FRIDAY OF -1 MONTH`),
+			Entry("second to last sunday", `DAY SUNDAY OF -2 MONTH FEBRUARY`),
+			Entry("last day", `DAY OF -1 MONTH`),
+			Entry("negation and union", `DAY SATURDAY AND NOT MONTH DECEMBER IN TIME 0800 0900`),
+		)
+	})
+
+	DescribeTable("Unsupported",
+		func(in string) {
+			e, err := timewarp.ParseExprString(in)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, _, err = timewarp.CompileSQL(e, "created_at", timewarp.PostgreSQL)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("relative day", `DAY 5`),
+		Entry("relative ordinal", `DAY SATURDAY OF 2 WEEK SATURDAY`),
+		Entry("ordinal of times", `TIME 0900 1000 OF 2 DAY`),
+		Entry("week", `WEEK MONDAY`),
+		Entry("sixth to last weekday", `DAY FRIDAY OF -6 MONTH`),
+	)
+})
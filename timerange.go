@@ -1,7 +1,11 @@
 package timewarp
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"sort"
+	"strings"
 	"time"
 )
 
@@ -24,11 +28,174 @@ func Parse(format, start, end string) (*TimeRange, error) {
 	return &TimeRange{Start: startTime, End: endTime}, nil
 }
 
+// timeLayouts are the ISO 8601 layouts accepted by ParseTime, in order of
+// preference.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04",
+	"20060102T150405Z0700",
+	"20060102T1504Z0700",
+	"20060102T150405",
+	"2006-01-02",
+	"20060102",
+}
+
+// ParseTime parses an ISO 8601 date or date and time.  Times without an
+// offset are in UTC.
+func ParseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+// ParseInterval creates a new time range from an ISO 8601 interval given as
+// "start/end", "start/duration" or "duration/end".
+func ParseInterval(s string) (*TimeRange, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid interval %q", s)
+	}
+
+	var (
+		tr  TimeRange
+		err error
+	)
+	switch {
+	case strings.HasPrefix(parts[0], "P"):
+		var p Period
+		if p, err = ParsePeriod(parts[0]); err != nil {
+			return nil, err
+		}
+		if tr.End, err = ParseTime(parts[1]); err != nil {
+			return nil, err
+		}
+		tr.Start = p.SubFrom(tr.End)
+	case strings.HasPrefix(parts[1], "P"):
+		var p Period
+		if p, err = ParsePeriod(parts[1]); err != nil {
+			return nil, err
+		}
+		if tr.Start, err = ParseTime(parts[0]); err != nil {
+			return nil, err
+		}
+		tr.End = p.AddTo(tr.Start)
+	default:
+		var start, end time.Time
+		if start, err = ParseTime(parts[0]); err != nil {
+			return nil, err
+		}
+		if end, err = ParseTime(parts[1]); err != nil {
+			return nil, err
+		}
+		if err = tr.set(start, end); err != nil {
+			return nil, err
+		}
+	}
+
+	return &tr, nil
+}
+
 // Duration returns the difference between the start and end time
 func (tr *TimeRange) Duration() time.Duration {
 	return tr.End.Sub(tr.Start)
 }
 
+// String returns the time range as an ISO 8601 interval of RFC 3339 times.
+func (tr *TimeRange) String() string {
+	return tr.Start.Format(time.RFC3339Nano) + "/" + tr.End.Format(time.RFC3339Nano)
+}
+
+// MarshalJSON encodes the time range as an object of RFC 3339 start and end
+// times.
+func (tr TimeRange) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newJSONRange(tr))
+}
+
+// jsonRange is the JSON object of a time range.  Types holding a time range
+// with other fields embed it in their own JSON objects.
+type jsonRange struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// newJSONRange returns the JSON object of the time range.
+func newJSONRange(tr TimeRange) jsonRange {
+	return jsonRange{tr.Start.Format(time.RFC3339Nano), tr.End.Format(time.RFC3339Nano)}
+}
+
+// UnmarshalJSON decodes an object of RFC 3339 start and end times, or a string
+// holding an ISO 8601 interval.
+func (tr *TimeRange) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return tr.UnmarshalText([]byte(s))
+	}
+
+	var v struct {
+		Start *time.Time `json:"start"`
+		End   *time.Time `json:"end"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.Start == nil || v.End == nil {
+		return fmt.Errorf("time range requires a start and end")
+	}
+	return tr.set(*v.Start, *v.End)
+}
+
+// MarshalText encodes the time range as an ISO 8601 interval.
+func (tr TimeRange) MarshalText() ([]byte, error) {
+	return []byte(tr.String()), nil
+}
+
+// UnmarshalText decodes an ISO 8601 interval in any of the forms accepted by
+// ParseInterval.
+func (tr *TimeRange) UnmarshalText(text []byte) error {
+	v, err := ParseInterval(string(text))
+	if err != nil {
+		return err
+	}
+	*tr = *v
+	return nil
+}
+
+// Scan implements the sql.Scanner interface for interval strings.  A NULL
+// value scans as the zero time range.
+func (tr *TimeRange) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*tr = TimeRange{}
+		return nil
+	case string:
+		return tr.UnmarshalText([]byte(v))
+	case []byte:
+		return tr.UnmarshalText(v)
+	default:
+		return fmt.Errorf("cannot scan %T into a time range", src)
+	}
+}
+
+// Value implements the driver.Valuer interface, storing the time range as an
+// ISO 8601 interval.
+func (tr TimeRange) Value() (driver.Value, error) {
+	return tr.String(), nil
+}
+
+// set assigns the start and end times if the end does not precede the start.
+func (tr *TimeRange) set(start, end time.Time) error {
+	if end.Before(start) {
+		return fmt.Errorf("time range ends at %s before it starts at %s", end.Format(time.RFC3339Nano), start.Format(time.RFC3339Nano))
+	}
+	tr.Start, tr.End = start, end
+	return nil
+}
+
 // Less returns true if the Start of the receiever precedes the Start of the
 // argument, unless they start at the same time in which case the one that
 // starts earlier
@@ -1,11 +1,13 @@
 package timewarp_test
 
 import (
+	"encoding/json"
 	"time"
 
-	. "github.com/takeinitiative/timewarp"
+	"github.com/takeinitiative/timewarp"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 )
 
@@ -16,12 +18,12 @@ var _ = Describe("TimeRange", func() {
 			format string
 			start  string
 			end    string
-			slot   *TimeRange
+			slot   *timewarp.TimeRange
 			err    error
 		)
 
 		JustBeforeEach(func() {
-			slot, err = Parse(format, start, end)
+			slot, err = timewarp.Parse(format, start, end)
 		})
 
 		Context("invalid time range", func() {
@@ -47,96 +49,176 @@ var _ = Describe("TimeRange", func() {
 		})
 	})
 
+	DescribeTable("Interval",
+		func(in string, start, end time.Time) {
+			slot, err := timewarp.ParseInterval(in)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(slot.Start).To(BeTemporally("==", start))
+			Expect(slot.End).To(BeTemporally("==", end))
+		},
+		Entry("start and end", "2024-01-01T09:00:00Z/2024-01-01T17:00:00Z",
+			time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 17, 0, 0, 0, time.UTC)),
+		Entry("start and duration", "2024-01-01T09:00Z/PT8H",
+			time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 17, 0, 0, 0, time.UTC)),
+		Entry("duration and end", "P1D/2024-01-02",
+			time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)),
+		Entry("basic format", "20240101T0900+0100/PT1H",
+			time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)),
+	)
+
+	DescribeTable("Invalid interval",
+		func(in string) {
+			_, err := timewarp.ParseInterval(in)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("single time", "2024-01-01T09:00Z"),
+		Entry("reversed", "2024-01-02/2024-01-01"),
+		Entry("two durations", "P1D/PT1H"),
+		Entry("bad time", "yesterday/PT1H"),
+	)
+
 	Describe("Sorting and Searching", func() {
 		var (
-			slots []*TimeRange
-			slot1 *TimeRange
-			slot2 *TimeRange
-			slot3 *TimeRange
-			slot4 *TimeRange
-			slot5 *TimeRange
+			slots []*timewarp.TimeRange
+			slot1 *timewarp.TimeRange
+			slot2 *timewarp.TimeRange
+			slot3 *timewarp.TimeRange
+			slot4 *timewarp.TimeRange
+			slot5 *timewarp.TimeRange
 		)
 
 		BeforeEach(func() {
-			slot1, _ = Parse(time.Kitchen, "12:00AM", "5:00AM")
-			slot2, _ = Parse(time.Kitchen, "1:00AM", "5:00AM")
-			slot3, _ = Parse(time.Kitchen, "12:00AM", "3:00AM")
-			slot4, _ = Parse(time.Kitchen, "4:00AM", "7:00AM")
-			slot5, _ = Parse(time.Kitchen, "8:00AM", "2:00PM")
-			slots = []*TimeRange{slot1, slot2, slot3, slot4, slot5}
+			slot1, _ = timewarp.Parse(time.Kitchen, "12:00AM", "5:00AM")
+			slot2, _ = timewarp.Parse(time.Kitchen, "1:00AM", "5:00AM")
+			slot3, _ = timewarp.Parse(time.Kitchen, "12:00AM", "3:00AM")
+			slot4, _ = timewarp.Parse(time.Kitchen, "4:00AM", "7:00AM")
+			slot5, _ = timewarp.Parse(time.Kitchen, "8:00AM", "2:00PM")
+			slots = []*timewarp.TimeRange{slot1, slot2, slot3, slot4, slot5}
 		})
 
 		Context("sorted", func() {
 			JustBeforeEach(func() {
-				Sort(slots)
+				timewarp.Sort(slots)
 			})
 
 			It("should be sorted", func() {
-				Expect(slots).To(Equal([]*TimeRange{slot3, slot1, slot2, slot4, slot5}))
+				Expect(slots).To(Equal([]*timewarp.TimeRange{slot3, slot1, slot2, slot4, slot5}))
 			})
 		})
 
 		Context("not found", func() {
 			var (
-				search1 *TimeRange
-				search2 *TimeRange
-				search3 *TimeRange
+				search1 *timewarp.TimeRange
+				search2 *timewarp.TimeRange
+				search3 *timewarp.TimeRange
 			)
 
 			BeforeEach(func() {
-				search1, _ = Parse(time.Kitchen, "12:00AM", "2:00PM")
-				search2, _ = Parse(time.Kitchen, "2:00AM", "6:00AM")
-				search3, _ = Parse(time.Kitchen, "6:00AM", "10:00AM")
+				search1, _ = timewarp.Parse(time.Kitchen, "12:00AM", "2:00PM")
+				search2, _ = timewarp.Parse(time.Kitchen, "2:00AM", "6:00AM")
+				search3, _ = timewarp.Parse(time.Kitchen, "6:00AM", "10:00AM")
 			})
 
 			It("should not find a time slot", func() {
-				Expect(SearchIndex(slots, search1)).To(Equal(-1))
-				Expect(SearchIndex(slots, search2)).To(Equal(-1))
-				Expect(SearchIndex(slots, search3)).To(Equal(-1))
+				Expect(timewarp.SearchIndex(slots, search1)).To(Equal(-1))
+				Expect(timewarp.SearchIndex(slots, search2)).To(Equal(-1))
+				Expect(timewarp.SearchIndex(slots, search3)).To(Equal(-1))
 			})
 		})
 
 		Context("found", func() {
 			var (
-				search1 *TimeRange
-				search2 *TimeRange
+				search1 *timewarp.TimeRange
+				search2 *timewarp.TimeRange
 			)
 
 			BeforeEach(func() {
-				search1, _ = Parse(time.Kitchen, "12:00AM", "3:00AM")
-				search2, _ = Parse(time.Kitchen, "12:00AM", "5:00AM")
-				Sort(slots)
+				search1, _ = timewarp.Parse(time.Kitchen, "12:00AM", "3:00AM")
+				search2, _ = timewarp.Parse(time.Kitchen, "12:00AM", "5:00AM")
+				timewarp.Sort(slots)
 			})
 
 			It("should find time slots", func() {
-				Expect(slots[SearchIndex(slots, search1)]).To(Equal(slot3))
-				Expect(slots[SearchIndex(slots, search2)]).To(Equal(slot1))
+				Expect(slots[timewarp.SearchIndex(slots, search1)]).To(Equal(slot3))
+				Expect(slots[timewarp.SearchIndex(slots, search2)]).To(Equal(slot1))
 			})
 		})
 
 	})
 
 	Describe("Merging", func() {
-		var slots []*TimeRange
+		var slots []*timewarp.TimeRange
 
 		BeforeEach(func() {
-			slot1, _ := Parse(time.Kitchen, "2:00PM", "4:00PM")
-			slot2, _ := Parse(time.Kitchen, "12:00PM", "5:00PM")
-			slot3, _ := Parse(time.Kitchen, "6:00PM", "9:00PM")
-			slot4, _ := Parse(time.Kitchen, "9:00PM", "10:00PM")
-			slot5, _ := Parse(time.Kitchen, "10:00AM", "3:00PM")
-			slots = []*TimeRange{slot1, slot2, slot3, slot4, slot5}
+			slot1, _ := timewarp.Parse(time.Kitchen, "2:00PM", "4:00PM")
+			slot2, _ := timewarp.Parse(time.Kitchen, "12:00PM", "5:00PM")
+			slot3, _ := timewarp.Parse(time.Kitchen, "6:00PM", "9:00PM")
+			slot4, _ := timewarp.Parse(time.Kitchen, "9:00PM", "10:00PM")
+			slot5, _ := timewarp.Parse(time.Kitchen, "10:00AM", "3:00PM")
+			slots = []*timewarp.TimeRange{slot1, slot2, slot3, slot4, slot5}
 		})
 
 		JustBeforeEach(func() {
-			Merge(&slots)
+			timewarp.Merge(&slots)
 		})
 
 		It("should merge overlapping time slots", func() {
-			slot1, _ := Parse(time.Kitchen, "10:00AM", "5:00PM")
-			slot2, _ := Parse(time.Kitchen, "6:00PM", "10:00PM")
+			slot1, _ := timewarp.Parse(time.Kitchen, "10:00AM", "5:00PM")
+			slot2, _ := timewarp.Parse(time.Kitchen, "6:00PM", "10:00PM")
+
+			Expect(slots).To(Equal([]*timewarp.TimeRange{slot1, slot2}))
+		})
+	})
+
+	Describe("Encoding", func() {
+		var slot timewarp.TimeRange
+
+		BeforeEach(func() {
+			slot = timewarp.TimeRange{
+				Start: time.Date(2018, 3, 13, 12, 0, 0, 0, time.UTC),
+				End:   time.Date(2018, 3, 13, 14, 0, 0, 0, time.UTC),
+			}
+		})
+
+		It("should marshal JSON as start and end", func() {
+			b, err := json.Marshal(slot)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(b)).To(Equal(`{"start":"2018-03-13T12:00:00Z","end":"2018-03-13T14:00:00Z"}`))
+
+			var out timewarp.TimeRange
+			Expect(json.Unmarshal(b, &out)).To(Succeed())
+			Expect(out).To(Equal(slot))
+		})
+
+		It("should unmarshal JSON intervals", func() {
+			var out timewarp.TimeRange
+			Expect(json.Unmarshal([]byte(`"2018-03-13T12:00:00Z/2018-03-13T14:00:00Z"`), &out)).To(Succeed())
+			Expect(out).To(Equal(slot))
+		})
+
+		It("should marshal text as an interval", func() {
+			b, err := slot.MarshalText()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(b)).To(Equal("2018-03-13T12:00:00Z/2018-03-13T14:00:00Z"))
+		})
+
+		It("should reject invalid ranges", func() {
+			var out timewarp.TimeRange
+			Expect(out.UnmarshalText([]byte("2018-03-13T12:00:00Z"))).NotTo(Succeed())
+			Expect(out.UnmarshalText([]byte("2018-03-13T14:00:00Z/2018-03-13T12:00:00Z"))).NotTo(Succeed())
+			Expect(json.Unmarshal([]byte(`{"start":"2018-03-13T12:00:00Z"}`), &out)).NotTo(Succeed())
+		})
+
+		It("should scan and value SQL intervals", func() {
+			v, err := slot.Value()
+			Expect(err).NotTo(HaveOccurred())
 
-			Expect(slots).To(Equal([]*TimeRange{slot1, slot2}))
+			var out timewarp.TimeRange
+			Expect(out.Scan([]byte(v.(string)))).To(Succeed())
+			Expect(out).To(Equal(slot))
+			Expect(out.Scan(nil)).To(Succeed())
+			Expect(out).To(Equal(timewarp.TimeRange{}))
+			Expect(out.Scan(42)).NotTo(Succeed())
 		})
 	})
 })
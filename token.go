@@ -69,6 +69,15 @@ const (
 
 	LPAREN // (
 	RPAREN // )
+
+	// COMMENT and the following were added after the tokens above, which
+	// keep their values.
+	COMMENT   // # or -- to the end of the line
+	STRING    // "quoted"
+	COLON     // :
+	DASH      // -
+	COMMA     // ,
+	SEMICOLON // ;
 )
 
 var tokens = [...]string{
@@ -113,27 +122,38 @@ var tokens = [...]string{
 
 	LPAREN: "(",
 	RPAREN: ")",
+
+	COMMENT:   "COMMENT",
+	STRING:    "STRING",
+	COLON:     ":",
+	DASH:      "-",
+	COMMA:     ",",
+	SEMICOLON: ";",
 }
 
-var keywords map[string]Token
+// keywords maps the lower case keywords to their tokens.
+var keywords = canonicalKeywords()
 
-func init() {
-	keywords = make(map[string]Token)
+// canonicalKeywords returns the canonical keywords of the operators, keywords,
+// months and days of the week.
+func canonicalKeywords() map[string]Token {
+	m := make(map[string]Token)
 	for tok := operatorBeg + 1; tok < operatorEnd; tok++ {
-		keywords[strings.ToLower(tokens[tok])] = tok
+		m[strings.ToLower(tokens[tok])] = tok
 	}
 
 	for tok := keywordBeg + 1; tok < keywordEnd; tok++ {
-		keywords[strings.ToLower(tokens[tok])] = tok
+		m[strings.ToLower(tokens[tok])] = tok
 	}
 
 	for tok := moyBeg + 1; tok < moyEnd; tok++ {
-		keywords[strings.ToLower(tokens[tok])] = tok
+		m[strings.ToLower(tokens[tok])] = tok
 	}
 
 	for tok := dowBeg + 1; tok < dowEnd; tok++ {
-		keywords[strings.ToLower(tokens[tok])] = tok
+		m[strings.ToLower(tokens[tok])] = tok
 	}
+	return m
 }
 
 // String returns the string representation of the token
@@ -164,10 +184,7 @@ func tokstr(tok Token, lit string) string {
 
 // Lookup returns the token associated with a given string
 func Lookup(ident string) Token {
-	if tok, ok := keywords[strings.ToLower(ident)]; ok {
-		return tok
-	}
-	return IDENT
+	return canonical.Lookup(ident)
 }
 
 // Pos specifies the line and character position of a token.
@@ -182,6 +199,14 @@ func (p Pos) String() string {
 	return fmt.Sprintf("%d col %d", p.Line+1, p.Char+1)
 }
 
+// before returns true if the position precedes the other position
+func (p Pos) before(other Pos) bool {
+	if p.Line == other.Line {
+		return p.Char < other.Char
+	}
+	return p.Line < other.Line
+}
+
 // getMonthOfYear converts the month token into a time.Month value
 func getMonthOfYear(tok Token) time.Month {
 	switch tok {